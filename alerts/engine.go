@@ -0,0 +1,461 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/noel-vega/hubble/docker"
+	"github.com/noel-vega/hubble/metrics"
+)
+
+// defaultInterval is how often rules are evaluated unless ALERTS_INTERVAL
+// is set
+const defaultInterval = 15 * time.Second
+
+// eventSettle is how long evaluation waits after a container event, giving
+// the container cache time to pick up the change
+const eventSettle = time.Second
+
+// restartSample is a container's restart count at a point in time
+type restartSample struct {
+	time  time.Time
+	count int
+}
+
+// Engine evaluates alert rules against the container cache and the latest
+// metrics samples. Conditions are re-checked on every evaluation: an alert
+// fires when its condition starts to hold and resolves when it stops.
+type Engine struct {
+	dockerService *docker.Service
+	store         *metrics.Store
+	interval      time.Duration
+	rulesPath     string
+
+	mu        sync.Mutex
+	rules     []Rule
+	history   *history
+	active    map[string]Alert     // by Alert.key
+	pending   map[string]time.Time // when a condition was first seen, for rules with For
+	restarts  map[string][]restartSample
+	listeners []func(Alert)
+	now       func() time.Time
+}
+
+// NewEngine loads the rules and alert history kept in dir. CPU and memory
+// rules read from store, which may be nil, in which case they never fire.
+func NewEngine(dir string, dockerService *docker.Service, store *metrics.Store) (*Engine, error) {
+	interval := defaultInterval
+	if value := os.Getenv("ALERTS_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid ALERTS_INTERVAL: %s", value)
+		}
+		interval = parsed
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create alerts directory: %w", err)
+	}
+
+	rulesPath := filepath.Join(dir, "rules.json")
+	rules, err := loadRules(rulesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := openHistory(filepath.Join(dir, "history.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Engine{
+		dockerService: dockerService,
+		store:         store,
+		interval:      interval,
+		rulesPath:     rulesPath,
+		rules:         rules,
+		history:       history,
+		active:        make(map[string]Alert),
+		pending:       make(map[string]time.Time),
+		restarts:      make(map[string][]restartSample),
+		now:           time.Now,
+	}
+
+	// Alerts still open when Hubble stopped are picked up again so they
+	// resolve, rather than firing a second time
+	for _, alert := range history.alerts {
+		if alert.Status == StatusFiring {
+			e.active[alert.key()] = alert
+		}
+	}
+
+	return e, nil
+}
+
+// Interval returns how often rules are evaluated
+func (e *Engine) Interval() time.Duration {
+	return e.interval
+}
+
+// OnChange registers fn to be called whenever an alert fires or resolves.
+// It is called from the evaluation goroutine and should not block.
+func (e *Engine) OnChange(fn func(Alert)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.listeners = append(e.listeners, fn)
+}
+
+// Run evaluates the rules every interval, and shortly after container
+// events, until ctx is done
+func (e *Engine) Run(ctx context.Context) {
+	events, unsubscribe := e.dockerService.SubscribeEvents(docker.EventFilter{Types: []string{"container"}})
+	defer func() { unsubscribe() }()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	var settle <-chan time.Time
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				// Fell behind; the ticker still covers evaluation
+				events, unsubscribe = e.dockerService.SubscribeEvents(docker.EventFilter{Types: []string{"container"}})
+			}
+			if settle == nil {
+				settle = time.After(eventSettle)
+			}
+			continue
+		case <-settle:
+			settle = nil
+		case <-ticker.C:
+		case <-ctx.Done():
+			e.mu.Lock()
+			e.history.close()
+			e.mu.Unlock()
+			return
+		}
+
+		cache := e.dockerService.Containers()
+		if !cache.Synced() {
+			continue
+		}
+		var points []metrics.Point
+		if e.store != nil {
+			points = e.store.Latest()
+		}
+		e.evaluate(cache.List(), points)
+	}
+}
+
+// evaluate checks every enabled rule against every container it applies
+// to, firing new alerts and resolving those whose condition has cleared
+func (e *Engine) evaluate(containers []docker.CachedContainer, points []metrics.Point) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now().UTC()
+	e.trackRestarts(now, containers)
+
+	latest := make(map[string]metrics.Point, len(points))
+	for _, p := range points {
+		if existing, ok := latest[p.Name]; !ok || p.Time.After(existing.Time) {
+			latest[p.Name] = p
+		}
+	}
+
+	var changed []Alert
+	seen := make(map[string]bool)
+	for _, rule := range e.rules {
+		if rule.Disabled {
+			continue
+		}
+
+		for _, c := range containers {
+			if !rule.matches(c.Project(), c.Service()) {
+				continue
+			}
+
+			point, hasPoint := latest[c.Name]
+			value, message, firing := e.check(rule, c, point, hasPoint, now)
+			if !firing {
+				continue
+			}
+
+			key := rule.ID + "/" + c.Name
+			seen[key] = true
+
+			if alert, ok := e.active[key]; ok {
+				alert.Value = value
+				e.active[key] = alert
+				continue
+			}
+
+			if rule.For > 0 {
+				since, ok := e.pending[key]
+				if !ok {
+					e.pending[key] = now
+					continue
+				}
+				if now.Sub(since) < time.Duration(rule.For) {
+					continue
+				}
+			}
+			delete(e.pending, key)
+
+			alert := Alert{
+				ID:          newID(),
+				RuleID:      rule.ID,
+				RuleName:    rule.Name,
+				Type:        rule.Type,
+				Status:      StatusFiring,
+				Container:   c.Name,
+				ContainerID: c.ID,
+				Project:     c.Project(),
+				Service:     c.Service(),
+				Message:     c.Name + " " + message,
+				Value:       value,
+				StartedAt:   now,
+			}
+			e.active[key] = alert
+			changed = append(changed, alert)
+		}
+	}
+
+	for key := range e.pending {
+		if !seen[key] {
+			delete(e.pending, key)
+		}
+	}
+
+	for key, alert := range e.active {
+		if seen[key] {
+			continue
+		}
+		resolvedAt := now
+		alert.Status = StatusResolved
+		alert.ResolvedAt = &resolvedAt
+		delete(e.active, key)
+		changed = append(changed, alert)
+	}
+
+	for _, alert := range changed {
+		if err := e.history.record(alert); err != nil {
+			log.Printf("Failed to record alert %s: %v", alert.ID, err)
+		}
+		for _, fn := range e.listeners {
+			fn(alert)
+		}
+	}
+}
+
+// check reports whether a rule's condition holds for a container, with the
+// value that triggered it and a description
+func (e *Engine) check(rule Rule, c docker.CachedContainer, point metrics.Point, hasPoint bool, now time.Time) (float64, string, bool) {
+	stopped := c.State == "exited" || c.State == "dead"
+
+	switch rule.Type {
+	case RuleExitCode:
+		if stopped && c.ExitCode != 0 && !c.StopRequested {
+			return float64(c.ExitCode), fmt.Sprintf("exited with code %d", c.ExitCode), true
+		}
+	case RuleOOMKilled:
+		if stopped && c.OOMKilled {
+			return float64(c.ExitCode), "was killed for running out of memory", true
+		}
+	case RuleUnhealthy:
+		if c.State == "running" && c.Health == "unhealthy" {
+			return 0, "is unhealthy", true
+		}
+	case RuleRestarts:
+		restarts := e.restartsWithin(c.Name, now, time.Duration(rule.Window))
+		if float64(restarts) > rule.Threshold {
+			return float64(restarts), fmt.Sprintf("restarted %d times in the last %s", restarts, time.Duration(rule.Window)), true
+		}
+	case RuleCPU:
+		if c.State == "running" && hasPoint && point.CPUPercent > rule.Threshold {
+			return point.CPUPercent, fmt.Sprintf("CPU usage is %.1f%% (threshold %g%%)", point.CPUPercent, rule.Threshold), true
+		}
+	case RuleMemory:
+		if c.State == "running" && hasPoint && point.MemoryLimit > 0 {
+			percent := float64(point.MemoryUsage) / float64(point.MemoryLimit) * 100
+			if percent > rule.Threshold {
+				return percent, fmt.Sprintf("memory usage is %.1f%% of its limit (threshold %g%%)", percent, rule.Threshold), true
+			}
+		}
+	}
+
+	return 0, "", false
+}
+
+// trackRestarts samples each container's restart count, keeping enough
+// history to cover the longest restarts rule window
+func (e *Engine) trackRestarts(now time.Time, containers []docker.CachedContainer) {
+	var window time.Duration
+	for _, rule := range e.rules {
+		if rule.Type == RuleRestarts && !rule.Disabled {
+			window = max(window, time.Duration(rule.Window))
+		}
+	}
+	if window == 0 {
+		clear(e.restarts)
+		return
+	}
+
+	seen := make(map[string]bool, len(containers))
+	for _, c := range containers {
+		seen[c.Name] = true
+		samples := e.restarts[c.Name]
+
+		// A recreated container starts counting from zero again
+		if len(samples) > 0 && c.RestartCount < samples[len(samples)-1].count {
+			samples = nil
+		}
+		if len(samples) == 0 || samples[len(samples)-1].count != c.RestartCount {
+			samples = append(samples, restartSample{time: now, count: c.RestartCount})
+		}
+
+		// Keep the newest sample older than the window as the baseline
+		cutoff := now.Add(-window)
+		for len(samples) > 1 && !samples[1].time.After(cutoff) {
+			samples = samples[1:]
+		}
+		e.restarts[c.Name] = samples
+	}
+
+	for name := range e.restarts {
+		if !seen[name] {
+			delete(e.restarts, name)
+		}
+	}
+}
+
+// restartsWithin returns how many times a container restarted during the
+// window, as far back as it has been observed
+func (e *Engine) restartsWithin(name string, now time.Time, window time.Duration) int {
+	samples := e.restarts[name]
+	if len(samples) == 0 {
+		return 0
+	}
+
+	cutoff := now.Add(-window)
+	base := samples[0]
+	for _, s := range samples[1:] {
+		if s.time.After(cutoff) {
+			break
+		}
+		base = s
+	}
+	return samples[len(samples)-1].count - base.count
+}
+
+// ListRules returns every rule in the order they were created
+func (e *Engine) ListRules() []Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Rule{}, e.rules...)
+}
+
+// GetRule returns a rule by ID
+func (e *Engine) GetRule(id string) (Rule, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, rule := range e.rules {
+		if rule.ID == id {
+			return rule, nil
+		}
+	}
+	return Rule{}, fmt.Errorf("rule not found: %s", id)
+}
+
+// CreateRule validates and saves a new rule
+func (e *Engine) CreateRule(rule Rule) (Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return Rule{}, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now().UTC()
+	rule.ID = newID()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+
+	rules := append(append([]Rule{}, e.rules...), rule)
+	if err := saveRules(e.rulesPath, rules); err != nil {
+		return Rule{}, err
+	}
+	e.rules = rules
+
+	return rule, nil
+}
+
+// UpdateRule replaces a rule's settings. Alerts it has open stay open while
+// their condition still holds under the new settings.
+func (e *Engine) UpdateRule(id string, rule Rule) (Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return Rule{}, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, existing := range e.rules {
+		if existing.ID != id {
+			continue
+		}
+
+		rule.ID = id
+		rule.CreatedAt = existing.CreatedAt
+		rule.UpdatedAt = e.now().UTC()
+
+		rules := append([]Rule{}, e.rules...)
+		rules[i] = rule
+		if err := saveRules(e.rulesPath, rules); err != nil {
+			return Rule{}, err
+		}
+		e.rules = rules
+
+		return rule, nil
+	}
+
+	return Rule{}, fmt.Errorf("rule not found: %s", id)
+}
+
+// DeleteRule removes a rule. Its open alerts resolve on the next
+// evaluation.
+func (e *Engine) DeleteRule(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, rule := range e.rules {
+		if rule.ID != id {
+			continue
+		}
+
+		rules := append(append([]Rule{}, e.rules[:i]...), e.rules[i+1:]...)
+		if err := saveRules(e.rulesPath, rules); err != nil {
+			return err
+		}
+		e.rules = rules
+
+		return nil
+	}
+
+	return fmt.Errorf("rule not found: %s", id)
+}
+
+// Alerts returns open and resolved alerts matching filter, most recently
+// started first. A limit of 0 returns all that are kept.
+func (e *Engine) Alerts(filter AlertFilter, limit int) []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.history.list(filter, limit)
+}
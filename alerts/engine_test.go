@@ -0,0 +1,181 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/noel-vega/hubble/docker"
+	"github.com/noel-vega/hubble/metrics"
+)
+
+func newTestEngine(t *testing.T, dir string, clock *time.Time) *Engine {
+	t.Helper()
+
+	e, err := NewEngine(dir, nil, nil)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	e.now = func() time.Time { return *clock }
+	t.Cleanup(func() { e.history.close() })
+	return e
+}
+
+func testContainer(name, state string) docker.CachedContainer {
+	return docker.CachedContainer{
+		ID:    name + "-id",
+		Name:  name,
+		State: state,
+		Labels: map[string]string{
+			"com.docker.compose.project": "site",
+			"com.docker.compose.service": "web",
+		},
+	}
+}
+
+func TestEngine_FiresDeduplicatesAndResolves(t *testing.T) {
+	clock := time.Date(2025, 1, 15, 3, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	e := newTestEngine(t, dir, &clock)
+
+	var changes []Alert
+	e.OnChange(func(a Alert) { changes = append(changes, a) })
+
+	exitRule, err := e.CreateRule(Rule{Name: "Crashed", Type: RuleExitCode, Project: "site"})
+	if err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+	if _, err := e.CreateRule(Rule{Name: "Memory", Type: RuleMemory, Threshold: 90, For: Duration(time.Minute)}); err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+	if _, err := e.CreateRule(Rule{Name: "Other project", Type: RuleExitCode, Project: "other"}); err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+
+	crashed := testContainer("site-web-1", "exited")
+	crashed.ExitCode = 1
+	busy := testContainer("site-web-2", "running")
+	points := []metrics.Point{{Name: "site-web-2", MemoryUsage: 95, MemoryLimit: 100}}
+
+	e.evaluate([]docker.CachedContainer{crashed, busy}, points)
+	if len(changes) != 1 || changes[0].RuleID != exitRule.ID || changes[0].Status != StatusFiring {
+		t.Fatalf("expected one firing exit code alert, got %+v", changes)
+	}
+	if changes[0].Message != "site-web-1 exited with code 1" {
+		t.Errorf("Message = %q", changes[0].Message)
+	}
+
+	// Still crashed: no duplicate. Memory has now been high for a minute.
+	clock = clock.Add(time.Minute)
+	e.evaluate([]docker.CachedContainer{crashed, busy}, points)
+	if len(changes) != 2 || changes[1].Type != RuleMemory || changes[1].Value != 95 {
+		t.Fatalf("expected the memory alert to fire after its For period, got %+v", changes)
+	}
+
+	clock = clock.Add(time.Minute)
+	e.evaluate([]docker.CachedContainer{testContainer("site-web-1", "running"), busy}, points)
+	if len(changes) != 3 || changes[2].Status != StatusResolved || changes[2].ID != changes[0].ID {
+		t.Fatalf("expected the exit code alert to resolve, got %+v", changes)
+	}
+	if changes[2].ResolvedAt == nil || !changes[2].ResolvedAt.Equal(clock) {
+		t.Errorf("ResolvedAt = %v", changes[2].ResolvedAt)
+	}
+
+	if firing := e.Alerts(AlertFilter{Status: StatusFiring}, 0); len(firing) != 1 || firing[0].Type != RuleMemory {
+		t.Errorf("firing alerts = %+v", firing)
+	}
+
+	// A restart picks up the open memory alert instead of firing it again
+	reopened := newTestEngine(t, dir, &clock)
+	reopened.OnChange(func(a Alert) { changes = append(changes, a) })
+	if len(reopened.ListRules()) != 3 {
+		t.Errorf("expected rules to be reloaded, got %d", len(reopened.ListRules()))
+	}
+	reopened.evaluate([]docker.CachedContainer{busy}, points)
+	if len(changes) != 3 {
+		t.Errorf("expected no new alerts after reopening, got %+v", changes[3:])
+	}
+	if all := reopened.Alerts(AlertFilter{}, 0); len(all) != 2 {
+		t.Errorf("expected 2 alerts in history, got %d", len(all))
+	}
+}
+
+func TestEngine_IgnoresRequestedStops(t *testing.T) {
+	clock := time.Date(2025, 1, 15, 3, 0, 0, 0, time.UTC)
+	e := newTestEngine(t, t.TempDir(), &clock)
+
+	var changes []Alert
+	e.OnChange(func(a Alert) { changes = append(changes, a) })
+
+	if _, err := e.CreateRule(Rule{Name: "Crashed", Type: RuleExitCode}); err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+
+	stopped := testContainer("site-web-1", "exited")
+	stopped.ExitCode = 143
+	stopped.StopRequested = true
+	killed := testContainer("site-web-2", "exited")
+	killed.ExitCode = 137
+	killed.StopRequested = true
+	crashed := testContainer("site-web-3", "exited")
+	crashed.ExitCode = 137
+
+	e.evaluate([]docker.CachedContainer{stopped, killed, crashed}, nil)
+	if len(changes) != 1 || changes[0].Container != "site-web-3" {
+		t.Errorf("expected only the crashed container to alert, got %+v", changes)
+	}
+}
+
+func TestEngine_Restarts(t *testing.T) {
+	clock := time.Date(2025, 1, 15, 3, 0, 0, 0, time.UTC)
+	e := newTestEngine(t, t.TempDir(), &clock)
+
+	if _, err := e.CreateRule(Rule{Name: "Flapping", Type: RuleRestarts, Threshold: 2, Window: Duration(10 * time.Minute)}); err != nil {
+		t.Fatalf("CreateRule failed: %v", err)
+	}
+
+	ctr := testContainer("site-web-1", "running")
+	for _, count := range []int{5, 6, 7} {
+		ctr.RestartCount = count
+		e.evaluate([]docker.CachedContainer{ctr}, nil)
+		clock = clock.Add(time.Minute)
+	}
+	if firing := e.Alerts(AlertFilter{Status: StatusFiring}, 0); len(firing) != 0 {
+		t.Fatalf("2 restarts should not exceed the threshold, got %+v", firing)
+	}
+
+	ctr.RestartCount = 8
+	e.evaluate([]docker.CachedContainer{ctr}, nil)
+	firing := e.Alerts(AlertFilter{Status: StatusFiring}, 0)
+	if len(firing) != 1 || firing[0].Value != 3 {
+		t.Fatalf("expected 3 restarts to fire, got %+v", firing)
+	}
+
+	// Once the restarts age out of the window the alert resolves
+	clock = clock.Add(15 * time.Minute)
+	e.evaluate([]docker.CachedContainer{ctr}, nil)
+	if firing := e.Alerts(AlertFilter{Status: StatusFiring}, 0); len(firing) != 0 {
+		t.Errorf("expected the alert to resolve, got %+v", firing)
+	}
+}
+
+func TestRule_Validate(t *testing.T) {
+	tests := []struct {
+		rule  Rule
+		valid bool
+	}{
+		{Rule{Name: "a", Type: RuleExitCode}, true},
+		{Rule{Type: RuleExitCode}, false},
+		{Rule{Name: "a", Type: "disk"}, false},
+		{Rule{Name: "a", Type: RuleRestarts, Threshold: 3}, false},
+		{Rule{Name: "a", Type: RuleRestarts, Threshold: 3, Window: Duration(time.Minute)}, true},
+		{Rule{Name: "a", Type: RuleCPU}, false},
+		{Rule{Name: "a", Type: RuleCPU, Threshold: 250}, true},
+		{Rule{Name: "a", Type: RuleMemory, Threshold: 120}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.rule.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %v", tt.rule, err, tt.valid)
+		}
+	}
+}
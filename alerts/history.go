@@ -0,0 +1,149 @@
+package alerts
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// maxHistory is how many alerts are kept in memory for listing
+const maxHistory = 1000
+
+// Status is whether an alert is still firing
+type Status string
+
+const (
+	StatusFiring   Status = "firing"
+	StatusResolved Status = "resolved"
+)
+
+// Alert is raised when a rule's condition holds for a container, and
+// resolved once it no longer does
+type Alert struct {
+	ID          string     `json:"id"`
+	RuleID      string     `json:"rule_id"`
+	RuleName    string     `json:"rule_name"`
+	Type        RuleType   `json:"type"`
+	Status      Status     `json:"status"`
+	Container   string     `json:"container"`
+	ContainerID string     `json:"container_id"`
+	Project     string     `json:"project,omitempty"`
+	Service     string     `json:"service,omitempty"`
+	Message     string     `json:"message"`
+	Value       float64    `json:"value"`
+	StartedAt   time.Time  `json:"started_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+}
+
+// key identifies the condition an alert is for, so a rule raises at most
+// one open alert per container. Containers are keyed by name, which
+// survives compose recreating them.
+func (a Alert) key() string {
+	return a.RuleID + "/" + a.Container
+}
+
+// AlertFilter selects alerts; empty fields match everything
+type AlertFilter struct {
+	Status  Status
+	Project string
+	RuleID  string
+}
+
+func (f AlertFilter) match(a Alert) bool {
+	return (f.Status == "" || f.Status == a.Status) &&
+		(f.Project == "" || f.Project == a.Project) &&
+		(f.RuleID == "" || f.RuleID == a.RuleID)
+}
+
+// history keeps recent alerts in memory and appends every change to a JSON
+// lines file, so a restart knows which alerts are still open
+type history struct {
+	file   *os.File
+	alerts []Alert // oldest first
+	index  map[string]int
+}
+
+func openHistory(path string) (*history, error) {
+	h := &history{index: make(map[string]int)}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open alert history: %w", err)
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var alert Alert
+		if err := json.Unmarshal(scanner.Bytes(), &alert); err != nil {
+			continue
+		}
+		h.put(alert)
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read alert history: %w", err)
+	}
+
+	h.file = file
+	return h, nil
+}
+
+// record stores a new or changed alert
+func (h *history) record(alert Alert) error {
+	h.put(alert)
+
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+	if _, err := h.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write alert history: %w", err)
+	}
+	return nil
+}
+
+// put replaces an alert already held or appends it, dropping the oldest
+// once maxHistory is exceeded
+func (h *history) put(alert Alert) {
+	if i, ok := h.index[alert.ID]; ok {
+		h.alerts[i] = alert
+		return
+	}
+
+	h.alerts = append(h.alerts, alert)
+	if len(h.alerts) > maxHistory {
+		h.alerts = h.alerts[len(h.alerts)-maxHistory:]
+		h.index = make(map[string]int, len(h.alerts))
+		for i, a := range h.alerts {
+			h.index[a.ID] = i
+		}
+		return
+	}
+	h.index[alert.ID] = len(h.alerts) - 1
+}
+
+// list returns matching alerts, most recently started first
+func (h *history) list(filter AlertFilter, limit int) []Alert {
+	result := make([]Alert, 0)
+	for _, alert := range h.alerts {
+		if filter.match(alert) {
+			result = append(result, alert)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].StartedAt.After(result[j].StartedAt)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+func (h *history) close() error {
+	return h.file.Close()
+}
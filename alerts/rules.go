@@ -0,0 +1,169 @@
+package alerts
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RuleType is the condition a rule watches for
+type RuleType string
+
+const (
+	// RuleExitCode fires while a container is stopped with a non-zero exit
+	// code
+	RuleExitCode RuleType = "exit_code"
+	// RuleOOMKilled fires while a container is stopped after running out of
+	// memory
+	RuleOOMKilled RuleType = "oom_killed"
+	// RuleRestarts fires when Docker restarts a container more than
+	// Threshold times within Window
+	RuleRestarts RuleType = "restarts"
+	// RuleUnhealthy fires while a container's health check fails
+	RuleUnhealthy RuleType = "unhealthy"
+	// RuleCPU fires while CPU usage is above Threshold percent, where one
+	// fully used core is 100
+	RuleCPU RuleType = "cpu"
+	// RuleMemory fires while memory usage is above Threshold percent of the
+	// container's limit
+	RuleMemory RuleType = "memory"
+)
+
+var ruleTypes = map[RuleType]bool{
+	RuleExitCode: true, RuleOOMKilled: true, RuleRestarts: true,
+	RuleUnhealthy: true, RuleCPU: true, RuleMemory: true,
+}
+
+// Rule describes when to raise an alert. Project and Service narrow the
+// containers it applies to; empty matches all.
+type Rule struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Type      RuleType `json:"type"`
+	Project   string   `json:"project,omitempty"`
+	Service   string   `json:"service,omitempty"`
+	Threshold float64  `json:"threshold,omitempty"`
+	// Window is the period restarts are counted over
+	Window Duration `json:"window,omitempty"`
+	// For is how long the condition must hold before the alert fires
+	For       Duration  `json:"for,omitempty"`
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks the rule's settings for its type
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("invalid rule: name is required")
+	}
+	if !ruleTypes[r.Type] {
+		return fmt.Errorf("invalid rule: unknown type %q", r.Type)
+	}
+	if r.For < 0 || r.Window < 0 {
+		return fmt.Errorf("invalid rule: durations must not be negative")
+	}
+
+	switch r.Type {
+	case RuleRestarts:
+		if r.Threshold < 1 {
+			return fmt.Errorf("invalid rule: threshold must be at least 1 restart")
+		}
+		if r.Window == 0 {
+			return fmt.Errorf("invalid rule: window is required for restarts rules")
+		}
+	case RuleCPU:
+		if r.Threshold <= 0 {
+			return fmt.Errorf("invalid rule: threshold must be greater than 0")
+		}
+	case RuleMemory:
+		if r.Threshold <= 0 || r.Threshold > 100 {
+			return fmt.Errorf("invalid rule: threshold must be a percentage between 0 and 100")
+		}
+	}
+
+	return nil
+}
+
+// matches reports whether the rule applies to a container of the given
+// project and service
+func (r Rule) matches(project, service string) bool {
+	return (r.Project == "" || r.Project == project) && (r.Service == "" || r.Service == service)
+}
+
+// Duration is a time.Duration written as a string such as "10m" in JSON
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"10m\"")
+	}
+	if s == "" {
+		*d = 0
+		return nil
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration: %s", s)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// loadRules reads the rules file, which may not exist yet
+func loadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []Rule{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert rules: %w", err)
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+// saveRules replaces the rules file
+func saveRules(path string, rules []Rule) error {
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode alert rules: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".rules-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to save alert rules: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save alert rules: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save alert rules: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save alert rules: %w", err)
+	}
+	return nil
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxRecentEntries is how many entries are kept in memory for listing
+const maxRecentEntries = 1000
+
+// Entry is a single audited action
+type Entry struct {
+	Time    time.Time      `json:"time"`
+	User    string         `json:"user"`
+	Action  string         `json:"action"`
+	Target  string         `json:"target"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Log records audit entries. Entries are appended as JSON lines to a file
+// when a path is configured, and the most recent ones are kept in memory.
+type Log struct {
+	mu      sync.Mutex
+	file    *os.File
+	entries []Entry // oldest first
+}
+
+// NewLog opens the audit log at path, loading its most recent entries. An
+// empty path keeps the log in memory only.
+func NewLog(path string) (*Log, error) {
+	l := &Log{}
+	if path == "" {
+		return l, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		l.append(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	l.file = file
+	return l, nil
+}
+
+// Record adds an entry, stamping it with the current time if it has none
+func (l *Log) Record(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.append(entry)
+
+	if l.file == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	return nil
+}
+
+// List returns up to limit recent entries newest first, optionally only
+// those with the given action. A limit of 0 returns all kept entries.
+func (l *Log) List(action string, limit int) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	list := make([]Entry, 0)
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		if action != "" && l.entries[i].Action != action {
+			continue
+		}
+		list = append(list, l.entries[i])
+		if limit > 0 && len(list) == limit {
+			break
+		}
+	}
+
+	return list
+}
+
+// Close closes the underlying file
+func (l *Log) Close() error {
+	if l.file != nil {
+		return l.file.Close()
+	}
+	return nil
+}
+
+func (l *Log) append(entry Entry) {
+	l.entries = append(l.entries, entry)
+	if len(l.entries) > maxRecentEntries {
+		l.entries = l.entries[len(l.entries)-maxRecentEntries:]
+	}
+}
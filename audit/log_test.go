@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLog_PersistsAndLists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	l, err := NewLog(path)
+	if err != nil {
+		t.Fatalf("NewLog failed: %v", err)
+	}
+	for _, action := range []string{"exec.start", "exec.end", "exec.start"} {
+		if err := l.Record(Entry{User: "admin", Action: action, Target: "abc123"}); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+	l.Close()
+
+	// Reopening loads the entries written before
+	l, err = NewLog(path)
+	if err != nil {
+		t.Fatalf("NewLog failed: %v", err)
+	}
+	defer l.Close()
+
+	all := l.List("", 0)
+	if len(all) != 3 || all[0].Action != "exec.start" || all[1].Action != "exec.end" {
+		t.Fatalf("List = %+v, want 3 entries newest first", all)
+	}
+	if all[0].Time.IsZero() || all[0].User != "admin" {
+		t.Errorf("unexpected entry: %+v", all[0])
+	}
+
+	if starts := l.List("exec.start", 1); len(starts) != 1 || starts[0].Action != "exec.start" {
+		t.Errorf("List(exec.start, 1) = %+v", starts)
+	}
+}
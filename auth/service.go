@@ -241,6 +241,11 @@ func (s *SessionStore) GetSessionCount() int {
 	return len(s.sessions)
 }
 
+// GetSessionCount returns the number of active sessions in the session store
+func GetSessionCount() int {
+	return sessionStore.GetSessionCount()
+}
+
 // hashToken creates a hash of the token for storage (simple hash for lookup)
 func hashToken(token string) string {
 	// For simplicity, using the token itself as key
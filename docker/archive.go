@@ -0,0 +1,198 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+)
+
+// PathStat describes a file or directory inside a container
+type PathStat struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	Mode       string    `json:"mode"`
+	ModTime    time.Time `json:"mod_time"`
+	IsDir      bool      `json:"is_dir"`
+	LinkTarget string    `json:"link_target,omitempty"`
+
+	mode os.FileMode
+}
+
+// IsRegular reports whether the path is a regular file, which can be
+// downloaded on its own rather than as an archive
+func (p PathStat) IsRegular() bool {
+	return p.mode.IsRegular()
+}
+
+func newPathStat(stat container.PathStat) PathStat {
+	return PathStat{
+		Name:       stat.Name,
+		Size:       stat.Size,
+		Mode:       stat.Mode.String(),
+		ModTime:    stat.Mtime,
+		IsDir:      stat.Mode.IsDir(),
+		LinkTarget: stat.LinkTarget,
+		mode:       stat.Mode,
+	}
+}
+
+// StatPath returns information about a path inside a container, without
+// following a final symlink
+func (s *Service) StatPath(ctx context.Context, containerID, path string) (PathStat, error) {
+	stat, err := s.client.ContainerStatPath(ctx, containerID, path)
+	if err != nil {
+		return PathStat{}, fmt.Errorf("failed to stat path: %w", err)
+	}
+	return newPathStat(stat), nil
+}
+
+// CopyFromContainer returns a tar archive of a file or directory inside a
+// container. The caller must close it.
+func (s *Service) CopyFromContainer(ctx context.Context, containerID, path string) (io.ReadCloser, PathStat, error) {
+	content, stat, err := s.client.CopyFromContainer(ctx, containerID, path)
+	if err != nil {
+		return nil, PathStat{}, fmt.Errorf("failed to copy from container: %w", err)
+	}
+	return content, newPathStat(stat), nil
+}
+
+// ReadContainerFile returns the contents of a regular file inside a
+// container. The caller must close it.
+func (s *Service) ReadContainerFile(ctx context.Context, containerID, path string) (io.ReadCloser, PathStat, error) {
+	content, stat, err := s.CopyFromContainer(ctx, containerID, path)
+	if err != nil {
+		return nil, PathStat{}, err
+	}
+	if !stat.IsRegular() {
+		content.Close()
+		return nil, PathStat{}, errdefs.InvalidParameter(fmt.Errorf("not a regular file: %s", path))
+	}
+
+	archive := tar.NewReader(content)
+	if _, err := archive.Next(); err != nil {
+		content.Close()
+		return nil, PathStat{}, fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{archive, content}, stat, nil
+}
+
+// CopyToContainer extracts a tar archive, optionally compressed, into a
+// directory inside a container. The directory must already exist.
+func (s *Service) CopyToContainer(ctx context.Context, containerID, path string, archive io.Reader) error {
+	if err := s.client.CopyToContainer(ctx, containerID, path, archive, container.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("failed to copy to container: %w", err)
+	}
+	return nil
+}
+
+// maxListingSize bounds how much of a directory's archive ListDirectory
+// reads, so listing a huge tree fails fast instead of streaming all of it
+const maxListingSize = 64 << 20
+
+// ListDirectory returns the entries of a directory inside a container,
+// directories first and then by name. Docker has no listing API, so this
+// reads the directory's archive and keeps its top level; directories whose
+// archive is larger than maxListingSize cannot be listed.
+func (s *Service) ListDirectory(ctx context.Context, containerID, path string) ([]PathStat, error) {
+	stat, err := s.StatPath(ctx, containerID, path)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.IsDir {
+		return nil, errdefs.InvalidParameter(fmt.Errorf("not a directory: %s", path))
+	}
+
+	content, _, err := s.CopyFromContainer(ctx, containerID, path)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	entries, err := listArchive(content, maxListingSize)
+	if errors.Is(err, errListingTooLarge) {
+		return nil, fmt.Errorf("directory too large to list: %s (list a subdirectory instead)", path)
+	}
+	return entries, err
+}
+
+var errListingTooLarge = errors.New("listing too large")
+
+// cappedReader fails with errListingTooLarge once more than n bytes are read
+type cappedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.n <= 0 {
+		return 0, errListingTooLarge
+	}
+	if int64(len(p)) > c.n {
+		p = p[:c.n]
+	}
+	n, err := c.r.Read(p)
+	c.n -= int64(n)
+	return n, err
+}
+
+// listArchive returns the direct children of the directory at the start of
+// a tar archive as Docker produces it: the directory itself comes first and
+// every other entry is beneath it. At most limit bytes of r are read.
+func listArchive(r io.Reader, limit int64) ([]PathStat, error) {
+	archive := tar.NewReader(&cappedReader{r: r, n: limit})
+
+	entries := make([]PathStat, 0)
+	root := ""
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		name := strings.TrimSuffix(header.Name, "/")
+		if root == "" {
+			root = name + "/"
+			continue
+		}
+
+		name, ok := strings.CutPrefix(name, root)
+		if !ok || name == "" || strings.Contains(name, "/") {
+			continue
+		}
+
+		info := header.FileInfo()
+		entries = append(entries, PathStat{
+			Name:       name,
+			Size:       header.Size,
+			Mode:       info.Mode().String(),
+			ModTime:    header.ModTime.UTC(),
+			IsDir:      info.IsDir(),
+			LinkTarget: header.Linkname,
+			mode:       info.Mode(),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
@@ -0,0 +1,59 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestListArchive_TopLevelOnly(t *testing.T) {
+	// A directory archive as Docker builds it, rooted at the directory's
+	// base name
+	var buf bytes.Buffer
+	archive := tar.NewWriter(&buf)
+	modTime := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	for _, h := range []tar.Header{
+		{Typeflag: tar.TypeDir, Name: "app/", Mode: 0755},
+		{Typeflag: tar.TypeReg, Name: "app/server.js", Mode: 0644, Size: 5},
+		{Typeflag: tar.TypeDir, Name: "app/public/", Mode: 0755},
+		{Typeflag: tar.TypeReg, Name: "app/public/index.html", Mode: 0644, Size: 3},
+		{Typeflag: tar.TypeSymlink, Name: "app/current", Linkname: "/srv/releases/42", Mode: 0777},
+	} {
+		h.ModTime = modTime
+		if err := archive.WriteHeader(&h); err != nil {
+			t.Fatalf("WriteHeader failed: %v", err)
+		}
+		archive.Write(bytes.Repeat([]byte("x"), int(h.Size)))
+	}
+	archive.Close()
+
+	data := buf.Bytes()
+	entries, err := listArchive(bytes.NewReader(data), maxListingSize)
+	if err != nil {
+		t.Fatalf("listArchive failed: %v", err)
+	}
+
+	var got []string
+	for _, e := range entries {
+		got = append(got, fmt.Sprintf("%s %s %d %v %s", e.Name, e.Mode, e.Size, e.IsDir, e.LinkTarget))
+	}
+	want := []string{
+		"public drwxr-xr-x 0 true ",
+		"current Lrwxrwxrwx 0 false /srv/releases/42",
+		"server.js -rw-r--r-- 5 false ",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got entries\n%v\nwant\n%v", got, want)
+	}
+	if !entries[0].ModTime.Equal(modTime) || !entries[2].IsRegular() {
+		t.Errorf("unexpected entry %+v", entries[2])
+	}
+
+	// The contents are read too, so a small limit is exceeded
+	if _, err := listArchive(bytes.NewReader(data), 1024); !errors.Is(err, errListingTooLarge) {
+		t.Errorf("expected errListingTooLarge, got %v", err)
+	}
+}
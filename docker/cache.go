@@ -0,0 +1,433 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-units"
+)
+
+// cacheRetryWait is how long the cache waits before seeding again after a
+// failure
+const cacheRetryWait = 5 * time.Second
+
+// maxConcurrentInspects bounds the inspect calls made while seeding
+const maxConcurrentInspects = 8
+
+// cacheActions are the container event actions that can change what the
+// cache holds; other actions such as attach or resize are ignored
+var cacheActions = map[string]bool{
+	"create": true, "start": true, "restart": true, "stop": true, "die": true,
+	"kill": true, "pause": true, "unpause": true, "oom": true, "health_status": true,
+	"rename": true, "update": true, "destroy": true,
+}
+
+// CachedContainer is a container as last seen by the cache
+type CachedContainer struct {
+	ID           string
+	Name         string
+	Image        string
+	State        string
+	ExitCode     int
+	OOMKilled    bool
+	Health       string
+	RestartCount int
+	Created      time.Time
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	Labels       map[string]string
+	Ports        []PortInfo
+
+	// StopRequested is set when the container was stopped, killed or
+	// restarted on request rather than exiting on its own
+	StopRequested bool
+}
+
+// Project returns the compose project the container belongs to, if any
+func (c CachedContainer) Project() string {
+	return c.Labels["com.docker.compose.project"]
+}
+
+// Service returns the compose service the container belongs to, if any
+func (c CachedContainer) Service() string {
+	return c.Labels["com.docker.compose.service"]
+}
+
+// Status describes the state the way `docker ps` does, such as
+// "Up 5 minutes (healthy)" or "Exited (1) 2 hours ago"
+func (c CachedContainer) Status() string {
+	now := time.Now()
+
+	switch c.State {
+	case "running":
+		status := "Up " + units.HumanDuration(now.Sub(c.StartedAt))
+		switch c.Health {
+		case "starting":
+			status += " (health: starting)"
+		case "healthy", "unhealthy":
+			status += " (" + c.Health + ")"
+		}
+		return status
+	case "paused":
+		return "Up " + units.HumanDuration(now.Sub(c.StartedAt)) + " (Paused)"
+	case "restarting":
+		return fmt.Sprintf("Restarting (%d) %s ago", c.ExitCode, units.HumanDuration(now.Sub(c.FinishedAt)))
+	case "removing":
+		return "Removal In Progress"
+	case "dead":
+		return "Dead"
+	case "created":
+		return "Created"
+	case "exited":
+		if c.FinishedAt.IsZero() {
+			return "Created"
+		}
+		return fmt.Sprintf("Exited (%d) %s ago", c.ExitCode, units.HumanDuration(now.Sub(c.FinishedAt)))
+	default:
+		return c.State
+	}
+}
+
+// ContainerCache keeps every container in memory, indexed by compose
+// project and service. It is seeded with one ContainerList call and then
+// kept current from Docker events, so listing containers does not hit the
+// Docker API.
+type ContainerCache struct {
+	client *client.Client
+
+	mu     sync.RWMutex
+	synced bool
+	// containers is keyed by short ID
+	containers map[string]CachedContainer
+	// index maps project -> service -> set of short IDs
+	index map[string]map[string]map[string]struct{}
+	stops *StopTracker
+}
+
+func newContainerCache(cli *client.Client) *ContainerCache {
+	return &ContainerCache{
+		client:     cli,
+		containers: make(map[string]CachedContainer),
+		index:      make(map[string]map[string]map[string]struct{}),
+		stops:      NewStopTracker(),
+	}
+}
+
+// Synced reports whether the cache is seeded and following events. Until
+// it is, callers should ask Docker directly.
+func (c *ContainerCache) Synced() bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.synced
+}
+
+// Get returns a container by its full or 12 character ID, or its name
+func (c *ContainerCache) Get(idOrName string) (CachedContainer, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if ctr, ok := c.containers[shortID(idOrName)]; ok {
+		return ctr, true
+	}
+	for _, ctr := range c.containers {
+		if ctr.Name == idOrName {
+			return ctr, true
+		}
+	}
+	return CachedContainer{}, false
+}
+
+// List returns every container, newest first
+func (c *ContainerCache) List() []CachedContainer {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result := make([]CachedContainer, 0, len(c.containers))
+	for _, ctr := range c.containers {
+		result = append(result, ctr)
+	}
+	sortNewestFirst(result)
+
+	return result
+}
+
+// Project returns the containers of a compose project, newest first
+func (c *ContainerCache) Project(project string) []CachedContainer {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var result []CachedContainer
+	for _, ids := range c.index[project] {
+		for id := range ids {
+			result = append(result, c.containers[id])
+		}
+	}
+	sortNewestFirst(result)
+
+	return result
+}
+
+// Service returns the containers of a compose service, newest first
+func (c *ContainerCache) Service(project, service string) []CachedContainer {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var result []CachedContainer
+	for id := range c.index[project][service] {
+		result = append(result, c.containers[id])
+	}
+	sortNewestFirst(result)
+
+	return result
+}
+
+// run keeps the cache current until ctx is done. It subscribes to events
+// before seeding so nothing that happens in between is missed, and seeds
+// again if the subscription falls behind.
+func (c *ContainerCache) run(ctx context.Context, hub *eventHub) {
+	for {
+		events, unsubscribe := hub.subscribe(EventFilter{Types: []string{"container"}})
+
+		if err := c.seed(ctx); err != nil {
+			unsubscribe()
+			log.Printf("Failed to seed container cache: %v", err)
+			select {
+			case <-time.After(cacheRetryWait):
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+	follow:
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					log.Printf("Container cache fell behind on events, reseeding")
+					break follow
+				}
+				c.apply(ctx, event)
+			case <-ctx.Done():
+				unsubscribe()
+				return
+			}
+		}
+
+		c.mu.Lock()
+		c.synced = false
+		c.mu.Unlock()
+	}
+}
+
+// seed replaces the cache contents with the containers Docker reports
+func (c *ContainerCache) seed(ctx context.Context) error {
+	summaries, err := c.client.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+	containers := make([]CachedContainer, 0, len(summaries))
+	slots := make(chan struct{}, maxConcurrentInspects)
+	for _, summary := range summaries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			ctr, err := c.inspect(ctx, summary.ID)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				// Removed since it was listed
+				if client.IsErrNotFound(err) {
+					return
+				}
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			// Kills from before the cache started were not seen, so an exit
+			// by SIGTERM or SIGKILL is taken as a requested stop
+			ctr.StopRequested = c.stops.Requested(summary.ID) ||
+				(ctr.State == "exited" && !ctr.OOMKilled && (ctr.ExitCode == 137 || ctr.ExitCode == 143))
+			containers = append(containers, ctr)
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.containers = make(map[string]CachedContainer, len(containers))
+	c.index = make(map[string]map[string]map[string]struct{})
+	for _, ctr := range containers {
+		c.put(ctr)
+	}
+	c.synced = true
+
+	return nil
+}
+
+// apply updates the cache for a container event by inspecting the
+// container again, or dropping it once it is gone
+func (c *ContainerCache) apply(ctx context.Context, event Event) {
+	if !cacheActions[event.Action] {
+		return
+	}
+	c.stops.Observe(event)
+
+	if event.Action == "destroy" {
+		c.mu.Lock()
+		c.remove(event.ID)
+		c.mu.Unlock()
+		return
+	}
+
+	ctr, err := c.inspect(ctx, event.ID)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			c.remove(event.ID)
+		} else {
+			log.Printf("Failed to refresh container %s in cache: %v", event.ID, err)
+		}
+		return
+	}
+
+	ctr.StopRequested = c.stops.Requested(event.ID)
+	c.remove(ctr.ID)
+	c.put(ctr)
+}
+
+// put adds a container; callers must hold the lock
+func (c *ContainerCache) put(ctr CachedContainer) {
+	c.containers[ctr.ID] = ctr
+
+	project, service := ctr.Project(), ctr.Service()
+	if project == "" {
+		return
+	}
+	if c.index[project] == nil {
+		c.index[project] = make(map[string]map[string]struct{})
+	}
+	if c.index[project][service] == nil {
+		c.index[project][service] = make(map[string]struct{})
+	}
+	c.index[project][service][ctr.ID] = struct{}{}
+}
+
+// remove drops a container and its index entries; callers must hold the
+// lock
+func (c *ContainerCache) remove(id string) {
+	ctr, ok := c.containers[id]
+	if !ok {
+		return
+	}
+	delete(c.containers, id)
+
+	project, service := ctr.Project(), ctr.Service()
+	if project == "" {
+		return
+	}
+	delete(c.index[project][service], id)
+	if len(c.index[project][service]) == 0 {
+		delete(c.index[project], service)
+	}
+	if len(c.index[project]) == 0 {
+		delete(c.index, project)
+	}
+}
+
+func (c *ContainerCache) inspect(ctx context.Context, id string) (CachedContainer, error) {
+	inspect, err := c.client.ContainerInspect(ctx, id)
+	if err != nil {
+		return CachedContainer{}, err
+	}
+
+	ctr := CachedContainer{
+		ID:           shortID(inspect.ID),
+		Name:         strings.TrimPrefix(inspect.Name, "/"),
+		RestartCount: inspect.RestartCount,
+		Ports:        make([]PortInfo, 0),
+	}
+	ctr.Created, _ = time.Parse(time.RFC3339Nano, inspect.Created)
+	if inspect.Config != nil {
+		ctr.Image = inspect.Config.Image
+		ctr.Labels = inspect.Config.Labels
+	}
+	if inspect.State != nil {
+		ctr.State = inspect.State.Status
+		ctr.ExitCode = inspect.State.ExitCode
+		ctr.OOMKilled = inspect.State.OOMKilled
+		ctr.StartedAt, _ = time.Parse(time.RFC3339Nano, inspect.State.StartedAt)
+		ctr.FinishedAt, _ = time.Parse(time.RFC3339Nano, inspect.State.FinishedAt)
+		if inspect.State.Health != nil {
+			ctr.Health = inspect.State.Health.Status
+		}
+	}
+
+	// Published ports are listed once per binding; IPv4 and IPv6 bindings of
+	// the same port collapse into one entry as they do in `docker ps`
+	if inspect.NetworkSettings != nil {
+		seen := make(map[PortInfo]bool)
+		for port, bindings := range inspect.NetworkSettings.Ports {
+			infos := []PortInfo{{Private: port.Int(), Type: port.Proto()}}
+			if len(bindings) > 0 {
+				infos = infos[:0]
+				for _, binding := range bindings {
+					var public int
+					fmt.Sscanf(binding.HostPort, "%d", &public)
+					infos = append(infos, PortInfo{Private: port.Int(), Public: public, Type: port.Proto()})
+				}
+			}
+			for _, info := range infos {
+				if !seen[info] {
+					seen[info] = true
+					ctr.Ports = append(ctr.Ports, info)
+				}
+			}
+		}
+		sort.Slice(ctr.Ports, func(i, j int) bool {
+			if ctr.Ports[i].Private != ctr.Ports[j].Private {
+				return ctr.Ports[i].Private < ctr.Ports[j].Private
+			}
+			return ctr.Ports[i].Public < ctr.Ports[j].Public
+		})
+	}
+
+	return ctr, nil
+}
+
+func sortNewestFirst(containers []CachedContainer) {
+	sort.Slice(containers, func(i, j int) bool {
+		if !containers[i].Created.Equal(containers[j].Created) {
+			return containers[i].Created.After(containers[j].Created)
+		}
+		return containers[i].Name < containers[j].Name
+	})
+}
@@ -0,0 +1,74 @@
+package docker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestContainerCache_Index(t *testing.T) {
+	c := newContainerCache(nil)
+	labels := func(project, service string) map[string]string {
+		return map[string]string{
+			"com.docker.compose.project": project,
+			"com.docker.compose.service": service,
+		}
+	}
+
+	now := time.Now()
+	c.put(CachedContainer{ID: "aaaaaaaaaaaa", Name: "site-web-1", Created: now.Add(-time.Hour), Labels: labels("site", "web")})
+	c.put(CachedContainer{ID: "bbbbbbbbbbbb", Name: "site-web-2", Created: now, Labels: labels("site", "web")})
+	c.put(CachedContainer{ID: "cccccccccccc", Name: "site-db-1", Created: now.Add(-2 * time.Hour), Labels: labels("site", "db")})
+	c.put(CachedContainer{ID: "dddddddddddd", Name: "standalone", Created: now})
+
+	if got := c.Project("site"); len(got) != 3 || got[0].Name != "site-web-2" || got[2].Name != "site-db-1" {
+		t.Errorf("Project(site) = %+v", got)
+	}
+	if got := c.Service("site", "web"); len(got) != 2 {
+		t.Errorf("Service(site, web) returned %d containers", len(got))
+	}
+	if got := c.List(); len(got) != 4 {
+		t.Errorf("List returned %d containers", len(got))
+	}
+	if ctr, ok := c.Get("cccccccccccc0123456789"); !ok || ctr.Name != "site-db-1" {
+		t.Errorf("Get by full ID = %+v, %v", ctr, ok)
+	}
+	if ctr, ok := c.Get("standalone"); !ok || ctr.ID != "dddddddddddd" {
+		t.Errorf("Get by name = %+v, %v", ctr, ok)
+	}
+
+	c.remove("cccccccccccc")
+	if got := c.Service("site", "db"); len(got) != 0 {
+		t.Errorf("Service(site, db) after remove = %+v", got)
+	}
+	if _, ok := c.index["site"]["db"]; ok {
+		t.Error("expected empty service index to be dropped")
+	}
+	c.remove("aaaaaaaaaaaa")
+	c.remove("bbbbbbbbbbbb")
+	if _, ok := c.index["site"]; ok {
+		t.Error("expected empty project index to be dropped")
+	}
+}
+
+func TestCachedContainer_Status(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		container CachedContainer
+		want      string
+	}{
+		{CachedContainer{State: "running", StartedAt: now.Add(-5 * time.Minute)}, "Up 5 minutes"},
+		{CachedContainer{State: "running", Health: "unhealthy", StartedAt: now.Add(-2 * time.Hour)}, "Up 2 hours (unhealthy)"},
+		{CachedContainer{State: "running", Health: "starting", StartedAt: now.Add(-3 * time.Second)}, "Up 3 seconds (health: starting)"},
+		{CachedContainer{State: "paused", StartedAt: now.Add(-time.Hour)}, "Up About an hour (Paused)"},
+		{CachedContainer{State: "exited", ExitCode: 137, FinishedAt: now.Add(-3 * 24 * time.Hour)}, "Exited (137) 3 days ago"},
+		{CachedContainer{State: "exited"}, "Created"},
+		{CachedContainer{State: "created"}, "Created"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.container.Status(); got != tt.want {
+			t.Errorf("Status() for %+v = %q, want %q", tt.container, got, tt.want)
+		}
+	}
+}
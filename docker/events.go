@@ -0,0 +1,200 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// eventBufferSize is how many events a subscriber may fall behind before it
+// is dropped
+const eventBufferSize = 256
+
+// eventRetryWait is how long to wait before reconnecting to the Docker
+// events API after the stream fails
+const eventRetryWait = 2 * time.Second
+
+// Event is a Docker event about a container, image or network
+type Event struct {
+	Type   string `json:"type"`
+	Action string `json:"action"`
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	// Project and Service are set for containers created by compose
+	Project    string            `json:"project,omitempty"`
+	Service    string            `json:"service,omitempty"`
+	Time       time.Time         `json:"time"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// EventFilter selects events; empty fields match everything
+type EventFilter struct {
+	Projects []string
+	Services []string
+	// Types holds event types ("container"), actions ("die") or both
+	// ("container.die")
+	Types []string
+}
+
+// Match reports whether an event passes the filter
+func (f EventFilter) Match(e Event) bool {
+	if len(f.Projects) > 0 && !slices.Contains(f.Projects, e.Project) {
+		return false
+	}
+	if len(f.Services) > 0 && !slices.Contains(f.Services, e.Service) {
+		return false
+	}
+	if len(f.Types) > 0 {
+		return slices.ContainsFunc(f.Types, func(t string) bool {
+			return t == e.Type || t == e.Action || t == e.Type+"."+e.Action
+		})
+	}
+	return true
+}
+
+// eventHub fans events out to subscribers
+type eventHub struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]EventFilter
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subscribers: make(map[chan Event]EventFilter)}
+}
+
+func (h *eventHub) subscribe(filter EventFilter) (<-chan Event, func()) {
+	ch := make(chan Event, eventBufferSize)
+
+	h.mu.Lock()
+	h.subscribers[ch] = filter
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// publish delivers an event to every matching subscriber. A subscriber
+// whose buffer is full is dropped and its channel closed, so it can tell
+// that it missed events rather than silently falling out of date.
+func (h *eventHub) publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch, filter := range h.subscribers {
+		if !filter.Match(e) {
+			continue
+		}
+		select {
+		case ch <- e:
+		default:
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// SubscribeEvents returns a channel receiving the events that match filter
+// and a function that unsubscribes. The channel is closed when unsubscribed
+// or if the subscriber falls too far behind. Events only flow while
+// WatchEvents is running.
+func (s *Service) SubscribeEvents(filter EventFilter) (<-chan Event, func()) {
+	return s.events.subscribe(filter)
+}
+
+// WatchEvents reads container, image and network events from Docker and
+// publishes them to subscribers until ctx is done. When the stream breaks
+// it reconnects and resumes from the last event received. It also keeps the
+// container cache current.
+func (s *Service) WatchEvents(ctx context.Context) {
+	go s.containers.run(ctx, s.events)
+
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("type", string(events.ContainerEventType))
+	filterArgs.Add("type", string(events.ImageEventType))
+	filterArgs.Add("type", string(events.NetworkEventType))
+
+	since := ""
+	for {
+		messages, errs := s.client.Events(ctx, events.ListOptions{
+			Since:   since,
+			Filters: filterArgs,
+		})
+
+	read:
+		for {
+			select {
+			case message := <-messages:
+				since = fmt.Sprintf("%d.%09d", message.TimeNano/int64(time.Second), message.TimeNano%int64(time.Second))
+				if event, ok := toEvent(message); ok {
+					s.events.publish(event)
+				}
+			case err := <-errs:
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("Docker events stream failed, reconnecting: %v", err)
+				break read
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-time.After(eventRetryWait):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// toEvent converts a Docker event message. Exec events, which health
+// checks produce on every run, are left out.
+func toEvent(message events.Message) (Event, bool) {
+	action := string(message.Action)
+	if strings.HasPrefix(action, "exec_") {
+		return Event{}, false
+	}
+
+	attributes := make(map[string]string, len(message.Actor.Attributes))
+	for key, value := range message.Actor.Attributes {
+		attributes[key] = value
+	}
+
+	// Some actions carry a detail after a colon, such as
+	// "health_status: unhealthy"; it is moved into the attributes
+	if name, detail, ok := strings.Cut(action, ":"); ok {
+		action = name
+		attributes[name] = strings.TrimSpace(detail)
+	}
+
+	event := Event{
+		Type:       string(message.Type),
+		Action:     action,
+		ID:         message.Actor.ID,
+		Name:       attributes["name"],
+		Project:    attributes["com.docker.compose.project"],
+		Service:    attributes["com.docker.compose.service"],
+		Time:       time.Unix(0, message.TimeNano).UTC(),
+		Attributes: attributes,
+	}
+	delete(attributes, "name")
+
+	if message.Type == events.ContainerEventType {
+		event.ID = shortID(event.ID)
+	}
+
+	return event, true
+}
@@ -0,0 +1,89 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/events"
+)
+
+func TestToEvent(t *testing.T) {
+	event, ok := toEvent(events.Message{
+		Type:   events.ContainerEventType,
+		Action: "health_status: unhealthy",
+		Actor: events.Actor{
+			ID: "0123456789abcdef0123",
+			Attributes: map[string]string{
+				"name":                       "site-web-1",
+				"com.docker.compose.project": "site",
+				"com.docker.compose.service": "web",
+			},
+		},
+		TimeNano: 1736937000123456789,
+	})
+	if !ok {
+		t.Fatal("expected health event to be kept")
+	}
+	if event.Action != "health_status" || event.Attributes["health_status"] != "unhealthy" {
+		t.Errorf("unexpected action %q with attributes %v", event.Action, event.Attributes)
+	}
+	if event.ID != "0123456789ab" || event.Name != "site-web-1" || event.Project != "site" || event.Service != "web" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if event.Time.UnixNano() != 1736937000123456789 {
+		t.Errorf("Time = %v", event.Time)
+	}
+
+	if _, ok := toEvent(events.Message{Type: events.ContainerEventType, Action: "exec_start: /bin/sh -c healthcheck"}); ok {
+		t.Error("expected exec event to be left out")
+	}
+}
+
+func TestEventFilter_Match(t *testing.T) {
+	event := Event{Type: "container", Action: "die", Project: "site", Service: "web"}
+
+	tests := []struct {
+		filter EventFilter
+		want   bool
+	}{
+		{EventFilter{}, true},
+		{EventFilter{Projects: []string{"other", "site"}}, true},
+		{EventFilter{Projects: []string{"other"}}, false},
+		{EventFilter{Services: []string{"db"}}, false},
+		{EventFilter{Types: []string{"container"}}, true},
+		{EventFilter{Types: []string{"die"}}, true},
+		{EventFilter{Types: []string{"container.die"}}, true},
+		{EventFilter{Types: []string{"container.start", "image"}}, false},
+		{EventFilter{Projects: []string{"site"}, Types: []string{"oom"}}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filter.Match(event); got != tt.want {
+			t.Errorf("%+v.Match = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestEventHub_DropsSlowSubscribers(t *testing.T) {
+	hub := newEventHub()
+
+	fast, unsubscribe := hub.subscribe(EventFilter{Types: []string{"start"}})
+	defer unsubscribe()
+	slow, _ := hub.subscribe(EventFilter{})
+
+	for range eventBufferSize {
+		hub.publish(Event{Type: "container", Action: "die"})
+	}
+	hub.publish(Event{Type: "container", Action: "start"})
+
+	if len(fast) != 1 {
+		t.Errorf("filtered subscriber got %d events, want 1", len(fast))
+	}
+
+	received := 0
+	for range slow {
+		received++
+	}
+	if received != eventBufferSize {
+		t.Errorf("slow subscriber got %d events before being dropped, want %d", received, eventBufferSize)
+	}
+}
@@ -0,0 +1,122 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+)
+
+// ExecOptions describes a command to run inside a container
+type ExecOptions struct {
+	Cmd        []string
+	User       string
+	WorkingDir string
+	Env        []string
+	// Cols and Rows set the initial terminal size; zero leaves Docker's default
+	Cols uint
+	Rows uint
+}
+
+// ExecSession is an attached exec instance with a TTY. Reads return the
+// terminal output and writes are sent to its input.
+type ExecSession struct {
+	ID string
+
+	conn types.HijackedResponse
+}
+
+func (e *ExecSession) Read(p []byte) (int, error) {
+	return e.conn.Reader.Read(p)
+}
+
+func (e *ExecSession) Write(p []byte) (int, error) {
+	return e.conn.Conn.Write(p)
+}
+
+// CloseWrite signals end of input to the process
+func (e *ExecSession) CloseWrite() error {
+	return e.conn.CloseWrite()
+}
+
+// Close detaches from the exec instance. The process itself keeps running
+// until it exits on its own, which for a shell happens once its input is
+// closed.
+func (e *ExecSession) Close() error {
+	e.conn.Close()
+	return nil
+}
+
+var _ io.ReadWriteCloser = (*ExecSession)(nil)
+
+// CreateExec creates a TTY exec instance in a running container and returns
+// its ID. The command does not start until it is attached.
+func (s *Service) CreateExec(ctx context.Context, containerID string, opts ExecOptions) (string, error) {
+	if len(opts.Cmd) == 0 {
+		return "", errdefs.InvalidParameter(fmt.Errorf("exec command is required"))
+	}
+
+	resp, err := s.client.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          opts.Cmd,
+		User:         opts.User,
+		WorkingDir:   opts.WorkingDir,
+		Env:          opts.Env,
+		Tty:          true,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		ConsoleSize:  consoleSize(opts.Cols, opts.Rows),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	return resp.ID, nil
+}
+
+// AttachExec starts an exec instance created by CreateExec and attaches to
+// its terminal
+func (s *Service) AttachExec(ctx context.Context, execID string, cols, rows uint) (*ExecSession, error) {
+	conn, err := s.client.ContainerExecAttach(ctx, execID, container.ExecAttachOptions{
+		Tty:         true,
+		ConsoleSize: consoleSize(cols, rows),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach to exec: %w", err)
+	}
+
+	return &ExecSession{ID: execID, conn: conn}, nil
+}
+
+// ResizeExec changes the terminal size of a running exec instance
+func (s *Service) ResizeExec(ctx context.Context, execID string, cols, rows uint) error {
+	if err := s.client.ContainerExecResize(ctx, execID, container.ResizeOptions{
+		Width:  cols,
+		Height: rows,
+	}); err != nil {
+		return fmt.Errorf("failed to resize exec: %w", err)
+	}
+	return nil
+}
+
+// ExecExitCode reports whether an exec instance is still running and, once
+// it has exited, its exit code
+func (s *Service) ExecExitCode(ctx context.Context, execID string) (running bool, exitCode int, err error) {
+	inspect, err := s.client.ContainerExecInspect(ctx, execID)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to inspect exec: %w", err)
+	}
+	return inspect.Running, inspect.ExitCode, nil
+}
+
+// consoleSize converts a width and height into Docker's [height, width]
+// pair, or nil when either is unset
+func consoleSize(cols, rows uint) *[2]uint {
+	if cols == 0 || rows == 0 {
+		return nil
+	}
+	return &[2]uint{rows, cols}
+}
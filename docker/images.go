@@ -0,0 +1,293 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// PullOptions selects what to pull and how to authenticate
+type PullOptions struct {
+	// Platform such as "linux/arm64"; empty pulls the daemon's platform
+	Platform string
+	Username string
+	Password string
+}
+
+// PullProgress is a status update while pulling an image. Updates with an
+// ID are about a single layer; the others are about the whole image.
+type PullProgress struct {
+	ID      string `json:"id,omitempty"`
+	Status  string `json:"status"`
+	Current int64  `json:"current,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+}
+
+// ImageDeleteItem is an image reference removed or image layer deleted
+type ImageDeleteItem struct {
+	Untagged string `json:"untagged,omitempty"`
+	Deleted  string `json:"deleted,omitempty"`
+}
+
+// ImagePruneReport lists what pruning removed
+type ImagePruneReport struct {
+	Deleted        []ImageDeleteItem `json:"deleted"`
+	SpaceReclaimed uint64            `json:"space_reclaimed"`
+}
+
+// ImageDetails is the configuration and layer history of an image
+type ImageDetails struct {
+	ID           string             `json:"id"`
+	RepoTags     []string           `json:"repo_tags"`
+	RepoDigests  []string           `json:"repo_digests"`
+	Created      string             `json:"created"`
+	Size         int64              `json:"size"`
+	Architecture string             `json:"architecture"`
+	Os           string             `json:"os"`
+	Author       string             `json:"author,omitempty"`
+	Config       ImageConfig        `json:"config"`
+	History      []ImageHistoryItem `json:"history"`
+}
+
+// ImageConfig is the default configuration of containers created from an
+// image
+type ImageConfig struct {
+	User         string            `json:"user,omitempty"`
+	Env          []string          `json:"env"`
+	Entrypoint   []string          `json:"entrypoint"`
+	Cmd          []string          `json:"cmd"`
+	WorkingDir   string            `json:"working_dir,omitempty"`
+	ExposedPorts []string          `json:"exposed_ports"`
+	Volumes      []string          `json:"volumes"`
+	Labels       map[string]string `json:"labels"`
+	StopSignal   string            `json:"stop_signal,omitempty"`
+}
+
+// ImageHistoryItem is one step of the build that produced an image, newest
+// first
+type ImageHistoryItem struct {
+	ID        string `json:"id,omitempty"`
+	Created   int64  `json:"created"`
+	CreatedBy string `json:"created_by"`
+	Size      int64  `json:"size"`
+	Comment   string `json:"comment,omitempty"`
+}
+
+// PullImage pulls an image, calling fn with each progress update, and
+// returns its ID. A reference without a tag or digest pulls "latest".
+func (s *Service) PullImage(ctx context.Context, ref string, opts PullOptions, fn func(PullProgress) error) (string, error) {
+	pullOptions := image.PullOptions{Platform: opts.Platform}
+	if opts.Username != "" {
+		auth, err := registry.EncodeAuthConfig(registry.AuthConfig{
+			Username: opts.Username,
+			Password: opts.Password,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to encode credentials: %w", err)
+		}
+		pullOptions.RegistryAuth = auth
+	}
+
+	// The client rejects a malformed reference with an untyped error, so
+	// check it here to report it as invalid
+	if _, err := reference.ParseNormalizedNamed(ref); err != nil {
+		return "", errdefs.InvalidParameter(fmt.Errorf("failed to pull image: %w", err))
+	}
+
+	body, err := s.client.ImagePull(ctx, ref, pullOptions)
+	if err != nil {
+		return "", fmt.Errorf("failed to pull image: %w", err)
+	}
+	defer body.Close()
+
+	err = decodeMessages(body, func(msg jsonmessage.JSONMessage) error {
+		if msg.Status == "" {
+			return nil
+		}
+		progress := PullProgress{ID: msg.ID, Status: msg.Status}
+		if msg.Progress != nil {
+			progress.Current = msg.Progress.Current
+			progress.Total = msg.Progress.Total
+		}
+		return fn(progress)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to pull image: %w", err)
+	}
+
+	inspect, err := s.client.ImageInspect(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect image: %w", err)
+	}
+	return shortID(strings.TrimPrefix(inspect.ID, "sha256:")), nil
+}
+
+// decodeMessages reads the JSON message stream of a pull, push or build,
+// calling fn for each message. An error message ends the stream with that
+// error.
+func decodeMessages(r io.Reader, fn func(jsonmessage.JSONMessage) error) error {
+	decoder := json.NewDecoder(r)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != nil {
+			return errors.New(msg.Error.Message)
+		}
+		if msg.ErrorMessage != "" {
+			return errors.New(msg.ErrorMessage)
+		}
+		if err := fn(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// InspectImage returns an image's configuration and history
+func (s *Service) InspectImage(ctx context.Context, imageID string) (*ImageDetails, error) {
+	inspect, err := s.client.ImageInspect(ctx, imageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image: %w", err)
+	}
+
+	history, err := s.client.ImageHistory(ctx, inspect.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image history: %w", err)
+	}
+
+	details := &ImageDetails{
+		ID:           strings.TrimPrefix(inspect.ID, "sha256:"),
+		RepoTags:     inspect.RepoTags,
+		RepoDigests:  inspect.RepoDigests,
+		Created:      inspect.Created,
+		Size:         inspect.Size,
+		Architecture: inspect.Architecture,
+		Os:           inspect.Os,
+		Author:       inspect.Author,
+		History:      make([]ImageHistoryItem, 0, len(history)),
+	}
+	if details.RepoTags == nil {
+		details.RepoTags = []string{}
+	}
+	if details.RepoDigests == nil {
+		details.RepoDigests = []string{}
+	}
+
+	details.Config = ImageConfig{
+		Env:          []string{},
+		Entrypoint:   []string{},
+		Cmd:          []string{},
+		ExposedPorts: []string{},
+		Volumes:      []string{},
+		Labels:       map[string]string{},
+	}
+	if config := inspect.Config; config != nil {
+		details.Config.User = config.User
+		details.Config.WorkingDir = config.WorkingDir
+		details.Config.StopSignal = config.StopSignal
+		if config.Env != nil {
+			details.Config.Env = config.Env
+		}
+		if config.Entrypoint != nil {
+			details.Config.Entrypoint = config.Entrypoint
+		}
+		if config.Cmd != nil {
+			details.Config.Cmd = config.Cmd
+		}
+		if config.Labels != nil {
+			details.Config.Labels = config.Labels
+		}
+		for port := range config.ExposedPorts {
+			details.Config.ExposedPorts = append(details.Config.ExposedPorts, port)
+		}
+		sort.Strings(details.Config.ExposedPorts)
+		for volume := range config.Volumes {
+			details.Config.Volumes = append(details.Config.Volumes, volume)
+		}
+		sort.Strings(details.Config.Volumes)
+	}
+
+	for _, item := range history {
+		id := ""
+		if item.ID != "<missing>" {
+			id = strings.TrimPrefix(item.ID, "sha256:")
+		}
+		details.History = append(details.History, ImageHistoryItem{
+			ID:        id,
+			Created:   item.Created,
+			CreatedBy: item.CreatedBy,
+			Size:      item.Size,
+			Comment:   item.Comment,
+		})
+	}
+
+	return details, nil
+}
+
+// RemoveImage removes an image by ID or reference. Removing a reference
+// only untags it while other references remain. Force removes an image
+// used by stopped containers or tagged more than once.
+func (s *Service) RemoveImage(ctx context.Context, imageID string, force bool) ([]ImageDeleteItem, error) {
+	removed, err := s.client.ImageRemove(ctx, imageID, image.RemoveOptions{
+		Force:         force,
+		PruneChildren: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove image: %w", err)
+	}
+	return deleteItems(removed), nil
+}
+
+// TagImage adds a reference, such as "registry.example.com/app:1.0", to an
+// image
+func (s *Service) TagImage(ctx context.Context, imageID, target string) error {
+	if err := s.client.ImageTag(ctx, imageID, target); err != nil {
+		return fmt.Errorf("failed to tag image: %w", err)
+	}
+	return nil
+}
+
+// PruneImages removes dangling images, or with all every image not used by
+// a container
+func (s *Service) PruneImages(ctx context.Context, all bool) (*ImagePruneReport, error) {
+	pruneFilters := filters.NewArgs()
+	if all {
+		pruneFilters.Add("dangling", "false")
+	}
+
+	report, err := s.client.ImagesPrune(ctx, pruneFilters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune images: %w", err)
+	}
+
+	return &ImagePruneReport{
+		Deleted:        deleteItems(report.ImagesDeleted),
+		SpaceReclaimed: report.SpaceReclaimed,
+	}, nil
+}
+
+func deleteItems(responses []image.DeleteResponse) []ImageDeleteItem {
+	items := make([]ImageDeleteItem, 0, len(responses))
+	for _, r := range responses {
+		items = append(items, ImageDeleteItem{
+			Untagged: r.Untagged,
+			Deleted:  strings.TrimPrefix(r.Deleted, "sha256:"),
+		})
+	}
+	return items
+}
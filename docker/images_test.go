@@ -0,0 +1,36 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+func TestDecodeMessages_StopsAtError(t *testing.T) {
+	stream := `{"status":"Pulling from library/nginx","id":"1.27"}
+{"status":"Downloading","progressDetail":{"current":1024,"total":4096},"id":"a1b2c3"}
+{"errorDetail":{"message":"write /var/lib/docker/tmp: no space left on device"},"error":"write /var/lib/docker/tmp: no space left on device"}
+{"status":"Pull complete","id":"a1b2c3"}
+`
+
+	var statuses []string
+	var current, total int64
+	err := decodeMessages(strings.NewReader(stream), func(msg jsonmessage.JSONMessage) error {
+		statuses = append(statuses, msg.Status)
+		if msg.Progress != nil {
+			current, total = msg.Progress.Current, msg.Progress.Total
+		}
+		return nil
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "no space left on device") {
+		t.Errorf("expected the stream's error, got %v", err)
+	}
+	if len(statuses) != 2 || statuses[1] != "Downloading" {
+		t.Errorf("got statuses %q", statuses)
+	}
+	if current != 1024 || total != 4096 {
+		t.Errorf("got progress %d/%d", current, total)
+	}
+}
@@ -0,0 +1,243 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// LogOptions selects which log lines to return
+type LogOptions struct {
+	// Tail is the number of lines to show from the end of each container's
+	// log, or "all"
+	Tail string
+	// Since and Until accept an RFC 3339 timestamp, a Unix timestamp or a
+	// duration relative to now such as "10m"
+	Since      string
+	Until      string
+	Timestamps bool
+	Follow     bool
+	// OnOpen, if set, is called once Docker has accepted the request and
+	// before the first line, which may be a long time later with Follow
+	OnOpen func()
+}
+
+// LogLine is a single line of container output
+type LogLine struct {
+	Container string `json:"container,omitempty"`
+	Stream    string `json:"stream"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Message   string `json:"message"`
+
+	// time orders lines from different containers
+	time time.Time
+}
+
+// StreamContainerLogs calls fn for each log line of a container, in order.
+// With Follow it keeps streaming until the container stops, ctx is done or
+// fn returns an error.
+func (s *Service) StreamContainerLogs(ctx context.Context, containerID string, opts LogOptions, fn func(LogLine) error) error {
+	inspect, err := s.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	return s.streamLogs(ctx, inspect.ID, "", inspect.Config.Tty, opts, fn)
+}
+
+// StreamServiceLogs calls fn for the log lines of every container of a
+// compose service, interleaved by timestamp. Without Follow the lines are
+// sorted before being returned; with Follow they are delivered as they
+// arrive.
+func (s *Service) StreamServiceLogs(ctx context.Context, projectName, serviceName string, opts LogOptions, fn func(LogLine) error) error {
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", fmt.Sprintf("com.docker.compose.project=%s", projectName))
+	filterArgs.Add("label", fmt.Sprintf("com.docker.compose.service=%s", serviceName))
+
+	containers, err := s.client.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filterArgs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	if len(containers) == 0 {
+		return errdefs.NotFound(fmt.Errorf("no containers found for service %s in project %s", serviceName, projectName))
+	}
+
+	// The containers are opened in their own goroutines, so the service
+	// counts as open once they have all been inspected
+	onOpen := opts.OnOpen
+	opts.OnOpen = nil
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Each container is read in its own goroutine; fn is only ever called
+	// from this one
+	lines := make(chan LogLine)
+	errs := make(chan error, len(containers))
+	var wg sync.WaitGroup
+	for _, c := range containers {
+		inspect, err := s.client.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			return fmt.Errorf("failed to inspect container: %w", err)
+		}
+
+		name := strings.TrimPrefix(inspect.Name, "/")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.streamLogs(ctx, inspect.ID, name, inspect.Config.Tty, opts, func(line LogLine) error {
+				select {
+				case lines <- line:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+	if onOpen != nil {
+		onOpen()
+	}
+
+	var collected []LogLine
+	for line := range lines {
+		if opts.Follow {
+			if err := fn(line); err != nil {
+				cancel()
+				return err
+			}
+		} else {
+			collected = append(collected, line)
+		}
+	}
+
+	close(errs)
+	for err := range errs {
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+	}
+
+	sort.SliceStable(collected, func(i, j int) bool {
+		return collected[i].time.Before(collected[j].time)
+	})
+	for _, line := range collected {
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// streamLogs reads a container's log and splits it into lines. Timestamps
+// are always requested from Docker so lines can be ordered; they are only
+// included in the output when asked for.
+func (s *Service) streamLogs(ctx context.Context, containerID, name string, tty bool, opts LogOptions, fn func(LogLine) error) error {
+	tail := opts.Tail
+	if tail == "" {
+		tail = "all"
+	}
+
+	reader, err := s.client.ContainerLogs(ctx, containerID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Since:      opts.Since,
+		Until:      opts.Until,
+		Timestamps: true,
+		Follow:     opts.Follow,
+		Tail:       tail,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get container logs: %w", err)
+	}
+	defer reader.Close()
+
+	if opts.OnOpen != nil {
+		opts.OnOpen()
+	}
+
+	emit := func(stream, raw string) error {
+		line := LogLine{Container: name, Stream: stream, Message: raw}
+		if ts, message, ok := strings.Cut(raw, " "); ok {
+			if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				line.time = t
+				line.Message = message
+				if opts.Timestamps {
+					line.Timestamp = ts
+				}
+			}
+		}
+		return fn(line)
+	}
+
+	stdout := &lineWriter{stream: "stdout", emit: emit}
+	stderr := &lineWriter{stream: "stderr", emit: emit}
+
+	// Containers with a TTY have a single raw stream; otherwise Docker
+	// multiplexes stdout and stderr into framed chunks
+	if tty {
+		_, err = io.Copy(stdout, reader)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, reader)
+	}
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to read container logs: %w", err)
+	}
+
+	if err := stdout.flush(); err != nil {
+		return err
+	}
+	return stderr.flush()
+}
+
+// lineWriter splits written bytes into lines and emits each one
+type lineWriter struct {
+	stream string
+	buf    []byte
+	emit   func(stream, line string) error
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(bytes.TrimSuffix(w.buf[:i], []byte("\r")))
+		w.buf = w.buf[i+1:]
+		if err := w.emit(w.stream, line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// flush emits a final line that was not newline terminated
+func (w *lineWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	line := string(w.buf)
+	w.buf = nil
+	return w.emit(w.stream, line)
+}
@@ -0,0 +1,44 @@
+package docker
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+func TestLineWriter_DemultiplexesStreams(t *testing.T) {
+	// Build a multiplexed log as Docker sends it for non-TTY containers,
+	// with a line split across frames
+	var framed bytes.Buffer
+	stdoutFrames := stdcopy.NewStdWriter(&framed, stdcopy.Stdout)
+	stderrFrames := stdcopy.NewStdWriter(&framed, stdcopy.Stderr)
+	stdoutFrames.Write([]byte("2024-05-01T10:00:00.000000001Z listening on :80\n2024-05-01T10:00:01.000000000Z GET /"))
+	stderrFrames.Write([]byte("2024-05-01T10:00:00.500000000Z warning: no config\r\n"))
+	stdoutFrames.Write([]byte(" 200\nno newline"))
+
+	var got []string
+	emit := func(stream, line string) error {
+		got = append(got, fmt.Sprintf("%s|%s", stream, line))
+		return nil
+	}
+	stdout := &lineWriter{stream: "stdout", emit: emit}
+	stderr := &lineWriter{stream: "stderr", emit: emit}
+
+	if _, err := stdcopy.StdCopy(stdout, stderr, &framed); err != nil {
+		t.Fatalf("StdCopy failed: %v", err)
+	}
+	stdout.flush()
+	stderr.flush()
+
+	want := []string{
+		"stdout|2024-05-01T10:00:00.000000001Z listening on :80",
+		"stderr|2024-05-01T10:00:00.500000000Z warning: no config",
+		"stdout|2024-05-01T10:00:01.000000000Z GET / 200",
+		"stdout|no newline",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got lines\n%v\nwant\n%v", got, want)
+	}
+}
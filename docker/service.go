@@ -10,7 +10,9 @@ import (
 )
 
 type Service struct {
-	client *client.Client
+	client     *client.Client
+	events     *eventHub
+	containers *ContainerCache
 }
 
 type ContainerInfo struct {
@@ -34,10 +36,33 @@ func NewService() (*Service, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create docker client: %w", err)
 	}
-	return &Service{client: cli}, nil
+	return &Service{client: cli, events: newEventHub(), containers: newContainerCache(cli)}, nil
+}
+
+// Containers returns the container cache, which is kept current while
+// WatchEvents runs
+func (s *Service) Containers() *ContainerCache {
+	return s.containers
 }
 
 func (s *Service) ListContainers(ctx context.Context) ([]ContainerInfo, error) {
+	if s.containers.Synced() {
+		cached := s.containers.List()
+		result := make([]ContainerInfo, 0, len(cached))
+		for _, c := range cached {
+			result = append(result, ContainerInfo{
+				ID:     c.ID,
+				Name:   c.Name,
+				Image:  c.Image,
+				State:  c.State,
+				Status: c.Status(),
+				Ports:  c.Ports,
+				Labels: c.Labels,
+			})
+		}
+		return result, nil
+	}
+
 	containers, err := s.client.ContainerList(ctx, container.ListOptions{
 		All: true,
 	})
@@ -86,13 +111,67 @@ func (s *Service) Client() *client.Client {
 	return s.client
 }
 
-func (s *Service) StopContainer(ctx context.Context, containerID string) error {
-	if err := s.client.ContainerStop(ctx, containerID, container.StopOptions{}); err != nil {
+// StopContainer stops a container, killing it if it has not exited after
+// timeout seconds. A nil timeout uses the container's own stop timeout,
+// which defaults to 10 seconds.
+func (s *Service) StopContainer(ctx context.Context, containerID string, timeout *int) error {
+	if err := s.client.ContainerStop(ctx, containerID, container.StopOptions{Timeout: timeout}); err != nil {
 		return fmt.Errorf("failed to stop container: %w", err)
 	}
 	return nil
 }
 
+// RestartContainer stops a container, with the same timeout as
+// StopContainer, and starts it again
+func (s *Service) RestartContainer(ctx context.Context, containerID string, timeout *int) error {
+	if err := s.client.ContainerRestart(ctx, containerID, container.StopOptions{Timeout: timeout}); err != nil {
+		return fmt.Errorf("failed to restart container: %w", err)
+	}
+	return nil
+}
+
+// KillContainer sends a signal, such as "SIGTERM" or "HUP", to a
+// container's main process. An empty signal sends SIGKILL.
+func (s *Service) KillContainer(ctx context.Context, containerID, signal string) error {
+	if err := s.client.ContainerKill(ctx, containerID, signal); err != nil {
+		return fmt.Errorf("failed to kill container: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) PauseContainer(ctx context.Context, containerID string) error {
+	if err := s.client.ContainerPause(ctx, containerID); err != nil {
+		return fmt.Errorf("failed to pause container: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) UnpauseContainer(ctx context.Context, containerID string) error {
+	if err := s.client.ContainerUnpause(ctx, containerID); err != nil {
+		return fmt.Errorf("failed to unpause container: %w", err)
+	}
+	return nil
+}
+
+// RemoveContainer removes a container. Force kills it first if it is
+// running; volumes also removes its anonymous volumes.
+func (s *Service) RemoveContainer(ctx context.Context, containerID string, force, volumes bool) error {
+	if err := s.client.ContainerRemove(ctx, containerID, container.RemoveOptions{
+		Force:         force,
+		RemoveVolumes: volumes,
+	}); err != nil {
+		return fmt.Errorf("failed to remove container: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) RenameContainer(ctx context.Context, containerID, name string) error {
+	if err := s.client.ContainerRename(ctx, containerID, name); err != nil {
+		return fmt.Errorf("failed to rename container: %w", err)
+	}
+	return nil
+}
+
 func (s *Service) StartContainer(ctx context.Context, containerID string) error {
 	if err := s.client.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
 		return fmt.Errorf("failed to start container: %w", err)
@@ -100,6 +179,21 @@ func (s *Service) StartContainer(ctx context.Context, containerID string) error
 	return nil
 }
 
+// GetRestartCount returns how many times Docker has restarted a container
+func (s *Service) GetRestartCount(ctx context.Context, containerID string) (int, error) {
+	if s.containers.Synced() {
+		if c, ok := s.containers.Get(containerID); ok {
+			return c.RestartCount, nil
+		}
+	}
+
+	inspect, err := s.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	return inspect.RestartCount, nil
+}
+
 type DetailedContainerInfo struct {
 	ID            string                 `json:"id"`
 	Name          string                 `json:"name"`
@@ -0,0 +1,239 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// ContainerStats is a container's resource usage at a point in time
+type ContainerStats struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Service       string    `json:"service,omitempty"`
+	Time          time.Time `json:"time"`
+	CPUPercent    float64   `json:"cpu_percent"`
+	OnlineCPUs    uint32    `json:"online_cpus"`
+	MemoryUsage   uint64    `json:"memory_usage"`
+	MemoryLimit   uint64    `json:"memory_limit"`
+	MemoryPercent float64   `json:"memory_percent"`
+	NetworkRx     uint64    `json:"network_rx_bytes"`
+	NetworkTx     uint64    `json:"network_tx_bytes"`
+	BlockRead     uint64    `json:"block_read_bytes"`
+	BlockWrite    uint64    `json:"block_write_bytes"`
+	PIDs          uint64    `json:"pids"`
+}
+
+// StatsTotals sums the usage of several containers. Memory limits are not
+// summed since containers without a limit report the host's memory.
+type StatsTotals struct {
+	Containers  int     `json:"containers"`
+	CPUPercent  float64 `json:"cpu_percent"`
+	MemoryUsage uint64  `json:"memory_usage"`
+	NetworkRx   uint64  `json:"network_rx_bytes"`
+	NetworkTx   uint64  `json:"network_tx_bytes"`
+	BlockRead   uint64  `json:"block_read_bytes"`
+	BlockWrite  uint64  `json:"block_write_bytes"`
+	PIDs        uint64  `json:"pids"`
+}
+
+// ProjectStats is the resource usage of a project's running containers
+type ProjectStats struct {
+	Project    string           `json:"project"`
+	Time       time.Time        `json:"time"`
+	Containers []ContainerStats `json:"containers"`
+	Total      StatsTotals      `json:"total"`
+}
+
+// GetContainerStats returns a container's current resource usage. Docker
+// takes two samples about a second apart so CPU usage can be computed.
+func (s *Service) GetContainerStats(ctx context.Context, containerID string) (*ContainerStats, error) {
+	resp, err := s.client.ContainerStats(ctx, containerID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container stats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var raw container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode container stats: %w", err)
+	}
+
+	stats := calculateStats(&raw)
+	return &stats, nil
+}
+
+// StreamContainerStats calls fn with a container's resource usage about
+// once a second until the container stops, ctx is done or fn returns an
+// error
+func (s *Service) StreamContainerStats(ctx context.Context, containerID string, fn func(ContainerStats) error) error {
+	resp, err := s.client.ContainerStats(ctx, containerID, true)
+	if err != nil {
+		return fmt.Errorf("failed to get container stats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var raw container.StatsResponse
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to decode container stats: %w", err)
+		}
+
+		// Docker sends a final empty sample once the container has stopped
+		if raw.Read.IsZero() {
+			continue
+		}
+
+		if err := fn(calculateStats(&raw)); err != nil {
+			return err
+		}
+	}
+}
+
+// GetProjectStats returns the resource usage of each running container of
+// a project and their totals
+func (s *Service) GetProjectStats(ctx context.Context, projectName string) (*ProjectStats, error) {
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", fmt.Sprintf("com.docker.compose.project=%s", projectName))
+
+	containers, err := s.client.ContainerList(ctx, container.ListOptions{
+		Filters: filterArgs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	result := &ProjectStats{
+		Project:    projectName,
+		Time:       time.Now().UTC(),
+		Containers: make([]ContainerStats, 0, len(containers)),
+	}
+
+	// Each sample takes about a second, so containers are sampled in parallel
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+	for _, c := range containers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stats, err := s.GetContainerStats(ctx, c.ID)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				// A container stopping in the meantime is not an error
+				if !client.IsErrNotFound(err) && firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			stats.Service = c.Labels["com.docker.compose.service"]
+			result.Containers = append(result.Containers, *stats)
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	sort.Slice(result.Containers, func(i, j int) bool {
+		return result.Containers[i].Name < result.Containers[j].Name
+	})
+	result.Total = SumStats(result.Containers)
+
+	return result, nil
+}
+
+// SumStats adds up the usage of several containers
+func SumStats(stats []ContainerStats) StatsTotals {
+	totals := StatsTotals{Containers: len(stats)}
+	for _, c := range stats {
+		totals.CPUPercent += c.CPUPercent
+		totals.MemoryUsage += c.MemoryUsage
+		totals.NetworkRx += c.NetworkRx
+		totals.NetworkTx += c.NetworkTx
+		totals.BlockRead += c.BlockRead
+		totals.BlockWrite += c.BlockWrite
+		totals.PIDs += c.PIDs
+	}
+	return totals
+}
+
+// calculateStats derives usage figures from a raw Docker stats sample the
+// same way `docker stats` does
+func calculateStats(raw *container.StatsResponse) ContainerStats {
+	stats := ContainerStats{
+		ID:          shortID(raw.ID),
+		Name:        strings.TrimPrefix(raw.Name, "/"),
+		Time:        raw.Read.UTC(),
+		OnlineCPUs:  raw.CPUStats.OnlineCPUs,
+		MemoryLimit: raw.MemoryStats.Limit,
+		PIDs:        raw.PidsStats.Current,
+	}
+	if stats.OnlineCPUs == 0 {
+		stats.OnlineCPUs = uint32(len(raw.CPUStats.CPUUsage.PercpuUsage))
+	}
+
+	// CPU usage is the container's share of the host's CPU time since the
+	// previous sample, scaled so one fully used core is 100%
+	cpu, pre := raw.CPUStats, raw.PreCPUStats
+	if cpu.CPUUsage.TotalUsage > pre.CPUUsage.TotalUsage && cpu.SystemUsage > pre.SystemUsage && pre.SystemUsage > 0 {
+		cpuDelta := float64(cpu.CPUUsage.TotalUsage - pre.CPUUsage.TotalUsage)
+		systemDelta := float64(cpu.SystemUsage - pre.SystemUsage)
+		stats.CPUPercent = cpuDelta / systemDelta * float64(stats.OnlineCPUs) * 100
+	}
+
+	// Page cache is reclaimable and not counted as used memory
+	stats.MemoryUsage = raw.MemoryStats.Usage
+	for _, key := range []string{"total_inactive_file", "inactive_file"} {
+		if cache, ok := raw.MemoryStats.Stats[key]; ok {
+			if cache < stats.MemoryUsage {
+				stats.MemoryUsage -= cache
+			}
+			break
+		}
+	}
+	if stats.MemoryLimit > 0 {
+		stats.MemoryPercent = float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100
+	}
+
+	for _, network := range raw.Networks {
+		stats.NetworkRx += network.RxBytes
+		stats.NetworkTx += network.TxBytes
+	}
+
+	for _, entry := range raw.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockRead += entry.Value
+		case "write":
+			stats.BlockWrite += entry.Value
+		}
+	}
+
+	return stats
+}
+
+// shortID truncates a container ID to the 12 characters shown elsewhere
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
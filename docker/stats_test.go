@@ -0,0 +1,71 @@
+package docker
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestCalculateStats(t *testing.T) {
+	raw := &container.StatsResponse{
+		ID:   "0123456789abcdef0123",
+		Name: "/site-web-1",
+		Read: time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC),
+		CPUStats: container.CPUStats{
+			CPUUsage:    container.CPUUsage{TotalUsage: 300_000_000},
+			SystemUsage: 4_000_000_000,
+			OnlineCPUs:  2,
+		},
+		PreCPUStats: container.CPUStats{
+			CPUUsage:    container.CPUUsage{TotalUsage: 100_000_000},
+			SystemUsage: 2_000_000_000,
+		},
+		MemoryStats: container.MemoryStats{
+			Usage: 150 << 20,
+			Limit: 500 << 20,
+			Stats: map[string]uint64{"inactive_file": 50 << 20},
+		},
+		Networks: map[string]container.NetworkStats{
+			"eth0": {RxBytes: 1000, TxBytes: 200},
+			"eth1": {RxBytes: 24, TxBytes: 56},
+		},
+		BlkioStats: container.BlkioStats{
+			IoServiceBytesRecursive: []container.BlkioStatEntry{
+				{Op: "read", Value: 4096},
+				{Op: "Write", Value: 8192},
+				{Op: "Read", Value: 4096},
+			},
+		},
+		PidsStats: container.PidsStats{Current: 7},
+	}
+
+	stats := calculateStats(raw)
+
+	if stats.ID != "0123456789ab" || stats.Name != "site-web-1" {
+		t.Errorf("unexpected identity: %s %s", stats.ID, stats.Name)
+	}
+	// 0.2s of CPU over 2s of system time on 2 CPUs
+	if math.Abs(stats.CPUPercent-20) > 0.001 {
+		t.Errorf("CPUPercent = %v, want 20", stats.CPUPercent)
+	}
+	if stats.MemoryUsage != 100<<20 || math.Abs(stats.MemoryPercent-20) > 0.001 {
+		t.Errorf("memory = %d (%v%%), want 100MiB (20%%)", stats.MemoryUsage, stats.MemoryPercent)
+	}
+	if stats.NetworkRx != 1024 || stats.NetworkTx != 256 {
+		t.Errorf("network = %d/%d, want 1024/256", stats.NetworkRx, stats.NetworkTx)
+	}
+	if stats.BlockRead != 8192 || stats.BlockWrite != 8192 {
+		t.Errorf("block I/O = %d/%d, want 8192/8192", stats.BlockRead, stats.BlockWrite)
+	}
+	if stats.PIDs != 7 {
+		t.Errorf("PIDs = %d, want 7", stats.PIDs)
+	}
+
+	// Without a previous sample there is nothing to compare against
+	raw.PreCPUStats = container.CPUStats{}
+	if stats := calculateStats(raw); stats.CPUPercent != 0 {
+		t.Errorf("CPUPercent without previous sample = %v, want 0", stats.CPUPercent)
+	}
+}
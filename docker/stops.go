@@ -0,0 +1,43 @@
+package docker
+
+import "sync"
+
+// StopTracker tells requested stops apart from crashes. Docker sends a
+// "kill" event before a container dies whenever it is stopped, killed or
+// restarted through its API, including by compose and by Hubble, but not
+// when its process exits on its own or is killed for running out of
+// memory.
+type StopTracker struct {
+	mu     sync.Mutex
+	killed map[string]bool
+}
+
+func NewStopTracker() *StopTracker {
+	return &StopTracker{killed: make(map[string]bool)}
+}
+
+// Observe records a container event; events other than kill, start and
+// destroy are ignored
+func (t *StopTracker) Observe(event Event) {
+	if event.Type != "container" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch event.Action {
+	case "kill":
+		t.killed[event.ID] = true
+	case "start", "destroy":
+		delete(t.killed, event.ID)
+	}
+}
+
+// Requested reports whether a container was killed or stopped on request
+// since it last started
+func (t *StopTracker) Requested(id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.killed[id]
+}
@@ -0,0 +1,522 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// ManagedLabel marks Hubble's own containers, networks and volumes, which
+// are never pruned
+const ManagedLabel = "com.hubble.managed"
+
+// anonymousVolumeLabel is set by Docker on volumes created without a name
+const anonymousVolumeLabel = "com.docker.volume.anonymous"
+
+// DiskUsage is the space used by Docker objects, with the compose project
+// that owns each of them
+type DiskUsage struct {
+	Images     []ImageUsage      `json:"images"`
+	Containers []ContainerUsage  `json:"containers"`
+	Volumes    []VolumeUsage     `json:"volumes"`
+	BuildCache []BuildCacheUsage `json:"build_cache"`
+	Totals     DiskUsageTotals   `json:"totals"`
+}
+
+// DiskUsageTotals sums each object type
+type DiskUsageTotals struct {
+	Images     UsageTotal `json:"images"`
+	Containers UsageTotal `json:"containers"`
+	Volumes    UsageTotal `json:"volumes"`
+	BuildCache UsageTotal `json:"build_cache"`
+}
+
+// UsageTotal is the size of one object type and how much of it is not in
+// use by anything
+type UsageTotal struct {
+	Count       int   `json:"count"`
+	Size        int64 `json:"size"`
+	Reclaimable int64 `json:"reclaimable"`
+}
+
+// ImageUsage is an image's size. SharedSize is the part in layers shared
+// with other images; UniqueSize is what removing only this image frees.
+type ImageUsage struct {
+	ID         string   `json:"id"`
+	RepoTags   []string `json:"repo_tags"`
+	Size       int64    `json:"size"`
+	SharedSize int64    `json:"shared_size"`
+	UniqueSize int64    `json:"unique_size"`
+	Created    int64    `json:"created"`
+	Containers int      `json:"containers"`
+	Dangling   bool     `json:"dangling"`
+	// Projects are the compose projects of the containers using the image,
+	// or the project that built it
+	Projects []string `json:"projects"`
+	Managed  bool     `json:"managed"`
+}
+
+// ContainerUsage is the space taken by a container's writable layer
+type ContainerUsage struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Image      string `json:"image"`
+	State      string `json:"state"`
+	SizeRw     int64  `json:"size_rw"`
+	SizeRootFs int64  `json:"size_root_fs"`
+	Project    string `json:"project,omitempty"`
+	Service    string `json:"service,omitempty"`
+	Managed    bool   `json:"managed"`
+}
+
+// VolumeUsage is a volume's size. Size is -1 when the driver cannot report
+// it.
+type VolumeUsage struct {
+	Name      string `json:"name"`
+	Driver    string `json:"driver"`
+	Size      int64  `json:"size"`
+	RefCount  int64  `json:"ref_count"`
+	Anonymous bool   `json:"anonymous"`
+	Project   string `json:"project,omitempty"`
+	Managed   bool   `json:"managed"`
+}
+
+// BuildCacheUsage is a build cache record
+type BuildCacheUsage struct {
+	ID          string     `json:"id"`
+	Type        string     `json:"type"`
+	Description string     `json:"description"`
+	Size        int64      `json:"size"`
+	InUse       bool       `json:"in_use"`
+	Shared      bool       `json:"shared"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	UsageCount  int        `json:"usage_count"`
+}
+
+// PruneScope is a kind of object that can be pruned
+type PruneScope string
+
+const (
+	PruneContainers PruneScope = "containers"
+	PruneImages     PruneScope = "images"
+	PruneVolumes    PruneScope = "volumes"
+	PruneBuildCache PruneScope = "build_cache"
+)
+
+// PruneOptions selects what to prune
+type PruneOptions struct {
+	Scopes []PruneScope `json:"scopes"`
+	// All prunes every unused image rather than only dangling ones, named
+	// volumes as well as anonymous ones, and all unused build cache
+	All    bool `json:"all"`
+	DryRun bool `json:"dry_run"`
+}
+
+// Validate checks the scopes
+func (o PruneOptions) Validate() error {
+	if len(o.Scopes) == 0 {
+		return fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range o.Scopes {
+		switch scope {
+		case PruneContainers, PruneImages, PruneVolumes, PruneBuildCache:
+		default:
+			return fmt.Errorf("invalid scope: %s", scope)
+		}
+	}
+	return nil
+}
+
+func (o PruneOptions) has(scope PruneScope) bool {
+	for _, s := range o.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// PruneReport lists what was, or with a dry run would be, removed per scope
+type PruneReport struct {
+	DryRun         bool              `json:"dry_run"`
+	Containers     *PruneScopeReport `json:"containers,omitempty"`
+	Images         *PruneScopeReport `json:"images,omitempty"`
+	Volumes        *PruneScopeReport `json:"volumes,omitempty"`
+	BuildCache     *PruneScopeReport `json:"build_cache,omitempty"`
+	SpaceReclaimed uint64            `json:"space_reclaimed"`
+}
+
+// PruneScopeReport lists the objects removed from one scope
+type PruneScopeReport struct {
+	Deleted        []string `json:"deleted"`
+	SpaceReclaimed uint64   `json:"space_reclaimed"`
+}
+
+// GetDiskUsage returns the space used by images, containers, volumes and
+// the build cache
+func (s *Service) GetDiskUsage(ctx context.Context) (*DiskUsage, error) {
+	du, err := s.client.DiskUsage(ctx, types.DiskUsageOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disk usage: %w", err)
+	}
+	return diskUsage(du), nil
+}
+
+func diskUsage(du types.DiskUsage) *DiskUsage {
+	result := &DiskUsage{
+		Images:     make([]ImageUsage, 0, len(du.Images)),
+		Containers: make([]ContainerUsage, 0, len(du.Containers)),
+		Volumes:    make([]VolumeUsage, 0, len(du.Volumes)),
+		BuildCache: make([]BuildCacheUsage, 0, len(du.BuildCache)),
+	}
+
+	// Images are owned by the projects of the containers using them, and
+	// volumes mounted by Hubble's containers are Hubble's even when they
+	// predate the managed label
+	imageProjects := make(map[string]map[string]bool)
+	imageContainers := make(map[string]int)
+	managedVolumes := make(map[string]bool)
+	for _, c := range du.Containers {
+		imageContainers[c.ImageID]++
+		if managed(c.Labels) {
+			for _, m := range c.Mounts {
+				if m.Name != "" {
+					managedVolumes[m.Name] = true
+				}
+			}
+		}
+		if project := c.Labels["com.docker.compose.project"]; project != "" {
+			if imageProjects[c.ImageID] == nil {
+				imageProjects[c.ImageID] = make(map[string]bool)
+			}
+			imageProjects[c.ImageID][project] = true
+		}
+
+		name := ""
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		usage := ContainerUsage{
+			ID:         shortID(c.ID),
+			Name:       name,
+			Image:      c.Image,
+			State:      c.State,
+			SizeRw:     c.SizeRw,
+			SizeRootFs: c.SizeRootFs,
+			Project:    c.Labels["com.docker.compose.project"],
+			Service:    c.Labels["com.docker.compose.service"],
+			Managed:    managed(c.Labels),
+		}
+		result.Containers = append(result.Containers, usage)
+
+		total := &result.Totals.Containers
+		total.Count++
+		total.Size += c.SizeRw
+		if stopped(c.State) {
+			total.Reclaimable += c.SizeRw
+		}
+	}
+
+	for _, img := range du.Images {
+		projects := imageProjects[img.ID]
+		if project := img.Labels["com.docker.compose.project"]; project != "" {
+			if projects == nil {
+				projects = make(map[string]bool)
+			}
+			projects[project] = true
+		}
+
+		shared := max(img.SharedSize, 0)
+		usage := ImageUsage{
+			ID:         shortID(strings.TrimPrefix(img.ID, "sha256:")),
+			RepoTags:   taggedRefs(img.RepoTags),
+			Size:       img.Size,
+			SharedSize: shared,
+			UniqueSize: img.Size - shared,
+			Created:    img.Created,
+			Containers: imageContainers[img.ID],
+			Projects:   sortedKeys(projects),
+			Managed:    managed(img.Labels),
+		}
+		usage.Dangling = len(usage.RepoTags) == 0
+		result.Images = append(result.Images, usage)
+
+		total := &result.Totals.Images
+		total.Count++
+		total.Size += img.Size
+		if usage.Containers == 0 {
+			total.Reclaimable += usage.UniqueSize
+		}
+	}
+
+	for _, v := range du.Volumes {
+		usage := VolumeUsage{
+			Name:      v.Name,
+			Driver:    v.Driver,
+			Size:      -1,
+			RefCount:  -1,
+			Anonymous: anonymous(v.Labels),
+			Project:   v.Labels["com.docker.compose.project"],
+			Managed:   managed(v.Labels) || managedVolumes[v.Name],
+		}
+		if v.UsageData != nil {
+			usage.Size = v.UsageData.Size
+			usage.RefCount = v.UsageData.RefCount
+		}
+		result.Volumes = append(result.Volumes, usage)
+
+		total := &result.Totals.Volumes
+		total.Count++
+		if usage.Size > 0 {
+			total.Size += usage.Size
+			if usage.RefCount == 0 {
+				total.Reclaimable += usage.Size
+			}
+		}
+	}
+
+	for _, record := range du.BuildCache {
+		result.BuildCache = append(result.BuildCache, BuildCacheUsage{
+			ID:          record.ID,
+			Type:        record.Type,
+			Description: record.Description,
+			Size:        record.Size,
+			InUse:       record.InUse,
+			Shared:      record.Shared,
+			CreatedAt:   record.CreatedAt,
+			LastUsedAt:  record.LastUsedAt,
+			UsageCount:  record.UsageCount,
+		})
+
+		total := &result.Totals.BuildCache
+		total.Count++
+		total.Size += record.Size
+		if !record.InUse {
+			total.Reclaimable += record.Size
+		}
+	}
+
+	// Largest first, which is what a cleanup looks for
+	sort.SliceStable(result.Images, func(i, j int) bool { return result.Images[i].UniqueSize > result.Images[j].UniqueSize })
+	sort.SliceStable(result.Containers, func(i, j int) bool { return result.Containers[i].SizeRw > result.Containers[j].SizeRw })
+	sort.SliceStable(result.Volumes, func(i, j int) bool { return result.Volumes[i].Size > result.Volumes[j].Size })
+	sort.SliceStable(result.BuildCache, func(i, j int) bool { return result.BuildCache[i].Size > result.BuildCache[j].Size })
+
+	return result
+}
+
+// Prune removes unused objects in the selected scopes, never touching
+// anything labeled com.hubble.managed. Since managed containers are never
+// pruned, the volumes they mount stay in use and are kept too. Containers
+// go first so the images and volumes they used can be pruned in the same
+// call. A dry run reports what would be removed from the current disk usage
+// instead; its sizes are estimates since layers may be shared.
+func (s *Service) Prune(ctx context.Context, opts PruneOptions) (*PruneReport, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun {
+		du, err := s.client.DiskUsage(ctx, types.DiskUsageOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get disk usage: %w", err)
+		}
+		return planPrune(du, opts), nil
+	}
+
+	unmanaged := filters.NewArgs(filters.Arg("label!", ManagedLabel))
+	report := &PruneReport{}
+
+	if opts.has(PruneContainers) {
+		pruned, err := s.client.ContainersPrune(ctx, unmanaged.Clone())
+		if err != nil {
+			return nil, fmt.Errorf("failed to prune containers: %w", err)
+		}
+		deleted := make([]string, 0, len(pruned.ContainersDeleted))
+		for _, id := range pruned.ContainersDeleted {
+			deleted = append(deleted, shortID(id))
+		}
+		report.Containers = &PruneScopeReport{Deleted: deleted, SpaceReclaimed: pruned.SpaceReclaimed}
+	}
+
+	if opts.has(PruneImages) {
+		imageFilters := unmanaged.Clone()
+		if opts.All {
+			imageFilters.Add("dangling", "false")
+		}
+		pruned, err := s.client.ImagesPrune(ctx, imageFilters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prune images: %w", err)
+		}
+		deleted := make([]string, 0, len(pruned.ImagesDeleted))
+		for _, item := range pruned.ImagesDeleted {
+			if item.Untagged != "" {
+				deleted = append(deleted, item.Untagged)
+			} else {
+				deleted = append(deleted, shortID(strings.TrimPrefix(item.Deleted, "sha256:")))
+			}
+		}
+		report.Images = &PruneScopeReport{Deleted: deleted, SpaceReclaimed: pruned.SpaceReclaimed}
+	}
+
+	if opts.has(PruneVolumes) {
+		volumeFilters := unmanaged.Clone()
+		if opts.All {
+			volumeFilters.Add("all", "true")
+		}
+		pruned, err := s.client.VolumesPrune(ctx, volumeFilters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prune volumes: %w", err)
+		}
+		deleted := pruned.VolumesDeleted
+		if deleted == nil {
+			deleted = []string{}
+		}
+		report.Volumes = &PruneScopeReport{Deleted: deleted, SpaceReclaimed: pruned.SpaceReclaimed}
+	}
+
+	if opts.has(PruneBuildCache) {
+		pruned, err := s.client.BuildCachePrune(ctx, build.CachePruneOptions{All: opts.All})
+		if err != nil {
+			return nil, fmt.Errorf("failed to prune build cache: %w", err)
+		}
+		deleted := pruned.CachesDeleted
+		if deleted == nil {
+			deleted = []string{}
+		}
+		report.BuildCache = &PruneScopeReport{Deleted: deleted, SpaceReclaimed: pruned.SpaceReclaimed}
+	}
+
+	report.SpaceReclaimed = report.total()
+	return report, nil
+}
+
+// planPrune works out what Prune would remove given the current disk usage
+func planPrune(du types.DiskUsage, opts PruneOptions) *PruneReport {
+	report := &PruneReport{DryRun: true}
+
+	// Containers that stay, and so keep their images and volumes in use
+	imagesInUse := make(map[string]bool)
+	volumesInUse := make(map[string]bool)
+	prunedContainers := []string{}
+	var containerSpace uint64
+	for _, c := range du.Containers {
+		if opts.has(PruneContainers) && stopped(c.State) && !managed(c.Labels) {
+			prunedContainers = append(prunedContainers, shortID(c.ID))
+			containerSpace += uint64(max(c.SizeRw, 0))
+			continue
+		}
+		imagesInUse[c.ImageID] = true
+		for _, m := range c.Mounts {
+			if m.Name != "" {
+				volumesInUse[m.Name] = true
+			}
+		}
+	}
+	if opts.has(PruneContainers) {
+		report.Containers = &PruneScopeReport{Deleted: prunedContainers, SpaceReclaimed: containerSpace}
+	}
+
+	if opts.has(PruneImages) {
+		scope := &PruneScopeReport{Deleted: []string{}}
+		for _, img := range du.Images {
+			tags := taggedRefs(img.RepoTags)
+			if imagesInUse[img.ID] || managed(img.Labels) || (!opts.All && len(tags) > 0) {
+				continue
+			}
+			if len(tags) > 0 {
+				scope.Deleted = append(scope.Deleted, tags...)
+			} else {
+				scope.Deleted = append(scope.Deleted, shortID(strings.TrimPrefix(img.ID, "sha256:")))
+			}
+			scope.SpaceReclaimed += uint64(max(img.Size-max(img.SharedSize, 0), 0))
+		}
+		report.Images = scope
+	}
+
+	if opts.has(PruneVolumes) {
+		scope := &PruneScopeReport{Deleted: []string{}}
+		for _, v := range du.Volumes {
+			if volumesInUse[v.Name] || managed(v.Labels) || (!opts.All && !anonymous(v.Labels)) {
+				continue
+			}
+			scope.Deleted = append(scope.Deleted, v.Name)
+			if v.UsageData != nil && v.UsageData.Size > 0 {
+				scope.SpaceReclaimed += uint64(v.UsageData.Size)
+			}
+		}
+		report.Volumes = scope
+	}
+
+	if opts.has(PruneBuildCache) {
+		scope := &PruneScopeReport{Deleted: []string{}}
+		for _, record := range du.BuildCache {
+			if record.InUse || (!opts.All && record.Shared) {
+				continue
+			}
+			scope.Deleted = append(scope.Deleted, record.ID)
+			scope.SpaceReclaimed += uint64(max(record.Size, 0))
+		}
+		report.BuildCache = scope
+	}
+
+	report.SpaceReclaimed = report.total()
+	return report
+}
+
+func (r *PruneReport) total() uint64 {
+	var total uint64
+	for _, scope := range []*PruneScopeReport{r.Containers, r.Images, r.Volumes, r.BuildCache} {
+		if scope != nil {
+			total += scope.SpaceReclaimed
+		}
+	}
+	return total
+}
+
+// taggedRefs drops the "<none>:<none>" placeholder of untagged images
+func taggedRefs(refs []string) []string {
+	tagged := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if ref != "<none>:<none>" {
+			tagged = append(tagged, ref)
+		}
+	}
+	return tagged
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// managed reports whether an object carries the com.hubble.managed label,
+// whatever its value, matching the label!= prune filter
+func managed(labels map[string]string) bool {
+	_, ok := labels[ManagedLabel]
+	return ok
+}
+
+// anonymous reports whether a volume was created without a name. Docker
+// sets the label with an empty value.
+func anonymous(labels map[string]string) bool {
+	_, ok := labels[anonymousVolumeLabel]
+	return ok
+}
+
+// stopped reports whether a container in this state is removed by a
+// container prune
+func stopped(state string) bool {
+	return state == "exited" || state == "created" || state == "dead"
+}
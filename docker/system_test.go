@@ -0,0 +1,120 @@
+package docker
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// testDiskUsage has a running web container, a stopped job container that
+// is the only user of its image and volume, and a stopped Hubble container
+// with its volumes
+func testDiskUsage() types.DiskUsage {
+	return types.DiskUsage{
+		Containers: []*container.Summary{
+			{ID: "aaaaaaaaaaaa1111", Names: []string{"/site-web-1"}, ImageID: "sha256:web", State: "running", SizeRw: 100,
+				Labels: map[string]string{"com.docker.compose.project": "site", "com.docker.compose.service": "web"}},
+			{ID: "bbbbbbbbbbbb2222", Names: []string{"/site-job-1"}, ImageID: "sha256:job", State: "exited", SizeRw: 200,
+				Labels: map[string]string{"com.docker.compose.project": "site", "com.docker.compose.service": "job"},
+				Mounts: []container.MountPoint{{Type: "volume", Name: "site_scratch"}}},
+			{ID: "cccccccccccc3333", Names: []string{"/hubble-registry"}, ImageID: "sha256:registry", State: "exited", SizeRw: 50,
+				Labels: map[string]string{ManagedLabel: "true"},
+				Mounts: []container.MountPoint{{Type: "volume", Name: "hubble-registry-data"}, {Type: "volume", Name: "hubble-registry-auth"}}},
+		},
+		Images: []*image.Summary{
+			{ID: "sha256:web", RepoTags: []string{"nginx:1.27"}, Size: 1000, SharedSize: 400},
+			{ID: "sha256:job", RepoTags: []string{"site-job:latest"}, Size: 800, SharedSize: 400},
+			{ID: "sha256:registry", RepoTags: []string{"registry:2"}, Size: 300, SharedSize: 0},
+			{ID: "sha256:old", RepoTags: []string{"<none>:<none>"}, Size: 500, SharedSize: -1},
+		},
+		Volumes: []*volume.Volume{
+			{Name: "site_scratch", Labels: map[string]string{"com.docker.compose.project": "site"}, UsageData: &volume.UsageData{Size: 4000, RefCount: 1}},
+			{Name: "3f9c1d", Labels: map[string]string{anonymousVolumeLabel: ""}, UsageData: &volume.UsageData{Size: 70, RefCount: 0}},
+			{Name: "hubble-registry-data", Labels: map[string]string{ManagedLabel: "true"}, UsageData: &volume.UsageData{Size: 9000, RefCount: 0}},
+			// Created before the deployment labeled its volumes
+			{Name: "hubble-registry-auth", UsageData: &volume.UsageData{Size: 10, RefCount: 1}},
+		},
+		BuildCache: []*build.CacheRecord{
+			{ID: "cache1", Size: 10, InUse: true},
+			{ID: "cache2", Size: 20, Shared: true},
+			{ID: "cache3", Size: 30},
+		},
+	}
+}
+
+func TestDiskUsage_Ownership(t *testing.T) {
+	du := diskUsage(testDiskUsage())
+
+	images := make(map[string]ImageUsage)
+	for _, img := range du.Images {
+		images[img.ID] = img
+	}
+	if img := images["web"]; fmt.Sprint(img.Projects) != "[site]" || img.UniqueSize != 600 || img.Containers != 1 {
+		t.Errorf("unexpected web image %+v", img)
+	}
+	if img := images["old"]; !img.Dangling || img.UniqueSize != 500 || len(img.RepoTags) != 0 {
+		t.Errorf("unexpected dangling image %+v", img)
+	}
+
+	if du.Containers[0].Name != "site-job-1" || du.Containers[0].Service != "job" {
+		t.Errorf("expected the largest container first, got %+v", du.Containers[0])
+	}
+	if !du.Volumes[0].Managed || du.Volumes[1].Project != "site" || !du.Volumes[3].Managed {
+		t.Errorf("unexpected volumes %+v", du.Volumes)
+	}
+
+	want := DiskUsageTotals{
+		Images:     UsageTotal{Count: 4, Size: 2600, Reclaimable: 500},
+		Containers: UsageTotal{Count: 3, Size: 350, Reclaimable: 250},
+		Volumes:    UsageTotal{Count: 4, Size: 13080, Reclaimable: 9070},
+		BuildCache: UsageTotal{Count: 3, Size: 60, Reclaimable: 50},
+	}
+	if du.Totals != want {
+		t.Errorf("got totals %+v, want %+v", du.Totals, want)
+	}
+}
+
+func TestPlanPrune(t *testing.T) {
+	tests := []struct {
+		opts                        PruneOptions
+		containers, images, volumes string
+		buildCache                  string
+		spaceReclaimed              uint64
+	}{
+		// Dangling images and anonymous volumes only
+		{PruneOptions{Scopes: []PruneScope{PruneImages, PruneVolumes, PruneBuildCache}}, "", "[old]", "[3f9c1d]", "[cache3]", 500 + 70 + 30},
+		// Pruning the job container frees its image and named volume, but
+		// never Hubble's registry
+		{PruneOptions{Scopes: []PruneScope{PruneContainers, PruneImages, PruneVolumes}, All: true}, "[bbbbbbbbbbbb]", "[site-job:latest old]", "[site_scratch 3f9c1d]", "", 200 + 400 + 500 + 4000 + 70},
+		// Without pruning containers, the job's image stays in use
+		{PruneOptions{Scopes: []PruneScope{PruneImages}, All: true}, "", "[old]", "", "", 500},
+	}
+
+	for _, tt := range tests {
+		report := planPrune(testDiskUsage(), tt.opts)
+		if !report.DryRun {
+			t.Error("expected a dry run report")
+		}
+		got := map[string]*PruneScopeReport{"containers": report.Containers, "images": report.Images, "volumes": report.Volumes, "build_cache": report.BuildCache}
+		want := map[string]string{"containers": tt.containers, "images": tt.images, "volumes": tt.volumes, "build_cache": tt.buildCache}
+		for scope, deleted := range want {
+			if deleted == "" {
+				if got[scope] != nil {
+					t.Errorf("%+v: expected no %s report, got %+v", tt.opts, scope, got[scope])
+				}
+				continue
+			}
+			if got[scope] == nil || fmt.Sprint(got[scope].Deleted) != deleted {
+				t.Errorf("%+v: %s deleted %+v, want %s", tt.opts, scope, got[scope], deleted)
+			}
+		}
+		if report.SpaceReclaimed != tt.spaceReclaimed {
+			t.Errorf("%+v: space reclaimed %d, want %d", tt.opts, report.SpaceReclaimed, tt.spaceReclaimed)
+		}
+	}
+}
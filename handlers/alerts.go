@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noel-vega/hubble/alerts"
+)
+
+type AlertsHandler struct {
+	engine *alerts.Engine
+}
+
+func NewAlertsHandler(engine *alerts.Engine) *AlertsHandler {
+	return &AlertsHandler{
+		engine: engine,
+	}
+}
+
+// List returns alert history, newest first, optionally filtered by status,
+// project and rule
+func (h *AlertsHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := alerts.AlertFilter{
+		Status:  alerts.Status(query.Get("status")),
+		Project: query.Get("project"),
+		RuleID:  query.Get("rule"),
+	}
+	if filter.Status != "" && filter.Status != alerts.StatusFiring && filter.Status != alerts.StatusResolved {
+		http.Error(w, "invalid status: "+query.Get("status"), http.StatusBadRequest)
+		return
+	}
+
+	limit := 100
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	alertList := h.engine.Alerts(filter, limit)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"alerts": alertList,
+		"count":  len(alertList),
+	})
+}
+
+func (h *AlertsHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	rules := h.engine.ListRules()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"rules": rules,
+		"count": len(rules),
+	})
+}
+
+func (h *AlertsHandler) GetRule(w http.ResponseWriter, r *http.Request) {
+	ruleID := chi.URLParam(r, "id")
+
+	rule, err := h.engine.GetRule(ruleID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+func (h *AlertsHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var rule alerts.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.engine.CreateRule(rule)
+	if err != nil {
+		writeRuleError(w, err, "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *AlertsHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	ruleID := chi.URLParam(r, "id")
+
+	var rule alerts.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.engine.UpdateRule(ruleID, rule)
+	if err != nil {
+		writeRuleError(w, err, ruleID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *AlertsHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	ruleID := chi.URLParam(r, "id")
+
+	if err := h.engine.DeleteRule(ruleID); err != nil {
+		writeRuleError(w, err, ruleID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "rule deleted successfully",
+		"id":      ruleID,
+	})
+}
+
+func writeRuleError(w http.ResponseWriter, err error, ruleID string) {
+	if err.Error() == "rule not found: "+ruleID {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else if strings.HasPrefix(err.Error(), "invalid rule:") {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
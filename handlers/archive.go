@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxUploadMemory is how much of a multipart upload is kept in memory;
+// larger files are spooled to disk
+const maxUploadMemory = 32 << 20
+
+// GetArchive downloads a path from a container: a regular file as is, and
+// anything else, or any path with format=tar, as a tar archive
+func (h *ContainersHandler) GetArchive(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	containerID := chi.URLParam(r, "id")
+	query := r.URL.Query()
+	filePath := query.Get("path")
+
+	if containerID == "" {
+		http.Error(w, "container ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if filePath == "" {
+		http.Error(w, "path is required", http.StatusBadRequest)
+		return
+	}
+
+	format := query.Get("format")
+	if format != "" && format != "tar" {
+		http.Error(w, "invalid format: "+format, http.StatusBadRequest)
+		return
+	}
+
+	stat, err := h.dockerService.StatPath(ctx, containerID, filePath)
+	if err != nil {
+		writeContainerError(w, err)
+		return
+	}
+
+	if stat.IsRegular() && format == "" {
+		content, stat, err := h.dockerService.ReadContainerFile(ctx, containerID, filePath)
+		if err != nil {
+			writeContainerError(w, err)
+			return
+		}
+		defer content.Close()
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": stat.Name}))
+		w.Header().Set("Content-Length", strconv.FormatInt(stat.Size, 10))
+		io.Copy(w, content)
+		return
+	}
+
+	content, stat, err := h.dockerService.CopyFromContainer(ctx, containerID, filePath)
+	if err != nil {
+		writeContainerError(w, err)
+		return
+	}
+	defer content.Close()
+
+	name := stat.Name
+	if name == "" || name == "/" || name == "." {
+		name = "root"
+	}
+
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + ".tar"}))
+	io.Copy(w, content)
+}
+
+// PutArchive copies files into a directory of a container, from either a
+// tar archive body or the files of a multipart form
+func (h *ContainersHandler) PutArchive(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	containerID := chi.URLParam(r, "id")
+	dirPath := r.URL.Query().Get("path")
+
+	if containerID == "" {
+		http.Error(w, "container ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if dirPath == "" {
+		http.Error(w, "path is required", http.StatusBadRequest)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var archive io.Reader
+	switch mediaType {
+	case "application/x-tar", "application/tar", "application/gzip", "application/x-gzip":
+		archive = r.Body
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(maxUploadMemory); err != nil {
+			http.Error(w, "invalid multipart form: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		var files []*multipart.FileHeader
+		for _, headers := range r.MultipartForm.File {
+			files = append(files, headers...)
+		}
+		if len(files) == 0 {
+			http.Error(w, "no files uploaded", http.StatusBadRequest)
+			return
+		}
+
+		reader, writer := io.Pipe()
+		go func() {
+			writer.CloseWithError(writeFilesArchive(writer, files))
+		}()
+		defer reader.Close()
+		archive = reader
+	default:
+		http.Error(w, "content type must be application/x-tar or multipart/form-data", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	if err := h.dockerService.CopyToContainer(ctx, containerID, dirPath, archive); err != nil {
+		writeContainerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "files copied successfully",
+		"id":      containerID,
+		"path":    dirPath,
+	})
+}
+
+// ListFiles lists a directory of a container
+func (h *ContainersHandler) ListFiles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	containerID := chi.URLParam(r, "id")
+
+	if containerID == "" {
+		http.Error(w, "container ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// No default: listing reads the whole subtree, which for / is the
+	// entire filesystem
+	dirPath := r.URL.Query().Get("path")
+	if dirPath == "" {
+		http.Error(w, "path is required", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.dockerService.ListDirectory(ctx, containerID, dirPath)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "directory too large to list") {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		} else {
+			writeContainerError(w, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"path":    dirPath,
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// writeFilesArchive writes uploaded files to a tar archive, each at the top
+// level under its base name
+func writeFilesArchive(w io.Writer, files []*multipart.FileHeader) error {
+	archive := tar.NewWriter(w)
+	now := time.Now()
+
+	for _, file := range files {
+		name := path.Base(file.Filename)
+		if name == "." || name == "/" {
+			return fmt.Errorf("invalid file name: %q", file.Filename)
+		}
+
+		if err := archive.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Size:     file.Size,
+			Mode:     0644,
+			ModTime:  now,
+		}); err != nil {
+			return err
+		}
+
+		content, err := file.Open()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(archive, content)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
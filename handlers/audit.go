@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/noel-vega/hubble/audit"
+)
+
+type AuditHandler struct {
+	auditLog *audit.Log
+}
+
+func NewAuditHandler(auditLog *audit.Log) *AuditHandler {
+	return &AuditHandler{
+		auditLog: auditLog,
+	}
+}
+
+// List returns recent audit entries newest first, optionally filtered by
+// action
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	entries := h.auditLog.List(r.URL.Query().Get("action"), limit)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
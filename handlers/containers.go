@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/go-chi/chi/v5"
 	"github.com/noel-vega/hubble/docker"
 )
@@ -43,8 +46,14 @@ func (h *ContainersHandler) Stop(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.dockerService.StopContainer(ctx, containerID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	timeout, err := parseStopTimeout(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.dockerService.StopContainer(ctx, containerID, timeout); err != nil {
+		writeContainerError(w, err)
 		return
 	}
 
@@ -65,7 +74,7 @@ func (h *ContainersHandler) Start(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.dockerService.StartContainer(ctx, containerID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeContainerError(w, err)
 		return
 	}
 
@@ -76,6 +85,191 @@ func (h *ContainersHandler) Start(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (h *ContainersHandler) Restart(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	containerID := chi.URLParam(r, "id")
+
+	if containerID == "" {
+		http.Error(w, "container ID is required", http.StatusBadRequest)
+		return
+	}
+
+	timeout, err := parseStopTimeout(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.dockerService.RestartContainer(ctx, containerID, timeout); err != nil {
+		writeContainerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "container restarted successfully",
+		"id":      containerID,
+	})
+}
+
+func (h *ContainersHandler) Kill(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	containerID := chi.URLParam(r, "id")
+
+	if containerID == "" {
+		http.Error(w, "container ID is required", http.StatusBadRequest)
+		return
+	}
+
+	signal := r.URL.Query().Get("signal")
+	if signal == "" {
+		signal = "SIGKILL"
+	}
+
+	if err := h.dockerService.KillContainer(ctx, containerID, signal); err != nil {
+		writeContainerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "signal sent successfully",
+		"id":      containerID,
+		"signal":  signal,
+	})
+}
+
+func (h *ContainersHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	containerID := chi.URLParam(r, "id")
+
+	if containerID == "" {
+		http.Error(w, "container ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.dockerService.PauseContainer(ctx, containerID); err != nil {
+		writeContainerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "container paused successfully",
+		"id":      containerID,
+	})
+}
+
+func (h *ContainersHandler) Unpause(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	containerID := chi.URLParam(r, "id")
+
+	if containerID == "" {
+		http.Error(w, "container ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.dockerService.UnpauseContainer(ctx, containerID); err != nil {
+		writeContainerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "container unpaused successfully",
+		"id":      containerID,
+	})
+}
+
+func (h *ContainersHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	containerID := chi.URLParam(r, "id")
+
+	if containerID == "" {
+		http.Error(w, "container ID is required", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	force := query.Get("force") == "true"
+	volumes := query.Get("volumes") == "true"
+
+	if err := h.dockerService.RemoveContainer(ctx, containerID, force, volumes); err != nil {
+		writeContainerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "container removed successfully",
+		"id":      containerID,
+	})
+}
+
+func (h *ContainersHandler) Rename(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	containerID := chi.URLParam(r, "id")
+
+	if containerID == "" {
+		http.Error(w, "container ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.dockerService.RenameContainer(ctx, containerID, req.Name); err != nil {
+		writeContainerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "container renamed successfully",
+		"id":      containerID,
+		"name":    req.Name,
+	})
+}
+
+// parseStopTimeout reads the optional timeout query parameter: seconds to
+// wait for the container to exit before it is killed
+func parseStopTimeout(r *http.Request) (*int, error) {
+	value := r.URL.Query().Get("timeout")
+	if value == "" {
+		return nil, nil
+	}
+
+	timeout, err := strconv.Atoi(value)
+	if err != nil || timeout < 0 {
+		return nil, fmt.Errorf("invalid timeout: %s", value)
+	}
+	return &timeout, nil
+}
+
+// writeContainerError maps Docker errors for container operations to
+// status codes
+func writeContainerError(w http.ResponseWriter, err error) {
+	if cerrdefs.IsNotFound(err) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else if cerrdefs.IsConflict(err) {
+		http.Error(w, err.Error(), http.StatusConflict)
+	} else if cerrdefs.IsInvalidArgument(err) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func (h *ContainersHandler) Get(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	containerID := chi.URLParam(r, "id")
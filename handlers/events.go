@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/noel-vega/hubble/docker"
+)
+
+// eventsKeepAlive is how often a comment is sent on an idle event stream so
+// proxies do not close it
+const eventsKeepAlive = 30 * time.Second
+
+type EventsHandler struct {
+	dockerService *docker.Service
+}
+
+func NewEventsHandler(dockerService *docker.Service) *EventsHandler {
+	return &EventsHandler{
+		dockerService: dockerService,
+	}
+}
+
+// Stream sends Docker events as Server-Sent Events, filtered by the
+// project, service and type query parameters
+func (h *EventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	sse, ok := newSSEStream(w)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := docker.EventFilter{
+		Projects: queryList(query["project"]),
+		Services: queryList(query["service"]),
+		Types:    queryList(query["type"]),
+	}
+
+	events, unsubscribe := h.dockerService.SubscribeEvents(filter)
+	defer unsubscribe()
+
+	sse.start()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				// Dropped for falling behind; the client should reload its
+				// state and reconnect
+				sse.end(errors.New("event stream fell behind"), nil)
+				return
+			}
+			if err := sse.event("docker", event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if err := sse.comment("keep-alive"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// queryList reads a query parameter that may be repeated or hold a comma
+// separated list
+func queryList(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
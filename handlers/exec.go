@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"github.com/noel-vega/hubble/audit"
+	"github.com/noel-vega/hubble/docker"
+	"github.com/noel-vega/hubble/middleware"
+)
+
+// defaultExecCommand is run when the request does not name a command
+const defaultExecCommand = "/bin/sh"
+
+// execWriteWait bounds how long a single WebSocket write may block
+const execWriteWait = 10 * time.Second
+
+type ExecHandler struct {
+	dockerService *docker.Service
+	auditLog      *audit.Log
+	upgrader      websocket.Upgrader
+}
+
+// NewExecHandler accepts sockets from Hubble's own origin and from the
+// origins listed in EXEC_ALLOWED_ORIGINS, such as the Vite dev server
+func NewExecHandler(dockerService *docker.Service, auditLog *audit.Log) *ExecHandler {
+	allowed := make(map[string]bool)
+	for _, origin := range strings.Split(os.Getenv("EXEC_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSuffix(strings.TrimSpace(origin), "/"); origin != "" {
+			allowed[strings.ToLower(origin)] = true
+		}
+	}
+
+	return &ExecHandler{
+		dockerService: dockerService,
+		auditLog:      auditLog,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+			// SameSite cookies are still sent from sibling subdomains, such
+			// as project apps published under HUBBLE_DOMAIN, so the origin
+			// must be checked as well
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				if origin == "" {
+					return true
+				}
+				u, err := url.Parse(origin)
+				if err != nil {
+					return false
+				}
+				return strings.EqualFold(u.Host, r.Host) || allowed[strings.ToLower(origin)]
+			},
+		},
+	}
+}
+
+// execControl is a JSON text message sent by the client
+type execControl struct {
+	Type string `json:"type"` // "input" or "resize"
+	Data string `json:"data,omitempty"`
+	Cols uint   `json:"cols,omitempty"`
+	Rows uint   `json:"rows,omitempty"`
+}
+
+// Exec runs a command with a TTY in a container and connects it to a
+// WebSocket. Binary messages carry terminal input and output; text messages
+// carry JSON control messages.
+func (h *ExecHandler) Exec(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	containerID := chi.URLParam(r, "id")
+
+	if containerID == "" {
+		http.Error(w, "container ID is required", http.StatusBadRequest)
+		return
+	}
+
+	opts, err := parseExecOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Create the exec before upgrading so failures get a proper status
+	execID, err := h.dockerService.CreateExec(ctx, containerID, opts)
+	if err != nil {
+		writeContainerError(w, err)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written an error response
+		return
+	}
+	defer conn.Close()
+
+	session, err := h.dockerService.AttachExec(ctx, execID, opts.Cols, opts.Rows)
+	if err != nil {
+		writeExecMessage(conn, map[string]any{"type": "error", "error": err.Error()})
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, ""), time.Now().Add(execWriteWait))
+		return
+	}
+	defer session.Close()
+
+	username := middleware.GetUsername(r)
+	startedAt := time.Now().UTC()
+	h.record(audit.Entry{
+		Time:   startedAt,
+		User:   username,
+		Action: "exec.start",
+		Target: containerID,
+		Details: map[string]any{
+			"exec_id":     execID,
+			"cmd":         opts.Cmd,
+			"exec_user":   opts.User,
+			"remote_addr": r.RemoteAddr,
+		},
+	})
+
+	// Client messages are forwarded until the socket closes, which also
+	// detaches from the process and ends the output loop below
+	go func() {
+		defer session.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			if messageType == websocket.BinaryMessage {
+				if _, err := session.Write(data); err != nil {
+					return
+				}
+				continue
+			}
+
+			var control execControl
+			if err := json.Unmarshal(data, &control); err != nil {
+				continue
+			}
+			switch control.Type {
+			case "input":
+				if _, err := io.WriteString(session, control.Data); err != nil {
+					return
+				}
+			case "resize":
+				if control.Cols > 0 && control.Rows > 0 {
+					h.dockerService.ResizeExec(ctx, execID, control.Cols, control.Rows)
+				}
+			}
+		}
+	}()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := session.Read(buf)
+		if n > 0 {
+			conn.SetWriteDeadline(time.Now().Add(execWriteWait))
+			if conn.WriteMessage(websocket.BinaryMessage, buf[:n]) != nil {
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	// The request context may already be gone if the client disconnected
+	inspectCtx, cancel := context.WithTimeout(context.Background(), execWriteWait)
+	defer cancel()
+
+	details := map[string]any{
+		"exec_id":     execID,
+		"duration_ms": time.Since(startedAt).Milliseconds(),
+	}
+	running, exitCode, err := h.dockerService.ExecExitCode(inspectCtx, execID)
+	if err == nil && !running {
+		details["exit_code"] = exitCode
+		writeExecMessage(conn, map[string]any{"type": "exit", "exit_code": exitCode})
+	} else {
+		details["detached"] = true
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(execWriteWait))
+
+	h.record(audit.Entry{
+		User:    username,
+		Action:  "exec.end",
+		Target:  containerID,
+		Details: details,
+	})
+}
+
+func (h *ExecHandler) record(entry audit.Entry) {
+	if err := h.auditLog.Record(entry); err != nil {
+		log.Printf("Failed to record audit entry: %v", err)
+	}
+}
+
+// parseExecOptions reads the command, user and terminal size from the
+// query string. cmd may be repeated to pass arguments.
+func parseExecOptions(r *http.Request) (docker.ExecOptions, error) {
+	query := r.URL.Query()
+
+	opts := docker.ExecOptions{
+		Cmd:        query["cmd"],
+		User:       query.Get("user"),
+		WorkingDir: query.Get("workdir"),
+	}
+	if len(opts.Cmd) == 0 {
+		opts.Cmd = []string{defaultExecCommand}
+	}
+
+	for name, target := range map[string]*uint{"cols": &opts.Cols, "rows": &opts.Rows} {
+		if value := query.Get(name); value != "" {
+			parsed, err := strconv.ParseUint(value, 10, 16)
+			if err != nil || parsed == 0 {
+				return opts, fmt.Errorf("invalid %s: %s", name, value)
+			}
+			*target = uint(parsed)
+		}
+	}
+
+	return opts, nil
+}
+
+func writeExecMessage(conn *websocket.Conn, message map[string]any) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	conn.SetWriteDeadline(time.Now().Add(execWriteWait))
+	conn.WriteMessage(websocket.TextMessage, data)
+}
@@ -3,7 +3,11 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"strconv"
 
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/go-chi/chi/v5"
 	"github.com/noel-vega/hubble/docker"
 )
 
@@ -32,3 +36,168 @@ func (h *ImagesHandler) List(w http.ResponseWriter, r *http.Request) {
 		"count":  len(images),
 	})
 }
+
+// Pull pulls an image, sending each progress update as a "progress"
+// Server-Sent Event and an "end" event with the image ID once it is done
+func (h *ImagesHandler) Pull(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req struct {
+		Image    string `json:"image"`
+		Platform string `json:"platform"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Image == "" {
+		http.Error(w, "image is required", http.StatusBadRequest)
+		return
+	}
+
+	sse, ok := newSSEStream(w)
+	if !ok {
+		return
+	}
+
+	opts := docker.PullOptions{
+		Platform: req.Platform,
+		Username: req.Username,
+		Password: req.Password,
+	}
+	imageID, err := h.dockerService.PullImage(ctx, req.Image, opts, func(progress docker.PullProgress) error {
+		return sse.event("progress", progress)
+	})
+	if ctx.Err() != nil {
+		// The client went away
+		return
+	}
+	if err != nil && !sse.started {
+		writeImageError(w, err)
+		return
+	}
+
+	var end any
+	if err == nil {
+		end = map[string]string{"image": req.Image, "id": imageID}
+	}
+	sse.end(err, end)
+}
+
+// Get returns an image's configuration and history. The ID may also be a
+// reference such as "nginx:1.27", with "/" escaped as "%2F".
+func (h *ImagesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	imageID, err := url.PathUnescape(chi.URLParam(r, "id"))
+	if err != nil || imageID == "" {
+		http.Error(w, "image ID is required", http.StatusBadRequest)
+		return
+	}
+
+	details, err := h.dockerService.InspectImage(ctx, imageID)
+	if err != nil {
+		writeImageError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(details)
+}
+
+func (h *ImagesHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	imageID, err := url.PathUnescape(chi.URLParam(r, "id"))
+	if err != nil || imageID == "" {
+		http.Error(w, "image ID is required", http.StatusBadRequest)
+		return
+	}
+
+	force := r.URL.Query().Get("force") == "true"
+
+	removed, err := h.dockerService.RemoveImage(ctx, imageID, force)
+	if err != nil {
+		writeImageError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "image removed successfully",
+		"id":      imageID,
+		"removed": removed,
+	})
+}
+
+func (h *ImagesHandler) Tag(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	imageID, err := url.PathUnescape(chi.URLParam(r, "id"))
+	if err != nil || imageID == "" {
+		http.Error(w, "image ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Target string `json:"target"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Target == "" {
+		http.Error(w, "target is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.dockerService.TagImage(ctx, imageID, req.Target); err != nil {
+		writeImageError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "image tagged successfully",
+		"id":      imageID,
+		"target":  req.Target,
+	})
+}
+
+// Prune removes dangling images, or with all=true every image not used by
+// a container
+func (h *ImagesHandler) Prune(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	all := false
+	if value := r.URL.Query().Get("all"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "invalid all: "+value, http.StatusBadRequest)
+			return
+		}
+		all = parsed
+	}
+
+	report, err := h.dockerService.PruneImages(ctx, all)
+	if err != nil {
+		writeImageError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func writeImageError(w http.ResponseWriter, err error) {
+	if cerrdefs.IsNotFound(err) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else if cerrdefs.IsConflict(err) {
+		http.Error(w, err.Error(), http.StatusConflict)
+	} else if cerrdefs.IsInvalidArgument(err) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noel-vega/hubble/jobs"
+)
+
+type JobsHandler struct {
+	jobManager *jobs.Manager
+}
+
+func NewJobsHandler(jobManager *jobs.Manager) *JobsHandler {
+	return &JobsHandler{
+		jobManager: jobManager,
+	}
+}
+
+func (h *JobsHandler) List(w http.ResponseWriter, r *http.Request) {
+	jobList := h.jobManager.List(r.URL.Query().Get("project"))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"jobs":  jobList,
+		"count": len(jobList),
+	})
+}
+
+func (h *JobsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "id")
+
+	job, err := h.jobManager.Get(jobID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+func (h *JobsHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "id")
+
+	job, err := h.jobManager.Cancel(jobID)
+	if err != nil {
+		if err.Error() == "job not found: "+jobID {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if err.Error() == "job already finished: "+jobID {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "job cancelled",
+		"job":     job,
+	})
+}
+
+// Stream sends a job's output as Server-Sent Events: every line already
+// emitted is replayed, then new lines follow as they are written. Each line
+// is an "output" event whose id is its line number, so a reconnecting
+// client (Last-Event-ID) resumes where it left off. A final "end" event
+// carries the finished job.
+func (h *JobsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	jobID := chi.URLParam(r, "id")
+
+	if _, err := h.jobManager.Get(jobID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	sse, ok := newSSEStream(w)
+	if !ok {
+		return
+	}
+
+	from := 0
+	if lastID, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil {
+		from = lastID + 1
+	}
+
+	sse.start()
+
+	job, err := h.jobManager.Follow(ctx, jobID, from, func(number int, line string) error {
+		// A bare carriage return would end the SSE data line early
+		return sse.send("output", strconv.Itoa(number), strings.ReplaceAll(line, "\r", ""))
+	})
+	if err != nil {
+		// The client went away
+		return
+	}
+
+	sse.end(nil, job)
+}
+
+// writeJobAccepted responds 202 with body plus the queued job, pointing the
+// client at the job's status endpoint
+func writeJobAccepted(w http.ResponseWriter, job jobs.Job, body map[string]any) {
+	body["job"] = job
+
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/go-chi/chi/v5"
+	"github.com/noel-vega/hubble/docker"
+)
+
+// defaultLogTail is how many lines are returned per container when the
+// request does not say
+const defaultLogTail = "100"
+
+func (h *ContainersHandler) Logs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	containerID := chi.URLParam(r, "id")
+
+	if containerID == "" {
+		http.Error(w, "container ID is required", http.StatusBadRequest)
+		return
+	}
+
+	opts, err := parseLogOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeLogs(w, r, opts, func(opts docker.LogOptions, fn func(docker.LogLine) error) error {
+		return h.dockerService.StreamContainerLogs(ctx, containerID, opts, fn)
+	})
+}
+
+func (h *ContainersHandler) ServiceLogs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	projectName := chi.URLParam(r, "name")
+	serviceName := chi.URLParam(r, "service")
+
+	if projectName == "" {
+		http.Error(w, "project name is required", http.StatusBadRequest)
+		return
+	}
+
+	if serviceName == "" {
+		http.Error(w, "service name is required", http.StatusBadRequest)
+		return
+	}
+
+	opts, err := parseLogOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeLogs(w, r, opts, func(opts docker.LogOptions, fn func(docker.LogLine) error) error {
+		return h.dockerService.StreamServiceLogs(ctx, projectName, serviceName, opts, fn)
+	})
+}
+
+// parseLogOptions reads tail, since, until, timestamps and follow from the
+// query string
+func parseLogOptions(r *http.Request) (docker.LogOptions, error) {
+	query := r.URL.Query()
+
+	opts := docker.LogOptions{
+		Tail:  query.Get("tail"),
+		Since: query.Get("since"),
+		Until: query.Get("until"),
+	}
+
+	if opts.Tail == "" {
+		opts.Tail = defaultLogTail
+	} else if opts.Tail != "all" {
+		if n, err := strconv.Atoi(opts.Tail); err != nil || n < 0 {
+			return opts, fmt.Errorf("invalid tail: %s", opts.Tail)
+		}
+	}
+
+	for name, target := range map[string]*bool{"timestamps": &opts.Timestamps, "follow": &opts.Follow} {
+		if value := query.Get(name); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return opts, fmt.Errorf("invalid %s: %s", name, value)
+			}
+			*target = parsed
+		}
+	}
+
+	return opts, nil
+}
+
+// writeLogs runs stream with opts and writes the lines it produces: as a
+// JSON list, or with follow as Server-Sent Events ("log" events with a JSON
+// line each, an "error" event if reading fails midway, then an "end" event
+// when the log closes)
+func writeLogs(w http.ResponseWriter, r *http.Request, opts docker.LogOptions, stream func(opts docker.LogOptions, fn func(docker.LogLine) error) error) {
+	if !opts.Follow {
+		lines := make([]docker.LogLine, 0)
+		err := stream(opts, func(line docker.LogLine) error {
+			lines = append(lines, line)
+			return nil
+		})
+		if err != nil {
+			writeLogsError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"logs":  lines,
+			"count": len(lines),
+		})
+		return
+	}
+
+	sse, ok := newSSEStream(w)
+	if !ok {
+		return
+	}
+
+	// Headers are sent as soon as Docker accepts the request, so clients see
+	// the stream open even when no lines arrive for a while, while errors
+	// before then (such as an unknown container) still get a proper status
+	opts.OnOpen = sse.start
+	err := stream(opts, func(line docker.LogLine) error {
+		return sse.event("log", line)
+	})
+	if r.Context().Err() != nil {
+		// The client went away
+		return
+	}
+	if err != nil && !sse.started {
+		writeLogsError(w, err)
+		return
+	}
+	sse.end(err, nil)
+}
+
+func writeLogsError(w http.ResponseWriter, err error) {
+	if cerrdefs.IsNotFound(err) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
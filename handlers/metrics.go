@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noel-vega/hubble/metrics"
+)
+
+// defaultMetricsRange is how far back a history query goes when from is
+// not given
+const defaultMetricsRange = time.Hour
+
+type MetricsHandler struct {
+	store *metrics.Store
+}
+
+func NewMetricsHandler(store *metrics.Store) *MetricsHandler {
+	return &MetricsHandler{
+		store: store,
+	}
+}
+
+func (h *MetricsHandler) ContainerHistory(w http.ResponseWriter, r *http.Request) {
+	containerID := chi.URLParam(r, "id")
+
+	if containerID == "" {
+		http.Error(w, "container ID is required", http.StatusBadRequest)
+		return
+	}
+
+	from, to, step, err := parseMetricsRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.store.ContainerHistory(containerID, from, to, step)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"container":  containerID,
+		"from":       history.From,
+		"to":         history.To,
+		"step":       history.Step,
+		"resolution": history.Resolution,
+		"points":     history.Points,
+	})
+}
+
+func (h *MetricsHandler) ProjectHistory(w http.ResponseWriter, r *http.Request) {
+	projectName := chi.URLParam(r, "name")
+
+	if projectName == "" {
+		http.Error(w, "project name is required", http.StatusBadRequest)
+		return
+	}
+
+	from, to, step, err := parseMetricsRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.store.ProjectHistory(projectName, from, to, step)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"project":    projectName,
+		"from":       history.From,
+		"to":         history.To,
+		"step":       history.Step,
+		"resolution": history.Resolution,
+		"points":     history.Points,
+	})
+}
+
+// parseMetricsRange reads from, to and step from the query string. Times
+// are RFC 3339, Unix seconds or a duration before now such as "6h".
+func parseMetricsRange(r *http.Request) (from, to time.Time, step time.Duration, err error) {
+	query := r.URL.Query()
+	now := time.Now().UTC()
+
+	from = now.Add(-defaultMetricsRange)
+	to = now
+	for name, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := query.Get(name); value != "" {
+			parsed, ok := parseMetricsTime(value, now)
+			if !ok {
+				return from, to, 0, fmt.Errorf("invalid %s: %s", name, value)
+			}
+			*target = parsed
+		}
+	}
+
+	if value := query.Get("step"); value != "" {
+		step, err = time.ParseDuration(value)
+		if err != nil || step <= 0 {
+			return from, to, 0, fmt.Errorf("invalid step: %s", value)
+		}
+	}
+
+	return from, to, step, nil
+}
+
+func parseMetricsTime(value string, now time.Time) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0).UTC(), true
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), true
+	}
+	return time.Time{}, false
+}
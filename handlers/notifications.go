@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noel-vega/hubble/notifications"
+)
+
+type NotificationsHandler struct {
+	notifier *notifications.Service
+}
+
+func NewNotificationsHandler(notifier *notifications.Service) *NotificationsHandler {
+	return &NotificationsHandler{
+		notifier: notifier,
+	}
+}
+
+func (h *NotificationsHandler) ListChannels(w http.ResponseWriter, r *http.Request) {
+	channels := h.notifier.ListChannels()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"channels": channels,
+		"count":    len(channels),
+	})
+}
+
+func (h *NotificationsHandler) GetChannel(w http.ResponseWriter, r *http.Request) {
+	channelID := chi.URLParam(r, "id")
+
+	channel, err := h.notifier.GetChannel(channelID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(channel)
+}
+
+func (h *NotificationsHandler) CreateChannel(w http.ResponseWriter, r *http.Request) {
+	var channel notifications.Channel
+	if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.notifier.CreateChannel(channel)
+	if err != nil {
+		writeChannelError(w, err, "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *NotificationsHandler) UpdateChannel(w http.ResponseWriter, r *http.Request) {
+	channelID := chi.URLParam(r, "id")
+
+	var channel notifications.Channel
+	if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.notifier.UpdateChannel(channelID, channel)
+	if err != nil {
+		writeChannelError(w, err, channelID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *NotificationsHandler) DeleteChannel(w http.ResponseWriter, r *http.Request) {
+	channelID := chi.URLParam(r, "id")
+
+	if err := h.notifier.DeleteChannel(channelID); err != nil {
+		writeChannelError(w, err, channelID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "channel deleted successfully",
+		"id":      channelID,
+	})
+}
+
+// TestChannel sends a test notification through a channel and reports the
+// delivery, including why it failed
+func (h *NotificationsHandler) TestChannel(w http.ResponseWriter, r *http.Request) {
+	channelID := chi.URLParam(r, "id")
+
+	delivery, err := h.notifier.Test(r.Context(), channelID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// ListDeliveries returns recent deliveries, newest first, optionally
+// filtered by channel, status and project
+func (h *NotificationsHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := notifications.DeliveryFilter{
+		ChannelID: query.Get("channel"),
+		Status:    notifications.DeliveryStatus(query.Get("status")),
+		Project:   query.Get("project"),
+	}
+	if filter.Status != "" && filter.Status != notifications.DeliveryDelivered && filter.Status != notifications.DeliveryFailed {
+		http.Error(w, "invalid status: "+query.Get("status"), http.StatusBadRequest)
+		return
+	}
+
+	limit := 100
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	deliveries := h.notifier.Deliveries(filter, limit)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+	})
+}
+
+func writeChannelError(w http.ResponseWriter, err error, channelID string) {
+	if err.Error() == "channel not found: "+channelID {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else if strings.HasPrefix(err.Error(), "invalid channel:") {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -1,23 +1,48 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/noel-vega/hubble/jobs"
+	"github.com/noel-vega/hubble/middleware"
+	"github.com/noel-vega/hubble/notifications"
 	"github.com/noel-vega/hubble/projects"
 )
 
 type ProjectsHandler struct {
 	projectsService *projects.Service
+	jobManager      *jobs.Manager
+	// notifier may be nil, in which case nothing is notified
+	notifier *notifications.Service
 }
 
-func NewProjectsHandler(projectsService *projects.Service) *ProjectsHandler {
+func NewProjectsHandler(projectsService *projects.Service, jobManager *jobs.Manager, notifier *notifications.Service) *ProjectsHandler {
 	return &ProjectsHandler{
 		projectsService: projectsService,
+		jobManager:      jobManager,
+		notifier:        notifier,
 	}
 }
 
+// composeContext returns the request context for compose file writes,
+// carrying the user to record in the revision and the If-Match precondition.
+// A successful write sets the ETag of the new compose file on w.
+func composeContext(w http.ResponseWriter, r *http.Request) context.Context {
+	ctx := projects.WithUser(r.Context(), middleware.GetUsername(r))
+	ctx = projects.WithIfMatch(ctx, r.Header.Get("If-Match"))
+	return projects.WithETagFunc(ctx, func(etag string) {
+		w.Header().Set("ETag", etag)
+	})
+}
+
 func (h *ProjectsHandler) List(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -68,6 +93,7 @@ func (h *ProjectsHandler) GetCompose(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", projects.ComposeETag([]byte(composeContent)))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
 		"content": composeContent,
@@ -149,12 +175,21 @@ func (h *ProjectsHandler) GetNetworks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// As with services, the ETag is taken first so it is never newer than
+	// the networks returned
+	etag, err := h.projectsService.GetComposeETag(ctx, projectName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
 	networks, err := h.projectsService.GetProjectNetworks(ctx, projectName)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
+	w.Header().Set("ETag", etag)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
 		"networks": networks,
@@ -171,12 +206,22 @@ func (h *ProjectsHandler) GetServices(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Take the ETag before reading the services: if the file changes in
+	// between, the ETag is stale and a write based on it fails with 412
+	// instead of silently overwriting the newer file
+	etag, err := h.projectsService.GetComposeETag(ctx, projectName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
 	services, err := h.projectsService.GetProjectServices(ctx, projectName)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
+	w.Header().Set("ETag", etag)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
 		"services": services,
@@ -185,7 +230,6 @@ func (h *ProjectsHandler) GetServices(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ProjectsHandler) StartService(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
 	projectName := chi.URLParam(r, "name")
 	serviceName := chi.URLParam(r, "service")
 
@@ -199,15 +243,18 @@ func (h *ProjectsHandler) StartService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.projectsService.StartService(ctx, projectName, serviceName)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.projectsService.ValidateProject(projectName); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{
-		"message": "service started successfully",
+	// Starting may create the containers and pull images, so it runs as a job
+	job := h.jobManager.Submit("start", projectName, middleware.GetUsername(r), func(ctx context.Context, out io.Writer) error {
+		return h.projectsService.StartService(ctx, projectName, serviceName, out)
+	})
+
+	writeJobAccepted(w, job, map[string]any{
+		"message": "service start queued",
 		"project": projectName,
 		"service": serviceName,
 	})
@@ -243,7 +290,7 @@ func (h *ProjectsHandler) StopService(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ProjectsHandler) Create(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := composeContext(w, r)
 
 	var req struct {
 		Name string `json:"name"`
@@ -269,6 +316,8 @@ func (h *ProjectsHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.notifier.Notify(notifications.ProjectCreated(req.Name, middleware.GetUsername(r)))
+
 	// Get the created project info
 	project, err := h.projectsService.GetProject(ctx, req.Name)
 	if err != nil {
@@ -285,7 +334,7 @@ func (h *ProjectsHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ProjectsHandler) AddService(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := composeContext(w, r)
 	projectName := chi.URLParam(r, "name")
 
 	if projectName == "" {
@@ -310,6 +359,10 @@ func (h *ProjectsHandler) AddService(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusConflict)
 		} else if err.Error() == "project not found: "+projectName {
 			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if err.Error() == "compose file has been modified: "+projectName {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		} else if err.Error() == "compose file precondition required: "+projectName {
+			http.Error(w, err.Error(), http.StatusPreconditionRequired)
 		} else {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
@@ -326,7 +379,7 @@ func (h *ProjectsHandler) AddService(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ProjectsHandler) UpdateService(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := composeContext(w, r)
 	projectName := chi.URLParam(r, "name")
 	serviceName := chi.URLParam(r, "service")
 
@@ -355,6 +408,10 @@ func (h *ProjectsHandler) UpdateService(w http.ResponseWriter, r *http.Request)
 			http.Error(w, err.Error(), http.StatusNotFound)
 		} else if err.Error() == "project not found: "+projectName {
 			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if err.Error() == "compose file has been modified: "+projectName {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		} else if err.Error() == "compose file precondition required: "+projectName {
+			http.Error(w, err.Error(), http.StatusPreconditionRequired)
 		} else {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
@@ -370,7 +427,7 @@ func (h *ProjectsHandler) UpdateService(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *ProjectsHandler) DeleteService(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := composeContext(w, r)
 	projectName := chi.URLParam(r, "name")
 	serviceName := chi.URLParam(r, "service")
 
@@ -390,6 +447,10 @@ func (h *ProjectsHandler) DeleteService(w http.ResponseWriter, r *http.Request)
 			http.Error(w, err.Error(), http.StatusNotFound)
 		} else if err.Error() == "project not found: "+projectName {
 			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if err.Error() == "compose file has been modified: "+projectName {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		} else if err.Error() == "compose file precondition required: "+projectName {
+			http.Error(w, err.Error(), http.StatusPreconditionRequired)
 		} else {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
@@ -405,7 +466,7 @@ func (h *ProjectsHandler) DeleteService(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *ProjectsHandler) AddNetwork(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := composeContext(w, r)
 	projectName := chi.URLParam(r, "name")
 
 	if projectName == "" {
@@ -432,6 +493,10 @@ func (h *ProjectsHandler) AddNetwork(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 		} else if err.Error() == "external networks cannot specify a driver (driver is managed by the existing network)" {
 			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else if err.Error() == "compose file has been modified: "+projectName {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		} else if err.Error() == "compose file precondition required: "+projectName {
+			http.Error(w, err.Error(), http.StatusPreconditionRequired)
 		} else {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
@@ -448,7 +513,7 @@ func (h *ProjectsHandler) AddNetwork(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ProjectsHandler) UpdateNetwork(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := composeContext(w, r)
 	projectName := chi.URLParam(r, "name")
 	networkName := chi.URLParam(r, "network")
 
@@ -479,6 +544,10 @@ func (h *ProjectsHandler) UpdateNetwork(w http.ResponseWriter, r *http.Request)
 			http.Error(w, err.Error(), http.StatusNotFound)
 		} else if err.Error() == "external networks cannot specify a driver (driver is managed by the existing network)" {
 			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else if err.Error() == "compose file has been modified: "+projectName {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		} else if err.Error() == "compose file precondition required: "+projectName {
+			http.Error(w, err.Error(), http.StatusPreconditionRequired)
 		} else {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
@@ -494,7 +563,7 @@ func (h *ProjectsHandler) UpdateNetwork(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *ProjectsHandler) DeleteNetwork(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := composeContext(w, r)
 	projectName := chi.URLParam(r, "name")
 	networkName := chi.URLParam(r, "network")
 
@@ -514,6 +583,10 @@ func (h *ProjectsHandler) DeleteNetwork(w http.ResponseWriter, r *http.Request)
 			http.Error(w, err.Error(), http.StatusNotFound)
 		} else if err.Error() == "project not found: "+projectName {
 			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if err.Error() == "compose file has been modified: "+projectName {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		} else if err.Error() == "compose file precondition required: "+projectName {
+			http.Error(w, err.Error(), http.StatusPreconditionRequired)
 		} else {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
@@ -527,3 +600,416 @@ func (h *ProjectsHandler) DeleteNetwork(w http.ResponseWriter, r *http.Request)
 		"network": networkName,
 	})
 }
+
+func (h *ProjectsHandler) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	projectName := chi.URLParam(r, "name")
+
+	if projectName == "" {
+		http.Error(w, "project name is required", http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := h.projectsService.ListRevisions(ctx, projectName)
+	if err != nil {
+		if err.Error() == "project not found: "+projectName {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"revisions": revisions,
+		"count":     len(revisions),
+	})
+}
+
+func (h *ProjectsHandler) GetRevision(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	projectName := chi.URLParam(r, "name")
+
+	if projectName == "" {
+		http.Error(w, "project name is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid revision id", http.StatusBadRequest)
+		return
+	}
+
+	revision, err := h.projectsService.GetRevision(ctx, projectName, id)
+	if err != nil {
+		if err.Error() == "project not found: "+projectName || err.Error() == fmt.Sprintf("revision not found: %d", id) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revision)
+}
+
+func (h *ProjectsHandler) DiffRevision(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	projectName := chi.URLParam(r, "name")
+
+	if projectName == "" {
+		http.Error(w, "project name is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid revision id", http.StatusBadRequest)
+		return
+	}
+
+	against := r.URL.Query().Get("against")
+
+	diff, err := h.projectsService.DiffRevision(ctx, projectName, id, against)
+	if err != nil {
+		if err.Error() == "project not found: "+projectName || strings.HasPrefix(err.Error(), "revision not found: ") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if err.Error() == "invalid revision: "+against {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"revision": id,
+		"against":  against,
+		"diff":     diff,
+	})
+}
+
+func (h *ProjectsHandler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	ctx := composeContext(w, r)
+	projectName := chi.URLParam(r, "name")
+
+	if projectName == "" {
+		http.Error(w, "project name is required", http.StatusBadRequest)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid revision id", http.StatusBadRequest)
+		return
+	}
+
+	revision, err := h.projectsService.RestoreRevision(ctx, projectName, id)
+	if err != nil {
+		if err.Error() == "project not found: "+projectName || err.Error() == fmt.Sprintf("revision not found: %d", id) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if err.Error() == "compose file has been modified: "+projectName {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		} else if err.Error() == "compose file precondition required: "+projectName {
+			http.Error(w, err.Error(), http.StatusPreconditionRequired)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// A nil revision means the compose file already matched
+	message := "revision restored successfully"
+	if revision == nil {
+		message = "compose file already matches revision"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":  message,
+		"project":  projectName,
+		"restored": id,
+		"revision": revision,
+	})
+}
+
+// decodeOptionalBody decodes a JSON request body into v, leaving v as is
+// when the body is empty
+func decodeOptionalBody(r *http.Request, v any) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+func (h *ProjectsHandler) Up(w http.ResponseWriter, r *http.Request) {
+	projectName := chi.URLParam(r, "name")
+
+	var opts projects.UpOptions
+	if err := decodeOptionalBody(r, &opts); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := opts.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.submitProjectJob(w, r, "up", func(ctx context.Context, out io.Writer) error {
+		return h.projectsService.UpProject(ctx, projectName, opts, out)
+	})
+}
+
+func (h *ProjectsHandler) Down(w http.ResponseWriter, r *http.Request) {
+	projectName := chi.URLParam(r, "name")
+
+	var opts projects.DownOptions
+	if err := decodeOptionalBody(r, &opts); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	h.submitProjectJob(w, r, "down", func(ctx context.Context, out io.Writer) error {
+		return h.projectsService.DownProject(ctx, projectName, opts, out)
+	})
+}
+
+func (h *ProjectsHandler) Restart(w http.ResponseWriter, r *http.Request) {
+	projectName := chi.URLParam(r, "name")
+
+	var req struct {
+		Services []string `json:"services"`
+	}
+	if err := decodeOptionalBody(r, &req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	h.submitProjectJob(w, r, "restart", func(ctx context.Context, out io.Writer) error {
+		return h.projectsService.RestartProject(ctx, projectName, req.Services, out)
+	})
+}
+
+func (h *ProjectsHandler) Pull(w http.ResponseWriter, r *http.Request) {
+	projectName := chi.URLParam(r, "name")
+
+	var req struct {
+		Services []string `json:"services"`
+	}
+	if err := decodeOptionalBody(r, &req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	h.submitProjectJob(w, r, "pull", func(ctx context.Context, out io.Writer) error {
+		return h.projectsService.PullProject(ctx, projectName, req.Services, out)
+	})
+}
+
+// BuildService builds a service's image as a job whose output is the build
+// log; the build itself is recorded with its duration and image digest
+func (h *ProjectsHandler) BuildService(w http.ResponseWriter, r *http.Request) {
+	projectName := chi.URLParam(r, "name")
+	serviceName := chi.URLParam(r, "service")
+
+	if projectName == "" {
+		http.Error(w, "project name is required", http.StatusBadRequest)
+		return
+	}
+
+	if serviceName == "" {
+		http.Error(w, "service name is required", http.StatusBadRequest)
+		return
+	}
+
+	var opts projects.BuildOptions
+	if err := decodeOptionalBody(r, &opts); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.projectsService.ValidateBuild(projectName, serviceName, opts); err != nil {
+		if err.Error() == "project not found: "+projectName || err.Error() == "service not found: "+serviceName {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	username := middleware.GetUsername(r)
+	job := h.jobManager.Submit("build", projectName, username, func(ctx context.Context, out io.Writer) error {
+		build, err := h.projectsService.BuildService(projects.WithUser(ctx, username), projectName, serviceName, opts, out)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Built %s (%s) in %.1fs\n", build.Image, build.ImageID, build.Duration)
+		if build.PushedTo != "" {
+			fmt.Fprintf(out, "Pushed %s@%s\n", build.PushedTo, build.Digest)
+		}
+		return nil
+	})
+
+	writeJobAccepted(w, job, map[string]any{
+		"message": "service build queued",
+		"project": projectName,
+		"service": serviceName,
+	})
+}
+
+// ListBuilds returns a project's recorded builds, newest first
+func (h *ProjectsHandler) ListBuilds(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	projectName := chi.URLParam(r, "name")
+
+	if projectName == "" {
+		http.Error(w, "project name is required", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 50
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	builds, err := h.projectsService.ListBuilds(ctx, projectName, query.Get("service"), limit)
+	if err != nil {
+		if err.Error() == "project not found: "+projectName {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"builds": builds,
+		"count":  len(builds),
+	})
+}
+
+// submitProjectJob checks the project exists and queues fn as a job of the
+// given type, responding with 202 and the job
+func (h *ProjectsHandler) submitProjectJob(w http.ResponseWriter, r *http.Request, jobType string, fn jobs.Func) {
+	projectName := chi.URLParam(r, "name")
+
+	if projectName == "" {
+		http.Error(w, "project name is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.projectsService.ValidateProject(projectName); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	job := h.jobManager.Submit(jobType, projectName, middleware.GetUsername(r), fn)
+
+	writeJobAccepted(w, job, map[string]any{
+		"message": "project " + jobType + " queued",
+		"project": projectName,
+	})
+}
+
+// Delete removes a project as a job, so it waits for any up or build
+// already queued for the project instead of racing it
+func (h *ProjectsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	projectName := chi.URLParam(r, "name")
+
+	query := r.URL.Query()
+	opts := projects.DeleteOptions{
+		RemoveVolumes:  query.Get("remove_volumes") == "true",
+		RemoveNetworks: query.Get("remove_networks") == "true",
+		Archive:        query.Get("archive") == "true",
+	}
+
+	username := middleware.GetUsername(r)
+	h.submitProjectJob(w, r, "delete", func(ctx context.Context, out io.Writer) error {
+		archived, err := h.projectsService.DeleteProject(projects.WithUser(ctx, username), projectName, opts, out)
+		if err != nil {
+			return err
+		}
+
+		if archived != nil {
+			fmt.Fprintf(out, "Archived project %s as %s\n", projectName, archived.ID)
+		} else {
+			fmt.Fprintf(out, "Deleted project %s\n", projectName)
+		}
+
+		h.notifier.Notify(notifications.ProjectDeleted(projectName, username, archived != nil))
+		return nil
+	})
+}
+
+func (h *ProjectsHandler) ListArchived(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	archived, err := h.projectsService.ListArchivedProjects(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"archives": archived,
+		"count":    len(archived),
+	})
+}
+
+func (h *ProjectsHandler) RestoreArchived(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	archived, err := h.projectsService.RestoreArchivedProject(ctx, id)
+	if err != nil {
+		if err.Error() == "archived project not found: "+id {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if strings.HasPrefix(err.Error(), "project already exists: ") {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "project restored successfully",
+		"project": archived.Project,
+	})
+}
+
+func (h *ProjectsHandler) DeleteArchived(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	if err := h.projectsService.DeleteArchivedProject(ctx, id); err != nil {
+		if err.Error() == "archived project not found: "+id {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "archived project deleted successfully",
+		"archive": id,
+	})
+}
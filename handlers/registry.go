@@ -3,6 +3,8 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/noel-vega/hubble/registry"
@@ -78,3 +80,111 @@ func (h *RegistryHandler) ListRepositoriesWithTags(w http.ResponseWriter, r *htt
 		"count":        len(repositories),
 	})
 }
+
+// GetTag returns the digest, size, layers, platforms and labels of the
+// image a tag points to. Repository names containing a slash are passed
+// URL-encoded, e.g. team%2Fapp.
+func (h *RegistryHandler) GetTag(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	repoName, err := url.PathUnescape(chi.URLParam(r, "name"))
+	if err != nil || repoName == "" {
+		http.Error(w, "invalid repository name", http.StatusBadRequest)
+		return
+	}
+	tag := chi.URLParam(r, "tag")
+
+	details, err := h.registryClient.GetTag(ctx, repoName, tag)
+	if err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(details)
+}
+
+// DeleteTag deletes the manifest a tag points to, along with any other
+// tags of the same manifest
+func (h *RegistryHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	repoName, err := url.PathUnescape(chi.URLParam(r, "name"))
+	if err != nil || repoName == "" {
+		http.Error(w, "invalid repository name", http.StatusBadRequest)
+		return
+	}
+	tag := chi.URLParam(r, "tag")
+
+	digest, err := h.registryClient.DeleteTag(ctx, repoName, tag)
+	if err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":    "tag deleted successfully",
+		"repository": repoName,
+		"tag":        tag,
+		"digest":     digest,
+	})
+}
+
+// DeleteRepository deletes every tag of a repository
+func (h *RegistryHandler) DeleteRepository(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	repoName, err := url.PathUnescape(chi.URLParam(r, "name"))
+	if err != nil || repoName == "" {
+		http.Error(w, "invalid repository name", http.StatusBadRequest)
+		return
+	}
+
+	deletion, err := h.registryClient.DeleteRepository(ctx, repoName)
+	if err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deletion)
+}
+
+// GarbageCollect deletes blobs no manifest references and reports the
+// space reclaimed
+func (h *RegistryHandler) GarbageCollect(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var opts registry.GCOptions
+	if err := decodeOptionalBody(r, &opts); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.registryClient.GarbageCollect(ctx, opts)
+	if err != nil {
+		writeRegistryError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func writeRegistryError(w http.ResponseWriter, err error) {
+	message := err.Error()
+	if strings.Contains(message, "status 404") || strings.Contains(message, "No such container") {
+		http.Error(w, message, http.StatusNotFound)
+	} else if strings.Contains(message, "invalid repository name") || strings.Contains(message, "invalid tag") {
+		http.Error(w, message, http.StatusBadRequest)
+	} else if strings.Contains(message, "deletion is disabled") {
+		http.Error(w, message, http.StatusMethodNotAllowed)
+	} else if strings.Contains(message, "already running") || strings.Contains(message, "is not running") {
+		http.Error(w, message, http.StatusConflict)
+	} else if strings.Contains(message, "not available") {
+		http.Error(w, message, http.StatusServiceUnavailable)
+	} else {
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
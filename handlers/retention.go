@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noel-vega/hubble/retention"
+)
+
+type RetentionHandler struct {
+	manager *retention.Manager
+}
+
+func NewRetentionHandler(manager *retention.Manager) *RetentionHandler {
+	return &RetentionHandler{
+		manager: manager,
+	}
+}
+
+func (h *RetentionHandler) ListPolicies(w http.ResponseWriter, r *http.Request) {
+	policies := h.manager.ListPolicies()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"policies": policies,
+		"count":    len(policies),
+	})
+}
+
+func (h *RetentionHandler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	policyID := chi.URLParam(r, "id")
+
+	policy, err := h.manager.GetPolicy(policyID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+func (h *RetentionHandler) CreatePolicy(w http.ResponseWriter, r *http.Request) {
+	var policy retention.Policy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.manager.CreatePolicy(policy)
+	if err != nil {
+		writePolicyError(w, err, "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *RetentionHandler) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	policyID := chi.URLParam(r, "id")
+
+	var policy retention.Policy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.manager.UpdatePolicy(policyID, policy)
+	if err != nil {
+		writePolicyError(w, err, policyID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *RetentionHandler) DeletePolicy(w http.ResponseWriter, r *http.Request) {
+	policyID := chi.URLParam(r, "id")
+
+	if err := h.manager.DeletePolicy(policyID); err != nil {
+		writePolicyError(w, err, policyID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "policy deleted successfully",
+		"id":      policyID,
+	})
+}
+
+// Preview reports what applying the policies now would delete
+func (h *RetentionHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	report, err := h.manager.Preview(r.Context())
+	if err != nil {
+		writeRetentionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// Apply applies the policies now instead of waiting for the next scheduled
+// run
+func (h *RetentionHandler) Apply(w http.ResponseWriter, r *http.Request) {
+	report, err := h.manager.Apply(r.Context())
+	if err != nil {
+		writeRetentionError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// LastRun returns the report of the most recent run
+func (h *RetentionHandler) LastRun(w http.ResponseWriter, r *http.Request) {
+	report := h.manager.LastRun()
+	if report == nil {
+		http.Error(w, "no retention run since startup", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func writePolicyError(w http.ResponseWriter, err error, policyID string) {
+	if err.Error() == "policy not found: "+policyID {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else if strings.HasPrefix(err.Error(), "invalid policy:") {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeRetentionError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "already running") {
+		http.Error(w, err.Error(), http.StatusConflict)
+	} else {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
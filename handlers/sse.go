@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// sseStream writes Server-Sent Events. Headers are only sent by start or
+// with the first event, so an error before then can still be written as a
+// plain response with a proper status.
+type sseStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	started bool
+}
+
+// newSSEStream returns a stream writing to w, or responds with an error and
+// returns false if w cannot be flushed
+func newSSEStream(w http.ResponseWriter) (*sseStream, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return nil, false
+	}
+	return &sseStream{w: w, flusher: flusher}, true
+}
+
+// start sends the headers if they have not been sent yet
+func (s *sseStream) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.w.Header().Set("Content-Type", "text/event-stream")
+	s.w.Header().Set("Cache-Control", "no-cache")
+	s.w.Header().Set("Connection", "keep-alive")
+	s.w.Header().Set("X-Accel-Buffering", "no")
+	s.w.WriteHeader(http.StatusOK)
+	s.flusher.Flush()
+}
+
+// event sends v as the JSON data of an event called name
+func (s *sseStream) event(name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.send(name, "", string(data))
+}
+
+// send sends an event called name with data as is, and id if it is set.
+// data must be a single line.
+func (s *sseStream) send(name, id, data string) error {
+	s.start()
+	frame := "event: " + name + "\n"
+	if id != "" {
+		frame += "id: " + id + "\n"
+	}
+	if _, err := fmt.Fprintf(s.w, "%sdata: %s\n\n", frame, data); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// comment sends a comment, which clients ignore, to keep an idle stream
+// open through proxies
+func (s *sseStream) comment(text string) error {
+	s.start()
+	if _, err := fmt.Fprintf(s.w, ": %s\n\n", text); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// end closes the stream: an "error" event if err is set, then an "end"
+// event with data, or an empty object if data is nil
+func (s *sseStream) end(err error, data any) {
+	if err != nil {
+		s.event("error", map[string]string{"error": err.Error()})
+	}
+	if data == nil {
+		data = struct{}{}
+	}
+	s.event("end", data)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/go-chi/chi/v5"
+	"github.com/noel-vega/hubble/docker"
+)
+
+// Stats returns a container's resource usage, or with stream=true sends a
+// "stats" Server-Sent Event about once a second until the container stops
+func (h *ContainersHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	containerID := chi.URLParam(r, "id")
+
+	if containerID == "" {
+		http.Error(w, "container ID is required", http.StatusBadRequest)
+		return
+	}
+
+	stream := false
+	if value := r.URL.Query().Get("stream"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "invalid stream: "+value, http.StatusBadRequest)
+			return
+		}
+		stream = parsed
+	}
+
+	if !stream {
+		stats, err := h.dockerService.GetContainerStats(ctx, containerID)
+		if err != nil {
+			writeStatsError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+		return
+	}
+
+	sse, ok := newSSEStream(w)
+	if !ok {
+		return
+	}
+
+	err := h.dockerService.StreamContainerStats(ctx, containerID, func(stats docker.ContainerStats) error {
+		return sse.event("stats", stats)
+	})
+	if ctx.Err() != nil {
+		// The client went away
+		return
+	}
+	if err != nil && !sse.started {
+		writeStatsError(w, err)
+		return
+	}
+	sse.end(err, nil)
+}
+
+// ProjectStats returns the resource usage of a project's running
+// containers and their totals
+func (h *ContainersHandler) ProjectStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	projectName := chi.URLParam(r, "name")
+
+	if projectName == "" {
+		http.Error(w, "project name is required", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.dockerService.GetProjectStats(ctx, projectName)
+	if err != nil {
+		writeStatsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+func writeStatsError(w http.ResponseWriter, err error) {
+	if cerrdefs.IsNotFound(err) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
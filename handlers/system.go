@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/noel-vega/hubble/docker"
+)
+
+type SystemHandler struct {
+	dockerService *docker.Service
+}
+
+func NewSystemHandler(dockerService *docker.Service) *SystemHandler {
+	return &SystemHandler{
+		dockerService: dockerService,
+	}
+}
+
+// DiskUsage returns the space used by images, containers, volumes and the
+// build cache, with the project that owns each of them
+func (h *SystemHandler) DiskUsage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	usage, err := h.dockerService.GetDiskUsage(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usage)
+}
+
+// Prune removes unused objects in the requested scopes, or with dry_run
+// reports what would be removed
+func (h *SystemHandler) Prune(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var opts docker.PruneOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := opts.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.dockerService.Prune(ctx, opts)
+	if err != nil {
+		// Docker refuses a prune while another one of the same kind runs
+		if cerrdefs.IsConflict(err) {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
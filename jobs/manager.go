@@ -0,0 +1,379 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type State string
+
+const (
+	StateQueued    State = "queued"
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// maxFinishedJobs is how many finished jobs are kept for inspection
+const maxFinishedJobs = 500
+
+// cancelWait is how long Cancel waits for a job to wind down
+const cancelWait = 10 * time.Second
+
+// maxOutputLines bounds the output kept per job; older lines are dropped
+const maxOutputLines = 10000
+
+// Func is the work a job performs. Output written to out is captured on
+// the job; ctx is cancelled when the job is cancelled.
+type Func func(ctx context.Context, out io.Writer) error
+
+// Job is a snapshot of a job's state
+type Job struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	Project    string     `json:"project"`
+	User       string     `json:"user"`
+	State      State      `json:"state"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Output     string     `json:"output,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+type job struct {
+	Job
+	// Output is kept as complete lines plus the unterminated remainder so it
+	// can be streamed line by line. dropped counts lines discarded from the
+	// front once maxOutputLines is exceeded, keeping line numbers stable.
+	lines   []string
+	partial []byte
+	dropped int
+	// changed is closed and replaced whenever output or state changes
+	changed chan struct{}
+
+	cancel context.CancelFunc
+	// finished is closed once the job has reached a final state; done once
+	// its goroutine has returned and the next job of the project may start
+	finished chan struct{}
+	done     chan struct{}
+}
+
+// Manager runs jobs in the background. Jobs for the same project run one at
+// a time in submission order; jobs for different projects run concurrently.
+type Manager struct {
+	mu       sync.Mutex
+	jobs     map[string]*job
+	finished []string // IDs of finished jobs, oldest first
+	// last holds the done channel of each project's most recent job; a new
+	// job waits on it, chaining the project's jobs in submission order
+	last map[string]chan struct{}
+	// listeners are called with each job once it has finished
+	listeners []func(Job)
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		jobs: make(map[string]*job),
+		last: make(map[string]chan struct{}),
+	}
+}
+
+// OnFinish registers fn to be called with each job, without its output,
+// once it reaches a final state
+func (m *Manager) OnFinish(fn func(Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+// Submit queues fn as a new job and returns it without waiting for it to run
+func (m *Manager) Submit(jobType, project, user string, fn Func) Job {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	j := &job{
+		Job: Job{
+			ID:        newID(),
+			Type:      jobType,
+			Project:   project,
+			User:      user,
+			State:     StateQueued,
+			CreatedAt: time.Now().UTC(),
+		},
+		changed:  make(chan struct{}),
+		cancel:   cancel,
+		finished: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	m.jobs[j.ID] = j
+	previous := m.last[project]
+	m.last[project] = j.done
+	snapshot := j.snapshot(false)
+	m.mu.Unlock()
+
+	go m.run(ctx, j, previous, fn)
+
+	return snapshot
+}
+
+func (m *Manager) run(ctx context.Context, j *job, previous chan struct{}, fn Func) {
+	defer close(j.done)
+	defer j.cancel()
+
+	// Wait for the previous job of the same project. A job cancelled while
+	// queued still waits before signalling done, so the chain stays ordered.
+	if previous != nil {
+		select {
+		case <-previous:
+		case <-ctx.Done():
+			m.finish(j, StateCancelled, nil)
+			<-previous
+			return
+		}
+	}
+	if ctx.Err() != nil {
+		m.finish(j, StateCancelled, nil)
+		return
+	}
+
+	m.mu.Lock()
+	now := time.Now().UTC()
+	j.State = StateRunning
+	j.StartedAt = &now
+	j.notify()
+	m.mu.Unlock()
+
+	err := fn(ctx, &jobWriter{m: m, j: j})
+
+	switch {
+	case ctx.Err() != nil:
+		m.finish(j, StateCancelled, nil)
+	case err != nil:
+		m.finish(j, StateFailed, err)
+	default:
+		m.finish(j, StateSucceeded, nil)
+	}
+}
+
+func (m *Manager) finish(j *job, state State, err error) {
+	m.mu.Lock()
+
+	now := time.Now().UTC()
+	j.State = state
+	j.FinishedAt = &now
+	if err != nil {
+		j.Error = err.Error()
+	}
+
+	// Flush an unterminated last line so followers see it
+	if len(j.partial) > 0 {
+		j.appendLine(string(j.partial))
+		j.partial = nil
+	}
+	close(j.finished)
+	j.notify()
+
+	// Forget the oldest finished jobs beyond the limit
+	m.finished = append(m.finished, j.ID)
+	for len(m.finished) > maxFinishedJobs {
+		delete(m.jobs, m.finished[0])
+		m.finished = m.finished[1:]
+	}
+
+	snapshot, listeners := j.snapshot(false), m.listeners
+	m.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(snapshot)
+	}
+}
+
+// Get returns a job including its output
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return Job{}, fmt.Errorf("job not found: %s", id)
+	}
+
+	return j.snapshot(true), nil
+}
+
+// List returns jobs newest first, without output. An empty project lists
+// jobs of all projects.
+func (m *Manager) List(project string) []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		if project == "" || j.Project == project {
+			list = append(list, j.snapshot(false))
+		}
+	}
+
+	sort.Slice(list, func(i, k int) bool {
+		return list[i].CreatedAt.After(list[k].CreatedAt)
+	})
+
+	return list
+}
+
+// Cancel stops a queued or running job, killing any process started
+// through its context
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	if !ok {
+		m.mu.Unlock()
+		return Job{}, fmt.Errorf("job not found: %s", id)
+	}
+	if j.State != StateQueued && j.State != StateRunning {
+		m.mu.Unlock()
+		return Job{}, fmt.Errorf("job already finished: %s", id)
+	}
+	m.mu.Unlock()
+
+	j.cancel()
+	select {
+	case <-j.finished:
+	case <-time.After(cancelWait):
+	}
+
+	return m.Get(id)
+}
+
+// Wait blocks until a job has finished or ctx is done
+func (m *Manager) Wait(ctx context.Context, id string) (Job, error) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return Job{}, fmt.Errorf("job not found: %s", id)
+	}
+
+	select {
+	case <-j.finished:
+		return m.Get(id)
+	case <-ctx.Done():
+		return Job{}, ctx.Err()
+	}
+}
+
+// Follow calls fn for each output line of a job, starting at line number
+// from (0 for the beginning), replaying lines already emitted and then
+// waiting for new ones. It returns the final job once the job has finished
+// and all its output was delivered, or ctx's error if ctx ends first.
+// Lines that were already dropped from a long job's output are skipped.
+func (m *Manager) Follow(ctx context.Context, id string, from int, fn func(number int, line string) error) (Job, error) {
+	next := from
+	for {
+		m.mu.Lock()
+		j, ok := m.jobs[id]
+		if !ok {
+			m.mu.Unlock()
+			return Job{}, fmt.Errorf("job not found: %s", id)
+		}
+
+		next = min(max(next, j.dropped), j.dropped+len(j.lines))
+		pending := append([]string(nil), j.lines[next-j.dropped:]...)
+		first := next
+		next += len(pending)
+		isFinished := j.FinishedAt != nil
+		changed := j.changed
+		snapshot := j.snapshot(false)
+		m.mu.Unlock()
+
+		for i, line := range pending {
+			if err := fn(first+i, line); err != nil {
+				return Job{}, err
+			}
+		}
+
+		if isFinished {
+			return snapshot, nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return Job{}, ctx.Err()
+		}
+	}
+}
+
+// snapshot copies the job's state; callers must hold the manager lock
+func (j *job) snapshot(withOutput bool) Job {
+	snapshot := j.Job
+	if withOutput {
+		var b strings.Builder
+		for _, line := range j.lines {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+		b.Write(j.partial)
+		snapshot.Output = b.String()
+	}
+	return snapshot
+}
+
+// write splits p into lines; callers must hold the manager lock
+func (j *job) write(p []byte) {
+	data := append(j.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		j.appendLine(string(bytes.TrimSuffix(data[:i], []byte("\r"))))
+		data = data[i+1:]
+	}
+	j.partial = append([]byte(nil), data...)
+	j.notify()
+}
+
+func (j *job) appendLine(line string) {
+	j.lines = append(j.lines, line)
+	if len(j.lines) > maxOutputLines {
+		j.lines = j.lines[1:]
+		j.dropped++
+	}
+}
+
+// notify wakes everyone following the job; callers must hold the manager lock
+func (j *job) notify() {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// jobWriter appends to a job's output under the manager lock
+type jobWriter struct {
+	m *Manager
+	j *job
+}
+
+func (w *jobWriter) Write(p []byte) (int, error) {
+	w.m.mu.Lock()
+	defer w.m.mu.Unlock()
+	w.j.write(p)
+	return len(p), nil
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,218 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func waitJob(t *testing.T, m *Manager, id string) Job {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	job, err := m.Wait(ctx, id)
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	return job
+}
+
+func TestManager_CapturesOutputAndState(t *testing.T) {
+	m := NewManager()
+
+	ok := m.Submit("up", "site", "admin", func(ctx context.Context, out io.Writer) error {
+		fmt.Fprintln(out, "Container site-web-1  Started")
+		return nil
+	})
+	if ok.State != StateQueued || ok.User != "admin" {
+		t.Errorf("unexpected submitted job: %+v", ok)
+	}
+
+	failed := m.Submit("pull", "other", "admin", func(ctx context.Context, out io.Writer) error {
+		fmt.Fprintln(out, "pull access denied")
+		return errors.New("exit status 1")
+	})
+
+	job := waitJob(t, m, ok.ID)
+	if job.State != StateSucceeded || job.Output != "Container site-web-1  Started\n" {
+		t.Errorf("unexpected succeeded job: %+v", job)
+	}
+	if job.StartedAt == nil || job.FinishedAt == nil {
+		t.Errorf("expected start and finish times, got %+v", job)
+	}
+
+	job = waitJob(t, m, failed.ID)
+	if job.State != StateFailed || job.Error != "exit status 1" || job.Output != "pull access denied\n" {
+		t.Errorf("unexpected failed job: %+v", job)
+	}
+
+	if list := m.List("site"); len(list) != 1 || list[0].ID != ok.ID || list[0].Output != "" {
+		t.Errorf("List(site) = %+v", list)
+	}
+}
+
+func TestManager_OnFinish(t *testing.T) {
+	m := NewManager()
+
+	finished := make(chan Job, 2)
+	m.OnFinish(func(j Job) { finished <- j })
+
+	ok := m.Submit("up", "site", "admin", func(ctx context.Context, out io.Writer) error {
+		fmt.Fprintln(out, "Container site-web-1  Started")
+		return nil
+	})
+	failed := m.Submit("pull", "other", "admin", func(ctx context.Context, out io.Writer) error {
+		return errors.New("exit status 1")
+	})
+
+	states := make(map[string]State)
+	for range 2 {
+		select {
+		case j := <-finished:
+			if j.Output != "" {
+				t.Errorf("OnFinish got output for %s: %q", j.ID, j.Output)
+			}
+			states[j.ID] = j.State
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for OnFinish")
+		}
+	}
+	if states[ok.ID] != StateSucceeded || states[failed.ID] != StateFailed {
+		t.Errorf("OnFinish states = %v", states)
+	}
+}
+
+func TestManager_Cancel(t *testing.T) {
+	m := NewManager()
+	started := make(chan struct{})
+
+	running := m.Submit("up", "site", "", func(ctx context.Context, out io.Writer) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	queued := m.Submit("down", "site", "", func(ctx context.Context, out io.Writer) error {
+		t.Error("cancelled queued job should not run")
+		return nil
+	})
+	<-started
+
+	job, err := m.Cancel(queued.ID)
+	if err != nil {
+		t.Fatalf("Cancel queued failed: %v", err)
+	}
+	if job.State != StateCancelled || job.StartedAt != nil {
+		t.Errorf("unexpected cancelled queued job: %+v", job)
+	}
+
+	// The queued job must not overtake the one still running
+	if job, _ := m.Get(running.ID); job.State != StateRunning {
+		t.Errorf("running job state = %s, want running", job.State)
+	}
+
+	job, err = m.Cancel(running.ID)
+	if err != nil {
+		t.Fatalf("Cancel running failed: %v", err)
+	}
+	if job.State != StateCancelled {
+		t.Errorf("running job state after cancel = %s, want cancelled", job.State)
+	}
+
+	if _, err := m.Cancel(running.ID); err == nil || err.Error() != "job already finished: "+running.ID {
+		t.Errorf("expected already finished error, got %v", err)
+	}
+	if _, err := m.Get("missing"); err == nil || err.Error() != "job not found: missing" {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestManager_RunsProjectJobsInOrder(t *testing.T) {
+	m := NewManager()
+
+	var mu sync.Mutex
+	var order []int
+	var ids []string
+	for i := range 10 {
+		job := m.Submit("restart", "site", "", func(ctx context.Context, out io.Writer) error {
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			return nil
+		})
+		ids = append(ids, job.ID)
+	}
+
+	waitJob(t, m, ids[len(ids)-1])
+
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("jobs ran in order %v, want submission order", order)
+		}
+	}
+}
+
+func TestManager_FollowReplaysAndStreams(t *testing.T) {
+	m := NewManager()
+	release := make(chan struct{})
+
+	job := m.Submit("up", "site", "", func(ctx context.Context, out io.Writer) error {
+		io.WriteString(out, "line 0\nline 1\n")
+		<-release
+		io.WriteString(out, "line 2\r\nline ")
+		io.WriteString(out, "3")
+		return nil
+	})
+
+	// Wait until the first lines are out, then join late
+	for {
+		if j, _ := m.Get(job.ID); j.Output == "line 0\nline 1\n" {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	var got []string
+	done := make(chan Job)
+	go func() {
+		final, err := m.Follow(context.Background(), job.ID, 0, func(number int, line string) error {
+			got = append(got, fmt.Sprintf("%d:%s", number, line))
+			if number == 1 {
+				close(release)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Follow failed: %v", err)
+		}
+		done <- final
+	}()
+
+	final := <-done
+	if final.State != StateSucceeded {
+		t.Errorf("final state = %s, want succeeded", final.State)
+	}
+
+	want := []string{"0:line 0", "1:line 1", "2:line 2", "3:line 3"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("followed lines = %v, want %v", got, want)
+	}
+
+	// Resuming after line 2 only delivers what comes after it
+	got = nil
+	if _, err := m.Follow(context.Background(), job.ID, 3, func(number int, line string) error {
+		got = append(got, fmt.Sprintf("%d:%s", number, line))
+		return nil
+	}); err != nil {
+		t.Fatalf("Follow failed: %v", err)
+	}
+	if fmt.Sprint(got) != "[3:line 3]" {
+		t.Errorf("resumed lines = %v, want [3:line 3]", got)
+	}
+}
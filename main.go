@@ -1,21 +1,43 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/noel-vega/hubble/alerts"
+	"github.com/noel-vega/hubble/audit"
 	"github.com/noel-vega/hubble/auth"
 	"github.com/noel-vega/hubble/docker"
 	"github.com/noel-vega/hubble/handlers"
+	"github.com/noel-vega/hubble/jobs"
+	"github.com/noel-vega/hubble/metrics"
 	"github.com/noel-vega/hubble/middleware"
+	"github.com/noel-vega/hubble/notifications"
 	"github.com/noel-vega/hubble/platform"
 	"github.com/noel-vega/hubble/projects"
 	"github.com/noel-vega/hubble/registry"
+	"github.com/noel-vega/hubble/retention"
 )
 
+// shutdownTimeout is how long requests in flight get to finish on shutdown,
+// which leaves time to flush metrics within Docker's default stop timeout
+const shutdownTimeout = 5 * time.Second
+
 func main() {
+	// Background work stops, and metrics are flushed, on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Initialize users from environment
 	if err := auth.InitializeUsers(); err != nil {
 		log.Fatalf("Failed to initialize users: %v", err)
@@ -36,6 +58,9 @@ func main() {
 	}
 	defer dockerService.Close()
 
+	// Follow Docker events so changes can be pushed to clients
+	go dockerService.WatchEvents(ctx)
+
 	// Ensure Hubble infrastructure (networks, etc.) is set up
 	log.Println("Setting up Hubble infrastructure...")
 	if err := platform.EnsureInfrastructure(dockerService.Client()); err != nil {
@@ -49,21 +74,145 @@ func main() {
 		log.Printf("Registry endpoints will not be available")
 	}
 
+	// Registry garbage collection runs in the Hubble registry container
+	if registryClient != nil {
+		registryClient.SetContainer(platform.RegistryContainer{Client: dockerService.Client()})
+	}
+
 	// Initialize projects service
-	projectsService, err := projects.NewService(dockerService.Client())
+	projectsService, err := projects.NewService(dockerService.Client(), dockerService.Containers())
 	if err != nil {
 		log.Printf("Warning: Failed to initialize projects service: %v", err)
 		log.Printf("Projects endpoints will not be available")
 	}
 
+	// Built images are pushed to the Hubble registry
+	if projectsService != nil && registryClient != nil {
+		username, password := registryClient.Credentials()
+		projectsService.SetRegistry(projects.Registry{
+			Host:     registryClient.Host(),
+			Username: username,
+			Password: password,
+		})
+	}
+
+	// Initialize audit log for sensitive operations such as container exec
+	auditLog, err := audit.NewLog(os.Getenv("AUDIT_LOG_PATH"))
+	if err != nil {
+		log.Fatalf("Failed to initialize audit log: %v", err)
+	}
+	defer auditLog.Close()
+
+	// Initialize metrics history, stored alongside projects unless
+	// METRICS_PATH says otherwise
+	var metricsStore *metrics.Store
+	var collectorDone chan struct{}
+	metricsPath := os.Getenv("METRICS_PATH")
+	if metricsPath == "" && os.Getenv("PROJECTS_ROOT_PATH") != "" {
+		metricsPath = filepath.Join(os.Getenv("PROJECTS_ROOT_PATH"), ".hubble", "metrics")
+	}
+	if metricsPath != "" {
+		metricsConfig, err := metrics.LoadConfig()
+		if err == nil {
+			metricsStore, err = metrics.NewStore(metricsPath, metricsConfig)
+		}
+		if err != nil {
+			log.Printf("Warning: Failed to initialize metrics store: %v", err)
+			log.Printf("Metrics history endpoints will not be available")
+		} else {
+			collector := metrics.NewCollector(dockerService, metricsStore, metricsConfig.Interval)
+			collectorDone = make(chan struct{})
+			go func() {
+				defer close(collectorDone)
+				collector.Run(ctx)
+			}()
+			log.Printf("Collecting container metrics every %v", metricsConfig.Interval)
+		}
+	}
+
+	// Initialize alerting, with rules and history stored alongside projects
+	// unless ALERTS_PATH says otherwise
+	var alertEngine *alerts.Engine
+	alertsPath := os.Getenv("ALERTS_PATH")
+	if alertsPath == "" && os.Getenv("PROJECTS_ROOT_PATH") != "" {
+		alertsPath = filepath.Join(os.Getenv("PROJECTS_ROOT_PATH"), ".hubble", "alerts")
+	}
+	if alertsPath != "" {
+		alertEngine, err = alerts.NewEngine(alertsPath, dockerService, metricsStore)
+		if err != nil {
+			log.Printf("Warning: Failed to initialize alerting: %v", err)
+			log.Printf("Alerts endpoints will not be available")
+		} else {
+			alertEngine.OnChange(func(a alerts.Alert) {
+				log.Printf("Alert %s: %s (%s)", a.Status, a.Message, a.RuleName)
+			})
+			go alertEngine.Run(ctx)
+			log.Printf("Evaluating alert rules every %v", alertEngine.Interval())
+		}
+	}
+
+	// Initialize job manager for long-running operations
+	jobManager := jobs.NewManager()
+
+	// Initialize notifications, with channels and the delivery log stored
+	// alongside projects unless NOTIFICATIONS_PATH says otherwise
+	var notifier *notifications.Service
+	notificationsPath := os.Getenv("NOTIFICATIONS_PATH")
+	if notificationsPath == "" && os.Getenv("PROJECTS_ROOT_PATH") != "" {
+		notificationsPath = filepath.Join(os.Getenv("PROJECTS_ROOT_PATH"), ".hubble", "notifications")
+	}
+	if notificationsPath != "" {
+		notifier, err = notifications.NewService(notificationsPath)
+		if err != nil {
+			log.Printf("Warning: Failed to initialize notifications: %v", err)
+			log.Printf("Notifications endpoints will not be available")
+		} else {
+			defer notifier.Close()
+			go notifier.WatchContainers(ctx, dockerService)
+			jobManager.OnFinish(func(j jobs.Job) {
+				if n, ok := notifications.FromJob(j); ok {
+					notifier.Notify(n)
+				}
+			})
+			if alertEngine != nil {
+				alertEngine.OnChange(func(a alerts.Alert) {
+					notifier.Notify(notifications.FromAlert(a))
+				})
+			}
+		}
+	}
+
+	// Initialize registry retention, with policies stored alongside projects
+	// unless RETENTION_PATH says otherwise. Compose files are read to keep
+	// referenced tags, so it needs the projects service.
+	var retentionManager *retention.Manager
+	retentionPath := os.Getenv("RETENTION_PATH")
+	if retentionPath == "" && os.Getenv("PROJECTS_ROOT_PATH") != "" {
+		retentionPath = filepath.Join(os.Getenv("PROJECTS_ROOT_PATH"), ".hubble", "retention")
+	}
+	if retentionPath != "" && registryClient != nil && projectsService != nil {
+		retentionManager, err = retention.NewManager(retentionPath, registryClient, projectsService)
+		if err != nil {
+			log.Printf("Warning: Failed to initialize registry retention: %v", err)
+			log.Printf("Retention endpoints will not be available")
+		} else {
+			go retentionManager.Run(ctx)
+			log.Printf("Applying registry retention policies every %v", retentionManager.Interval())
+		}
+	}
+
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler()
+	jobsHandler := handlers.NewJobsHandler(jobManager)
 	containersHandler := handlers.NewContainersHandler(dockerService)
+	execHandler := handlers.NewExecHandler(dockerService, auditLog)
+	auditHandler := handlers.NewAuditHandler(auditLog)
+	eventsHandler := handlers.NewEventsHandler(dockerService)
 
 	// Initialize projects handler if projects service is available
 	var projectsHandler *handlers.ProjectsHandler
 	if projectsService != nil {
-		projectsHandler = handlers.NewProjectsHandler(projectsService)
+		projectsHandler = handlers.NewProjectsHandler(projectsService, jobManager, notifier)
 	}
 
 	// Initialize registry handler if registry client is available
@@ -74,16 +223,48 @@ func main() {
 	}
 	imagesHandler := handlers.NewImagesHandler(dockerService)
 
+	// Initialize retention handler if registry retention is available
+	var retentionHandler *handlers.RetentionHandler
+	if retentionManager != nil {
+		retentionHandler = handlers.NewRetentionHandler(retentionManager)
+	}
+	systemHandler := handlers.NewSystemHandler(dockerService)
+
+	// Initialize metrics handler if metrics history is available
+	var metricsHandler *handlers.MetricsHandler
+	if metricsStore != nil {
+		metricsHandler = handlers.NewMetricsHandler(metricsStore)
+	}
+
+	// Initialize alerts handler if alerting is available
+	var alertsHandler *handlers.AlertsHandler
+	if alertEngine != nil {
+		alertsHandler = handlers.NewAlertsHandler(alertEngine)
+	}
+
+	// Initialize notifications handler if notifications are available
+	var notificationsHandler *handlers.NotificationsHandler
+	if notifier != nil {
+		notificationsHandler = handlers.NewNotificationsHandler(notifier)
+	}
+
+	// Initialize Prometheus exporter for Hubble and its workloads
+	exporter := metrics.NewExporter(dockerService, metricsStore, auth.GetSessionCount)
+
 	// Setup router
 	r := chi.NewRouter()
 	r.Use(chimiddleware.Logger)
 	r.Use(chimiddleware.Recoverer)
+	r.Use(exporter.Middleware)
 
 	// Public routes
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("hubble"))
 	})
 
+	// Prometheus metrics (public unless METRICS_TOKEN is set)
+	r.Handle("/metrics", exporter.Handler(os.Getenv("METRICS_TOKEN")))
+
 	// Auth routes (public)
 	r.Route("/auth", func(r chi.Router) {
 		r.Post("/login", authHandler.Login)
@@ -101,20 +282,95 @@ func main() {
 		if registryHandler != nil {
 			r.Get("/registry/repositories", registryHandler.ListRepositories)
 			r.Get("/registry/repositories/{name}/tags", registryHandler.ListTags)
+			r.Get("/registry/repositories/{name}/tags/{tag}", registryHandler.GetTag)
+			r.Delete("/registry/repositories/{name}/tags/{tag}", registryHandler.DeleteTag)
+			r.Delete("/registry/repositories/{name}", registryHandler.DeleteRepository)
+			r.Post("/registry/gc", registryHandler.GarbageCollect)
 			r.Get("/registry/catalog", registryHandler.ListRepositoriesWithTags)
 		}
+
+		// Registry retention endpoints (if retention is available)
+		if retentionHandler != nil {
+			r.Get("/registry/retention/policies", retentionHandler.ListPolicies)
+			r.Post("/registry/retention/policies", retentionHandler.CreatePolicy)
+			r.Get("/registry/retention/policies/{id}", retentionHandler.GetPolicy)
+			r.Put("/registry/retention/policies/{id}", retentionHandler.UpdatePolicy)
+			r.Delete("/registry/retention/policies/{id}", retentionHandler.DeletePolicy)
+			r.Get("/registry/retention/report", retentionHandler.Preview)
+			r.Post("/registry/retention/run", retentionHandler.Apply)
+			r.Get("/registry/retention/runs/last", retentionHandler.LastRun)
+		}
 		r.Get("/containers", containersHandler.List)
 		r.Get("/containers/{id}", containersHandler.Get)
+		r.Get("/containers/{id}/logs", containersHandler.Logs)
+		r.Get("/containers/{id}/exec", execHandler.Exec)
+		r.Get("/containers/{id}/stats", containersHandler.Stats)
+		r.Get("/containers/{id}/archive", containersHandler.GetArchive)
+		r.Put("/containers/{id}/archive", containersHandler.PutArchive)
+		r.Get("/containers/{id}/files", containersHandler.ListFiles)
 		r.Post("/containers/{id}/stop", containersHandler.Stop)
 		r.Post("/containers/{id}/start", containersHandler.Start)
+		r.Post("/containers/{id}/restart", containersHandler.Restart)
+		r.Post("/containers/{id}/kill", containersHandler.Kill)
+		r.Post("/containers/{id}/pause", containersHandler.Pause)
+		r.Post("/containers/{id}/unpause", containersHandler.Unpause)
+		r.Post("/containers/{id}/rename", containersHandler.Rename)
+		r.Delete("/containers/{id}", containersHandler.Remove)
+		r.Get("/projects/{name}/services/{service}/logs", containersHandler.ServiceLogs)
+		r.Get("/projects/{name}/stats", containersHandler.ProjectStats)
 		r.Get("/images", imagesHandler.List)
+		r.Post("/images/pull", imagesHandler.Pull)
+		r.Post("/images/prune", imagesHandler.Prune)
+		r.Get("/images/{id}", imagesHandler.Get)
+		r.Delete("/images/{id}", imagesHandler.Remove)
+		r.Post("/images/{id}/tag", imagesHandler.Tag)
+		r.Get("/system/df", systemHandler.DiskUsage)
+		r.Post("/system/prune", systemHandler.Prune)
+		r.Get("/events", eventsHandler.Stream)
+		r.Get("/jobs", jobsHandler.List)
+		r.Get("/jobs/{id}", jobsHandler.Get)
+		r.Get("/jobs/{id}/stream", jobsHandler.Stream)
+		r.Post("/jobs/{id}/cancel", jobsHandler.Cancel)
+		r.Get("/audit", auditHandler.List)
+
+		// Metrics history endpoints (if the metrics store is available)
+		if metricsHandler != nil {
+			r.Get("/metrics/containers/{id}", metricsHandler.ContainerHistory)
+			r.Get("/metrics/projects/{name}", metricsHandler.ProjectHistory)
+		}
+
+		// Alerts endpoints (if alerting is available)
+		if alertsHandler != nil {
+			r.Get("/alerts", alertsHandler.List)
+			r.Get("/alerts/rules", alertsHandler.ListRules)
+			r.Post("/alerts/rules", alertsHandler.CreateRule)
+			r.Get("/alerts/rules/{id}", alertsHandler.GetRule)
+			r.Put("/alerts/rules/{id}", alertsHandler.UpdateRule)
+			r.Delete("/alerts/rules/{id}", alertsHandler.DeleteRule)
+		}
+
+		// Notifications endpoints (if notifications are available)
+		if notificationsHandler != nil {
+			r.Get("/notifications/channels", notificationsHandler.ListChannels)
+			r.Post("/notifications/channels", notificationsHandler.CreateChannel)
+			r.Get("/notifications/channels/{id}", notificationsHandler.GetChannel)
+			r.Put("/notifications/channels/{id}", notificationsHandler.UpdateChannel)
+			r.Delete("/notifications/channels/{id}", notificationsHandler.DeleteChannel)
+			r.Post("/notifications/channels/{id}/test", notificationsHandler.TestChannel)
+			r.Get("/notifications/deliveries", notificationsHandler.ListDeliveries)
+		}
 
 		// Projects endpoints (if projects service is configured)
 		if projectsHandler != nil {
 			r.Post("/projects", projectsHandler.Create)
 			r.Get("/projects", projectsHandler.List)
 			r.Get("/projects/{name}", projectsHandler.Get)
+			r.Delete("/projects/{name}", projectsHandler.Delete)
 			r.Get("/projects/{name}/compose", projectsHandler.GetCompose)
+			r.Post("/projects/{name}/up", projectsHandler.Up)
+			r.Post("/projects/{name}/down", projectsHandler.Down)
+			r.Post("/projects/{name}/restart", projectsHandler.Restart)
+			r.Post("/projects/{name}/pull", projectsHandler.Pull)
 			r.Get("/projects/{name}/containers", projectsHandler.GetContainers)
 			r.Get("/projects/{name}/volumes", projectsHandler.GetVolumes)
 			r.Get("/projects/{name}/environment", projectsHandler.GetEnvironment)
@@ -128,12 +384,42 @@ func main() {
 			r.Delete("/projects/{name}/services/{service}", projectsHandler.DeleteService)
 			r.Post("/projects/{name}/services/{service}/start", projectsHandler.StartService)
 			r.Post("/projects/{name}/services/{service}/stop", projectsHandler.StopService)
+			r.Post("/projects/{name}/services/{service}/build", projectsHandler.BuildService)
+			r.Get("/projects/{name}/builds", projectsHandler.ListBuilds)
+			r.Get("/projects/{name}/revisions", projectsHandler.ListRevisions)
+			r.Get("/projects/{name}/revisions/{id}", projectsHandler.GetRevision)
+			r.Get("/projects/{name}/revisions/{id}/diff", projectsHandler.DiffRevision)
+			r.Post("/projects/{name}/revisions/{id}/restore", projectsHandler.RestoreRevision)
+			r.Get("/archives", projectsHandler.ListArchived)
+			r.Post("/archives/{id}/restore", projectsHandler.RestoreArchived)
+			r.Delete("/archives/{id}", projectsHandler.DeleteArchived)
 		}
 	})
 
-	// Start server
+	// Start server. Requests share ctx, so streams end on shutdown instead
+	// of holding it up.
+	server := &http.Server{
+		Addr:        ":5000",
+		Handler:     r,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		log.Println("Shutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Failed to shut down server: %v", err)
+		}
+	}()
+
 	log.Println("Starting server on :5000")
-	if err := http.ListenAndServe(":5000", r); err != nil {
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("Server failed: %v", err)
 	}
+
+	// Wait for the collector to flush partially filled rollup buckets
+	if collectorDone != nil {
+		<-collectorDone
+	}
 }
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/noel-vega/hubble/docker"
+)
+
+const (
+	// maxConcurrentSamples bounds how many containers are sampled at once;
+	// each sample takes Docker about a second
+	maxConcurrentSamples = 8
+	// sampleTimeout bounds a single sample, so a container Docker is slow
+	// to report on is skipped without holding up the others
+	sampleTimeout = 10 * time.Second
+)
+
+// Collector samples the resource usage of every running container on an
+// interval and stores it
+type Collector struct {
+	dockerService *docker.Service
+	store         *Store
+	interval      time.Duration
+}
+
+func NewCollector(dockerService *docker.Service, store *Store, interval time.Duration) *Collector {
+	return &Collector{
+		dockerService: dockerService,
+		store:         store,
+		interval:      interval,
+	}
+}
+
+// Run samples until ctx is done, then flushes partially filled buckets
+func (c *Collector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.collect(ctx)
+
+		select {
+		case <-ctx.Done():
+			if err := c.store.Flush(); err != nil {
+				log.Printf("Failed to flush metrics: %v", err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// collect samples every running container once. With many containers a
+// cycle may take longer than the interval, and the next one then starts as
+// soon as it ends.
+func (c *Collector) collect(ctx context.Context) {
+	listCtx, cancel := context.WithTimeout(ctx, c.interval)
+	defer cancel()
+
+	containers, err := c.dockerService.ListContainers(listCtx)
+	if err != nil {
+		log.Printf("Failed to list containers for metrics: %v", err)
+		return
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var points []Point
+	var skipped []string
+	slots := make(chan struct{}, maxConcurrentSamples)
+	for _, ctr := range containers {
+		if ctr.State != "running" {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			sampleCtx, cancel := context.WithTimeout(ctx, sampleTimeout)
+			defer cancel()
+			stats, err := c.dockerService.GetContainerStats(sampleCtx, ctr.ID)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				// The container may also have stopped since it was listed
+				skipped = append(skipped, fmt.Sprintf("%s (%v)", ctr.Name, err))
+				return
+			}
+			points = append(points, Point{
+				Time:        stats.Time,
+				Container:   ctr.ID,
+				Name:        ctr.Name,
+				Project:     ctr.Labels["com.docker.compose.project"],
+				Service:     ctr.Labels["com.docker.compose.service"],
+				CPUPercent:  stats.CPUPercent,
+				MemoryUsage: stats.MemoryUsage,
+				MemoryLimit: stats.MemoryLimit,
+				NetworkRx:   stats.NetworkRx,
+				NetworkTx:   stats.NetworkTx,
+				BlockRead:   stats.BlockRead,
+				BlockWrite:  stats.BlockWrite,
+				PIDs:        stats.PIDs,
+			})
+		}()
+	}
+	wg.Wait()
+
+	if len(skipped) > 0 && ctx.Err() == nil {
+		log.Printf("Skipped metrics for %d containers: %s", len(skipped), strings.Join(skipped, ", "))
+	}
+
+	if err := c.store.Append(points); err != nil {
+		log.Printf("Failed to store metrics: %v", err)
+	}
+	if err := c.store.Prune(); err != nil {
+		log.Printf("Failed to prune metrics: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config controls how often containers are sampled and how long each
+// resolution is kept
+type Config struct {
+	Interval            time.Duration
+	RawRetention        time.Duration
+	FiveMinuteRetention time.Duration
+	HourRetention       time.Duration
+}
+
+// DefaultConfig samples every 15 seconds and keeps raw samples for a day,
+// 5 minute averages for a week and hourly averages for 90 days
+func DefaultConfig() Config {
+	return Config{
+		Interval:            15 * time.Second,
+		RawRetention:        24 * time.Hour,
+		FiveMinuteRetention: 7 * 24 * time.Hour,
+		HourRetention:       90 * 24 * time.Hour,
+	}
+}
+
+// LoadConfig reads METRICS_INTERVAL, METRICS_RAW_RETENTION,
+// METRICS_5M_RETENTION and METRICS_1H_RETENTION, falling back to
+// DefaultConfig for those that are unset. Values are Go durations such as
+// "30s" or "48h", or a number of days such as "30d".
+func LoadConfig() (Config, error) {
+	cfg := DefaultConfig()
+
+	for name, target := range map[string]*time.Duration{
+		"METRICS_INTERVAL":      &cfg.Interval,
+		"METRICS_RAW_RETENTION": &cfg.RawRetention,
+		"METRICS_5M_RETENTION":  &cfg.FiveMinuteRetention,
+		"METRICS_1H_RETENTION":  &cfg.HourRetention,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		d, err := parseDuration(value)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("invalid %s: %s", name, value)
+		}
+		*target = d
+	}
+
+	if cfg.Interval > 5*time.Minute {
+		return cfg, fmt.Errorf("invalid METRICS_INTERVAL: must be at most 5m")
+	}
+
+	return cfg, nil
+}
+
+// parseDuration is time.ParseDuration with an added "d" unit for days
+func parseDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,247 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/noel-vega/hubble/docker"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// scrapeTimeout bounds the Docker calls made while collecting a scrape
+const scrapeTimeout = 10 * time.Second
+
+// Exporter exposes Hubble's own metrics and those of the containers it
+// manages in the Prometheus text format
+type Exporter struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewExporter registers the HTTP, session and workload metrics. CPU and
+// memory gauges come from the latest samples in store, which may be nil.
+func NewExporter(dockerService *docker.Service, store *Store, sessionCount func() int) *Exporter {
+	e := &Exporter{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "hubble_http_requests_total",
+			Help: "HTTP requests handled, by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "hubble_http_request_duration_seconds",
+			Help:    "Time taken to handle HTTP requests, by method and route pattern.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+	}
+
+	e.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		e.requests,
+		e.duration,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "hubble_auth_sessions_active",
+			Help: "Active login sessions.",
+		}, func() float64 { return float64(sessionCount()) }),
+		&workloadCollector{dockerService: dockerService, store: store},
+	)
+
+	return e
+}
+
+// Middleware records a request count and duration for every request,
+// labelled with the matched chi route pattern so IDs in URLs do not create
+// new series
+func (e *Exporter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		hijack := &hijackRecorder{ResponseWriter: w}
+		ww := chimiddleware.NewWrapResponseWriter(hijack, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if hijack.hijacked && strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade") {
+			// WebSocket upgrades write their 101 on the hijacked connection
+			status = http.StatusSwitchingProtocols
+		} else if status == 0 {
+			// Nothing written means an implicit 200
+			status = http.StatusOK
+		}
+
+		e.requests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		e.duration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// hijackRecorder notes whether a handler took over the connection, as a
+// WebSocket upgrade does
+type hijackRecorder struct {
+	http.ResponseWriter
+	hijacked bool
+}
+
+func (h *hijackRecorder) Flush() {
+	if f, ok := h.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := h.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := hj.Hijack()
+	h.hijacked = err == nil
+	return conn, rw, err
+}
+
+// Handler serves the metrics. With a non-empty token, requests must send it
+// as a bearer token.
+func (e *Exporter) Handler(token string) http.Handler {
+	metrics := promhttp.HandlerFor(e.registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return metrics
+	}
+
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "Unauthorized - Invalid metrics token", http.StatusUnauthorized)
+			return
+		}
+		metrics.ServeHTTP(w, r)
+	})
+}
+
+var (
+	containerLabels = []string{"container", "project", "service"}
+
+	containerStateDesc = prometheus.NewDesc("hubble_container_state",
+		"Container state; 1 for the container's current state.",
+		append(containerLabels, "state"), nil)
+	containerRestartsDesc = prometheus.NewDesc("hubble_container_restarts_total",
+		"Times the container has been restarted by Docker.",
+		containerLabels, nil)
+	containerCPUDesc = prometheus.NewDesc("hubble_container_cpu_percent",
+		"Container CPU usage from the latest sample; one fully used core is 100.",
+		containerLabels, nil)
+	containerMemoryDesc = prometheus.NewDesc("hubble_container_memory_usage_bytes",
+		"Container memory usage excluding page cache from the latest sample.",
+		containerLabels, nil)
+	containerMemoryLimitDesc = prometheus.NewDesc("hubble_container_memory_limit_bytes",
+		"Container memory limit, or the host's memory if it has none.",
+		containerLabels, nil)
+
+	projectContainersDesc = prometheus.NewDesc("hubble_project_containers",
+		"Containers of a compose project, by state.",
+		[]string{"project", "state"}, nil)
+	projectCPUDesc = prometheus.NewDesc("hubble_project_cpu_percent",
+		"Summed CPU usage of a project's containers from the latest samples.",
+		[]string{"project"}, nil)
+	projectMemoryDesc = prometheus.NewDesc("hubble_project_memory_usage_bytes",
+		"Summed memory usage of a project's containers from the latest samples.",
+		[]string{"project"}, nil)
+)
+
+// workloadCollector reports container and project metrics read from Docker
+// at scrape time
+type workloadCollector struct {
+	dockerService *docker.Service
+	store         *Store
+}
+
+func (c *workloadCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		containerStateDesc, containerRestartsDesc, containerCPUDesc, containerMemoryDesc,
+		containerMemoryLimitDesc, projectContainersDesc, projectCPUDesc, projectMemoryDesc,
+	} {
+		ch <- desc
+	}
+}
+
+func (c *workloadCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+
+	containers, err := c.dockerService.ListContainers(ctx)
+	if err != nil {
+		log.Printf("Failed to list containers for metrics: %v", err)
+		return
+	}
+
+	projectStates := make(map[string]map[string]int)
+	for _, ctr := range containers {
+		project := ctr.Labels["com.docker.compose.project"]
+		labels := []string{ctr.Name, project, ctr.Labels["com.docker.compose.service"]}
+
+		ch <- prometheus.MustNewConstMetric(containerStateDesc, prometheus.GaugeValue, 1, append(labels, ctr.State)...)
+
+		if restarts, err := c.dockerService.GetRestartCount(ctx, ctr.ID); err == nil {
+			ch <- prometheus.MustNewConstMetric(containerRestartsDesc, prometheus.CounterValue, float64(restarts), labels...)
+		}
+
+		if project != "" {
+			if projectStates[project] == nil {
+				projectStates[project] = make(map[string]int)
+			}
+			projectStates[project][ctr.State]++
+		}
+	}
+
+	for project, states := range projectStates {
+		for state, count := range states {
+			ch <- prometheus.MustNewConstMetric(projectContainersDesc, prometheus.GaugeValue, float64(count), project, state)
+		}
+	}
+
+	if c.store == nil {
+		return
+	}
+
+	// A recreated container can briefly have samples under both its old and
+	// new ID; only the newest is reported so series stay unique
+	latest := make(map[string]Point)
+	for _, p := range c.store.Latest() {
+		if existing, ok := latest[p.Name]; !ok || p.Time.After(existing.Time) {
+			latest[p.Name] = p
+		}
+	}
+
+	projectCPU := make(map[string]float64)
+	projectMemory := make(map[string]float64)
+	for _, p := range latest {
+		labels := []string{p.Name, p.Project, p.Service}
+		ch <- prometheus.MustNewConstMetric(containerCPUDesc, prometheus.GaugeValue, p.CPUPercent, labels...)
+		ch <- prometheus.MustNewConstMetric(containerMemoryDesc, prometheus.GaugeValue, float64(p.MemoryUsage), labels...)
+		ch <- prometheus.MustNewConstMetric(containerMemoryLimitDesc, prometheus.GaugeValue, float64(p.MemoryLimit), labels...)
+
+		if p.Project != "" {
+			projectCPU[p.Project] += p.CPUPercent
+			projectMemory[p.Project] += float64(p.MemoryUsage)
+		}
+	}
+
+	for project, cpu := range projectCPU {
+		ch <- prometheus.MustNewConstMetric(projectCPUDesc, prometheus.GaugeValue, cpu, project)
+		ch <- prometheus.MustNewConstMetric(projectMemoryDesc, prometheus.GaugeValue, projectMemory[project], project)
+	}
+}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestExporter_RecordsRoutePatterns(t *testing.T) {
+	e := NewExporter(nil, nil, func() int { return 0 })
+
+	r := chi.NewRouter()
+	r.Use(e.Middleware)
+	r.Get("/containers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Post("/containers/{id}/start", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, id := range []string{"abc123", "def456"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/containers/"+id, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nothing", nil))
+
+	if got := testutil.ToFloat64(e.requests.WithLabelValues("GET", "/containers/{id}", "404")); got != 2 {
+		t.Errorf("requests for /containers/{id} = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(e.requests.WithLabelValues("GET", "unmatched", "404")); got != 1 {
+		t.Errorf("unmatched requests = %v, want 1", got)
+	}
+
+	// A handler that writes nothing responds 200
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/containers/abc123/start", nil))
+	if got := testutil.ToFloat64(e.requests.WithLabelValues("POST", "/containers/{id}/start", "200")); got != 1 {
+		t.Errorf("requests for /containers/{id}/start = %v, want 1", got)
+	}
+}
+
+// hijackableRecorder is a ResponseRecorder whose connection can be taken
+// over, as a WebSocket upgrade does
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (h hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	server, client := net.Pipe()
+	client.Close()
+	return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+}
+
+func TestExporter_RecordsUpgrades(t *testing.T) {
+	e := NewExporter(nil, nil, func() int { return 0 })
+
+	r := chi.NewRouter()
+	r.Use(e.Middleware)
+	r.Get("/containers/{id}/exec", func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Hijack failed: %v", err)
+			return
+		}
+		conn.Close()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/containers/abc123/exec", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	r.ServeHTTP(hijackableRecorder{httptest.NewRecorder()}, req)
+
+	if got := testutil.ToFloat64(e.requests.WithLabelValues("GET", "/containers/{id}/exec", "101")); got != 1 {
+		t.Errorf("upgraded requests = %v, want 1", got)
+	}
+}
+
+func TestExporter_RequiresToken(t *testing.T) {
+	e := NewExporter(nil, nil, func() int { return 0 })
+	handler := e.Handler("secret")
+
+	for _, header := range []string{"", "Bearer wrong", "secret"} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status = %d, want 401", header, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,496 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxPoints is how many points a query returns when no step is given
+const maxPoints = 300
+
+// Point is a container's resource usage over one sample or, once
+// downsampled, averaged over a bucket. Network and block I/O are counters
+// since the container started; a downsampled point holds their last value.
+type Point struct {
+	Time        time.Time `json:"time"`
+	Container   string    `json:"container,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	Project     string    `json:"project,omitempty"`
+	Service     string    `json:"service,omitempty"`
+	CPUPercent  float64   `json:"cpu_percent"`
+	CPUMax      float64   `json:"cpu_max"`
+	MemoryUsage uint64    `json:"memory_usage"`
+	MemoryMax   uint64    `json:"memory_max"`
+	MemoryLimit uint64    `json:"memory_limit,omitempty"`
+	NetworkRx   uint64    `json:"network_rx_bytes"`
+	NetworkTx   uint64    `json:"network_tx_bytes"`
+	BlockRead   uint64    `json:"block_read_bytes"`
+	BlockWrite  uint64    `json:"block_write_bytes"`
+	PIDs        uint64    `json:"pids"`
+	// Samples is the number of raw samples the point was built from
+	Samples int `json:"samples"`
+	// Containers is set on project rollups to the number of containers summed
+	Containers int `json:"containers,omitempty"`
+}
+
+// History is the result of a range query
+type History struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	Step string    `json:"step"`
+	// Resolution names the tier the points were read from
+	Resolution string  `json:"resolution"`
+	Points     []Point `json:"points"`
+}
+
+// tier is one level of the store. Raw samples are kept at the collection
+// interval and rolled up into coarser tiers that are kept for longer. Each
+// tier is split into segment files so expired data is removed a file at a
+// time.
+type tier struct {
+	name       string
+	resolution time.Duration
+	segment    time.Duration
+	retention  time.Duration
+}
+
+// Store is an on-disk time series store for container metrics. Points are
+// appended as JSON lines to <dir>/<tier>/<segment start>.jsonl.
+type Store struct {
+	dir   string
+	tiers []tier
+
+	mu sync.RWMutex
+	// open holds the buckets still being filled for each downsampled tier,
+	// keyed by container
+	open []map[string]*accumulator
+	// latest holds each container's most recent raw sample
+	latest map[string]Point
+
+	now func() time.Time
+}
+
+func NewStore(dir string, cfg Config) (*Store, error) {
+	tiers := []tier{
+		{name: "raw", resolution: cfg.Interval, segment: time.Hour, retention: cfg.RawRetention},
+		{name: "5m", resolution: 5 * time.Minute, segment: 24 * time.Hour, retention: cfg.FiveMinuteRetention},
+		{name: "1h", resolution: time.Hour, segment: 7 * 24 * time.Hour, retention: cfg.HourRetention},
+	}
+
+	for _, t := range tiers {
+		if err := os.MkdirAll(filepath.Join(dir, t.name), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create metrics directory: %w", err)
+		}
+	}
+
+	open := make([]map[string]*accumulator, len(tiers))
+	for i := range open {
+		open[i] = make(map[string]*accumulator)
+	}
+
+	return &Store{
+		dir:    dir,
+		tiers:  tiers,
+		open:   open,
+		latest: make(map[string]Point),
+		now:    time.Now,
+	}, nil
+}
+
+// Append stores raw samples and rolls them up into the downsampled tiers.
+// Buckets are written once they are complete.
+func (s *Store) Append(points []Point) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw := make([]Point, 0, len(points))
+	for _, p := range points {
+		p.Time = p.Time.UTC()
+		p.Samples = 1
+		p.CPUMax = p.CPUPercent
+		p.MemoryMax = p.MemoryUsage
+		raw = append(raw, p)
+	}
+	if err := s.write(s.tiers[0], raw); err != nil {
+		return err
+	}
+
+	now := s.now()
+	for _, p := range raw {
+		s.latest[p.Container] = p
+	}
+	for key, p := range s.latest {
+		if p.Time.Before(now.Add(-latestMaxAge(s.tiers[0]))) {
+			delete(s.latest, key)
+		}
+	}
+
+	for i := 1; i < len(s.tiers); i++ {
+		t := s.tiers[i]
+		var finished []Point
+
+		for _, p := range raw {
+			start := p.Time.Truncate(t.resolution)
+			acc := s.open[i][p.Container]
+			if acc != nil && !acc.start.Equal(start) {
+				finished = append(finished, acc.point())
+				acc = nil
+			}
+			if acc == nil {
+				acc = &accumulator{start: start}
+				s.open[i][p.Container] = acc
+			}
+			acc.add(p)
+		}
+
+		// Close buckets whose time is up, including those of containers that
+		// are no longer sampled
+		for key, acc := range s.open[i] {
+			if !acc.start.Add(t.resolution).After(now) {
+				finished = append(finished, acc.point())
+				delete(s.open[i], key)
+			}
+		}
+
+		if err := s.write(t, finished); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Flush writes the buckets still being filled, for use on shutdown
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 1; i < len(s.tiers); i++ {
+		finished := make([]Point, 0, len(s.open[i]))
+		for key, acc := range s.open[i] {
+			finished = append(finished, acc.point())
+			delete(s.open[i], key)
+		}
+		if err := s.write(s.tiers[i], finished); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Prune removes segment files that are entirely past their tier's retention
+func (s *Store) Prune() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for _, t := range s.tiers {
+		dir := filepath.Join(s.dir, t.name)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return fmt.Errorf("failed to read metrics directory: %w", err)
+		}
+
+		for _, entry := range entries {
+			start, ok := segmentStart(entry.Name())
+			if !ok {
+				continue
+			}
+			if start.Add(t.segment).Before(now.Add(-t.retention)) {
+				if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+					return fmt.Errorf("failed to remove expired metrics: %w", err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Latest returns the most recent sample of every container sampled within
+// the last few collection intervals
+func (s *Store) Latest() []Point {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cutoff := s.now().Add(-latestMaxAge(s.tiers[0]))
+	points := make([]Point, 0, len(s.latest))
+	for _, p := range s.latest {
+		if !p.Time.Before(cutoff) {
+			points = append(points, p)
+		}
+	}
+
+	return points
+}
+
+// latestMaxAge is how old a container's last sample may be before it is
+// no longer considered current
+func latestMaxAge(raw tier) time.Duration {
+	return 3 * raw.resolution
+}
+
+// ContainerHistory returns a container's usage between from and to in
+// buckets of step. The container is matched by ID or by name, so a name
+// follows a service across container recreations. A zero step picks one
+// giving at most maxPoints points.
+func (s *Store) ContainerHistory(container string, from, to time.Time, step time.Duration) (*History, error) {
+	id := container[:min(len(container), 12)]
+	match := func(p Point) bool {
+		return p.Container == id || p.Name == container
+	}
+
+	return s.query(from, to, step, match, func(buckets map[time.Time]map[string]*accumulator, start time.Time) Point {
+		// All matching containers are merged into a single series
+		merged := &accumulator{start: start}
+		for _, acc := range buckets[start] {
+			merged.merge(acc)
+		}
+		return merged.point()
+	})
+}
+
+// ProjectHistory returns the summed usage of a project's containers
+// between from and to in buckets of step
+func (s *Store) ProjectHistory(project string, from, to time.Time, step time.Duration) (*History, error) {
+	match := func(p Point) bool {
+		return p.Project == project
+	}
+
+	return s.query(from, to, step, match, func(buckets map[time.Time]map[string]*accumulator, start time.Time) Point {
+		sum := Point{Time: start, Project: project}
+		for _, acc := range buckets[start] {
+			p := acc.point()
+			sum.CPUPercent += p.CPUPercent
+			sum.CPUMax += p.CPUMax
+			sum.MemoryUsage += p.MemoryUsage
+			sum.MemoryMax += p.MemoryMax
+			sum.NetworkRx += p.NetworkRx
+			sum.NetworkTx += p.NetworkTx
+			sum.BlockRead += p.BlockRead
+			sum.BlockWrite += p.BlockWrite
+			sum.PIDs += p.PIDs
+			sum.Samples += p.Samples
+			sum.Containers++
+		}
+		return sum
+	})
+}
+
+// query reads the points matching match from the finest tier that still
+// covers from, groups them per step bucket and container, and builds one
+// point per bucket with combine
+func (s *Store) query(from, to time.Time, step time.Duration, match func(Point) bool, combine func(map[time.Time]map[string]*accumulator, time.Time) Point) (*History, error) {
+	from, to = from.UTC(), to.UTC()
+	if !from.Before(to) {
+		return nil, fmt.Errorf("invalid time range: from must be before to")
+	}
+	if step < 0 {
+		return nil, fmt.Errorf("invalid step: %s", step)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	index := len(s.tiers) - 1
+	for i, t := range s.tiers {
+		if !from.Before(now.Add(-t.retention)) {
+			index = i
+			break
+		}
+	}
+	t := s.tiers[index]
+
+	if step == 0 {
+		step = (to.Sub(from) + maxPoints - 1) / maxPoints
+		step = ((step + time.Second - 1) / time.Second) * time.Second
+	}
+	step = max(step, t.resolution)
+
+	points, err := s.read(index, from, to, match)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make(map[time.Time]map[string]*accumulator)
+	for _, p := range points {
+		start := p.Time.Truncate(step)
+		if buckets[start] == nil {
+			buckets[start] = make(map[string]*accumulator)
+		}
+		acc := buckets[start][p.Container]
+		if acc == nil {
+			acc = &accumulator{start: start}
+			buckets[start][p.Container] = acc
+		}
+		acc.add(p)
+	}
+
+	starts := make([]time.Time, 0, len(buckets))
+	for start := range buckets {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		return starts[i].Before(starts[j])
+	})
+
+	history := &History{
+		From:       from,
+		To:         to,
+		Step:       step.String(),
+		Resolution: t.name,
+		Points:     make([]Point, 0, len(starts)),
+	}
+	for _, start := range starts {
+		history.Points = append(history.Points, combine(buckets, start))
+	}
+
+	return history, nil
+}
+
+// read returns the points of a tier between from and to, including buckets
+// that are still being filled; callers must hold the read lock
+func (s *Store) read(index int, from, to time.Time, match func(Point) bool) ([]Point, error) {
+	t := s.tiers[index]
+	var points []Point
+
+	for start := from.Truncate(t.segment); !start.After(to); start = start.Add(t.segment) {
+		file, err := os.Open(s.segmentPath(t, start))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open metrics segment: %w", err)
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var p Point
+			// A line being written concurrently may be incomplete
+			if err := json.Unmarshal(scanner.Bytes(), &p); err != nil {
+				continue
+			}
+			if !p.Time.Before(from) && !p.Time.After(to) && match(p) {
+				points = append(points, p)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read metrics segment: %w", err)
+		}
+	}
+
+	if index > 0 {
+		for _, acc := range s.open[index] {
+			if p := acc.point(); !p.Time.Before(from) && !p.Time.After(to) && match(p) {
+				points = append(points, p)
+			}
+		}
+	}
+
+	return points, nil
+}
+
+// write appends points to their tier's segment files; callers must hold
+// the lock
+func (s *Store) write(t tier, points []Point) error {
+	segments := make(map[time.Time][]byte)
+	for _, p := range points {
+		data, err := json.Marshal(p)
+		if err != nil {
+			return fmt.Errorf("failed to encode metrics: %w", err)
+		}
+		start := p.Time.Truncate(t.segment)
+		segments[start] = append(append(segments[start], data...), '\n')
+	}
+
+	for start, data := range segments {
+		file, err := os.OpenFile(s.segmentPath(t, start), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open metrics segment: %w", err)
+		}
+		_, err = file.Write(data)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write metrics: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) segmentPath(t tier, start time.Time) string {
+	return filepath.Join(s.dir, t.name, fmt.Sprintf("%d.jsonl", start.Unix()))
+}
+
+func segmentStart(name string) (time.Time, bool) {
+	unix, err := strconv.ParseInt(strings.TrimSuffix(name, ".jsonl"), 10, 64)
+	if err != nil || !strings.HasSuffix(name, ".jsonl") {
+		return time.Time{}, false
+	}
+	return time.Unix(unix, 0).UTC(), true
+}
+
+// accumulator combines points into one: gauges are averaged weighted by
+// sample count, maxima are kept, and counters and labels come from the
+// latest point
+type accumulator struct {
+	start   time.Time
+	latest  Point
+	cpuSum  float64
+	memSum  float64
+	pidsSum float64
+	cpuMax  float64
+	memMax  uint64
+	samples int
+}
+
+func (a *accumulator) add(p Point) {
+	weight := max(p.Samples, 1)
+	a.cpuSum += p.CPUPercent * float64(weight)
+	a.memSum += float64(p.MemoryUsage) * float64(weight)
+	a.pidsSum += float64(p.PIDs) * float64(weight)
+	a.cpuMax = max(a.cpuMax, p.CPUMax, p.CPUPercent)
+	a.memMax = max(a.memMax, p.MemoryMax, p.MemoryUsage)
+	a.samples += weight
+	if !p.Time.Before(a.latest.Time) {
+		a.latest = p
+	}
+}
+
+func (a *accumulator) merge(other *accumulator) {
+	a.cpuSum += other.cpuSum
+	a.memSum += other.memSum
+	a.pidsSum += other.pidsSum
+	a.cpuMax = max(a.cpuMax, other.cpuMax)
+	a.memMax = max(a.memMax, other.memMax)
+	a.samples += other.samples
+	if !other.latest.Time.Before(a.latest.Time) {
+		a.latest = other.latest
+	}
+}
+
+func (a *accumulator) point() Point {
+	p := a.latest
+	p.Time = a.start
+	p.Samples = a.samples
+	p.CPUMax = a.cpuMax
+	p.MemoryMax = a.memMax
+	if a.samples > 0 {
+		p.CPUPercent = a.cpuSum / float64(a.samples)
+		p.MemoryUsage = uint64(a.memSum / float64(a.samples))
+		p.PIDs = uint64(a.pidsSum/float64(a.samples) + 0.5)
+	}
+	return p
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_DownsamplesAndQueries(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir, Config{
+		Interval:            15 * time.Second,
+		RawRetention:        time.Hour,
+		FiveMinuteRetention: 24 * time.Hour,
+		HourRetention:       7 * 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+
+	start := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	now := start
+	store.now = func() time.Time { return now }
+
+	// Twelve minutes of samples for two containers; web alternates between
+	// 10% and 30% CPU
+	for i := range 48 {
+		now = start.Add(time.Duration(i) * 15 * time.Second)
+		cpu := 10.0
+		if i%2 == 1 {
+			cpu = 30
+		}
+		err := store.Append([]Point{
+			{Time: now, Container: "aaa", Name: "site-web-1", Project: "site", Service: "web", CPUPercent: cpu, MemoryUsage: 100, NetworkRx: uint64(i * 10)},
+			{Time: now, Container: "bbb", Name: "site-db-1", Project: "site", Service: "db", CPUPercent: 5, MemoryUsage: 200},
+			{Time: now, Container: "ccc", Name: "other-app-1", Project: "other", CPUPercent: 50, MemoryUsage: 999},
+		})
+		if err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	history, err := store.ContainerHistory("aaa", start, now, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("ContainerHistory failed: %v", err)
+	}
+	if history.Resolution != "raw" || len(history.Points) != 3 {
+		t.Fatalf("got %d points at %s resolution, want 3 raw", len(history.Points), history.Resolution)
+	}
+	first := history.Points[0]
+	if first.CPUPercent != 20 || first.CPUMax != 30 || first.Samples != 20 || first.NetworkRx != 190 {
+		t.Errorf("unexpected first bucket: %+v", first)
+	}
+
+	byName, err := store.ContainerHistory("site-web-1", start, now, 5*time.Minute)
+	if err != nil || len(byName.Points) != 3 || byName.Points[0].CPUPercent != 20 {
+		t.Errorf("ContainerHistory by name = %+v, %v", byName, err)
+	}
+
+	project, err := store.ProjectHistory("site", start, now, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("ProjectHistory failed: %v", err)
+	}
+	if p := project.Points[0]; p.MemoryUsage != 300 || p.CPUPercent != 25 || p.Containers != 2 {
+		t.Errorf("unexpected project bucket: %+v", p)
+	}
+
+	// Three hours later the raw samples are past retention; the range is
+	// answered from the 5 minute tier, including the bucket still open
+	now = start.Add(3 * time.Hour)
+	history, err = store.ContainerHistory("aaa", start, start.Add(15*time.Minute), 0)
+	if err != nil {
+		t.Fatalf("ContainerHistory failed: %v", err)
+	}
+	if history.Resolution != "5m" || history.Step != "5m0s" || len(history.Points) != 3 {
+		t.Fatalf("got %d points at %s resolution with step %s, want 3 at 5m", len(history.Points), history.Resolution, history.Step)
+	}
+	if p := history.Points[0]; p.CPUPercent != 20 || p.Samples != 20 {
+		t.Errorf("unexpected downsampled bucket: %+v", p)
+	}
+
+	if err := store.Prune(); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "raw")); len(entries) != 0 {
+		t.Errorf("expired raw segments were not pruned: %v", entries)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "5m")); len(entries) != 1 {
+		t.Errorf("5m segments = %v, want 1", entries)
+	}
+
+	if _, err := store.ContainerHistory("aaa", now, start, 0); err == nil {
+		t.Errorf("expected an error for an inverted range")
+	}
+}
@@ -0,0 +1,200 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// EventType is what a notification is about
+type EventType string
+
+const (
+	EventDeploySucceeded  EventType = "deploy.succeeded"
+	EventDeployFailed     EventType = "deploy.failed"
+	EventContainerCrashed EventType = "container.crashed"
+	EventProjectCreated   EventType = "project.created"
+	EventProjectDeleted   EventType = "project.deleted"
+	EventAlertFiring      EventType = "alert.firing"
+	EventAlertResolved    EventType = "alert.resolved"
+	// EventTest is sent by Test and cannot be subscribed to
+	EventTest EventType = "test"
+)
+
+var eventTypes = map[EventType]bool{
+	EventDeploySucceeded: true, EventDeployFailed: true, EventContainerCrashed: true,
+	EventProjectCreated: true, EventProjectDeleted: true,
+	EventAlertFiring: true, EventAlertResolved: true,
+}
+
+// ChannelType is how a channel delivers notifications
+type ChannelType string
+
+const (
+	// ChannelWebhook posts the notification as JSON, signed with the
+	// channel's secret when it has one
+	ChannelWebhook ChannelType = "webhook"
+	// ChannelSlack posts to a Slack-compatible incoming webhook
+	ChannelSlack ChannelType = "slack"
+	// ChannelDiscord posts to a Discord incoming webhook
+	ChannelDiscord ChannelType = "discord"
+	// ChannelEmail sends an email over SMTP
+	ChannelEmail ChannelType = "email"
+)
+
+// Notification is a message sent to every channel subscribed to its event
+// and project
+type Notification struct {
+	ID      string         `json:"id"`
+	Event   EventType      `json:"event"`
+	Project string         `json:"project,omitempty"`
+	Title   string         `json:"title"`
+	Message string         `json:"message"`
+	Time    time.Time      `json:"time"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+// Subscription selects notifications for a channel. An empty project
+// matches every project and empty events match every event type.
+type Subscription struct {
+	Project string      `json:"project,omitempty"`
+	Events  []EventType `json:"events,omitempty"`
+}
+
+func (s Subscription) match(n Notification) bool {
+	return (s.Project == "" || s.Project == n.Project) &&
+		(len(s.Events) == 0 || slices.Contains(s.Events, n.Event))
+}
+
+// SMTPConfig is where and to whom an email channel sends
+type SMTPConfig struct {
+	Host string `json:"host"`
+	// Port defaults to 587; 465 uses implicit TLS, other ports upgrade
+	// with STARTTLS when the server offers it
+	Port     int      `json:"port,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// Channel is a configured destination for notifications
+type Channel struct {
+	ID   string      `json:"id"`
+	Name string      `json:"name"`
+	Type ChannelType `json:"type"`
+	// URL is the webhook to post to for webhook, slack and discord channels
+	URL string `json:"url,omitempty"`
+	// Secret signs webhook payloads
+	Secret        string         `json:"secret,omitempty"`
+	SMTP          *SMTPConfig    `json:"smtp,omitempty"`
+	Subscriptions []Subscription `json:"subscriptions"`
+	Disabled      bool           `json:"disabled"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// Validate checks the channel's settings for its type
+func (c Channel) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("invalid channel: name is required")
+	}
+
+	switch c.Type {
+	case ChannelWebhook, ChannelSlack, ChannelDiscord:
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid channel: url must be an http or https URL")
+		}
+	case ChannelEmail:
+		if c.SMTP == nil || c.SMTP.Host == "" {
+			return fmt.Errorf("invalid channel: smtp host is required")
+		}
+		if c.SMTP.From == "" || len(c.SMTP.To) == 0 {
+			return fmt.Errorf("invalid channel: smtp from and to are required")
+		}
+	default:
+		return fmt.Errorf("invalid channel: unknown type %q", c.Type)
+	}
+
+	if len(c.Subscriptions) == 0 {
+		return fmt.Errorf("invalid channel: at least one subscription is required")
+	}
+	for _, sub := range c.Subscriptions {
+		for _, event := range sub.Events {
+			if !eventTypes[event] {
+				return fmt.Errorf("invalid channel: unknown event type %q", event)
+			}
+		}
+	}
+
+	return nil
+}
+
+// subscribed reports whether the channel wants a notification
+func (c Channel) subscribed(n Notification) bool {
+	if c.Disabled {
+		return false
+	}
+	return slices.ContainsFunc(c.Subscriptions, func(s Subscription) bool { return s.match(n) })
+}
+
+// redacted returns the channel without its webhook secret and SMTP
+// password, for API responses
+func (c Channel) redacted() Channel {
+	c.Secret = ""
+	if c.SMTP != nil {
+		smtp := *c.SMTP
+		smtp.Password = ""
+		c.SMTP = &smtp
+	}
+	return c
+}
+
+// loadChannels reads the channels file, which may not exist yet
+func loadChannels(path string) ([]Channel, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []Channel{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notification channels: %w", err)
+	}
+
+	var channels []Channel
+	if err := json.Unmarshal(data, &channels); err != nil {
+		return nil, fmt.Errorf("failed to parse notification channels: %w", err)
+	}
+	return channels, nil
+}
+
+// saveChannels replaces the channels file. It holds secrets, so it is only
+// readable by its owner.
+func saveChannels(path string, channels []Channel) error {
+	data, err := json.MarshalIndent(channels, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode notification channels: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".channels-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to save notification channels: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save notification channels: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save notification channels: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save notification channels: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sendTimeout bounds a single delivery attempt
+const sendTimeout = 15 * time.Second
+
+// statusError is a non-2xx response from a webhook
+type statusError struct {
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	if e.body == "" {
+		return fmt.Sprintf("unexpected status %d", e.code)
+	}
+	return fmt.Sprintf("unexpected status %d: %s", e.code, e.body)
+}
+
+// permanent reports whether retrying cannot help: the receiver rejected
+// the request itself rather than being unavailable
+func (e *statusError) permanent() bool {
+	return e.code >= 400 && e.code < 500 && e.code != http.StatusRequestTimeout && e.code != http.StatusTooManyRequests
+}
+
+// send delivers a notification through a channel once
+func send(ctx context.Context, client *http.Client, c Channel, n Notification) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	switch c.Type {
+	case ChannelWebhook:
+		return sendWebhook(ctx, client, c, n)
+	case ChannelSlack:
+		return postJSON(ctx, client, c.URL, map[string]string{"text": chatText(n, "*")}, nil)
+	case ChannelDiscord:
+		return postJSON(ctx, client, c.URL, map[string]string{"content": chatText(n, "**")}, nil)
+	case ChannelEmail:
+		return sendEmail(ctx, *c.SMTP, n)
+	default:
+		return fmt.Errorf("unknown channel type: %s", c.Type)
+	}
+}
+
+// sendWebhook posts the notification as JSON. With a secret, the
+// X-Hubble-Signature header carries "sha256=" and the hex HMAC-SHA256 of
+// the X-Hubble-Timestamp value, a dot and the body, so receivers can check
+// the payload and reject replays.
+func sendWebhook(ctx context.Context, client *http.Client, c Channel, n Notification) error {
+	headers := map[string]string{
+		"X-Hubble-Event":        string(n.Event),
+		"X-Hubble-Notification": n.ID,
+	}
+
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	if c.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		headers["X-Hubble-Timestamp"] = timestamp
+		headers["X-Hubble-Signature"] = "sha256=" + Sign(c.Secret, timestamp, body)
+	}
+
+	return post(ctx, client, c.URL, body, headers)
+}
+
+// Sign returns the hex HMAC-SHA256 signature of a webhook body
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, payload any, headers map[string]string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	return post(ctx, client, url, body, headers)
+}
+
+func post(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Hubble-Notifications")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &statusError{code: resp.StatusCode, body: strings.TrimSpace(string(snippet))}
+	}
+	return nil
+}
+
+// chatText formats a notification for chat webhooks, emphasizing the
+// title with the service's bold marker
+func chatText(n Notification, bold string) string {
+	return bold + n.Title + bold + "\n" + n.Message
+}
+
+// sendEmail sends a plain text email. Port 465 uses implicit TLS; other
+// ports upgrade with STARTTLS when the server offers it, which is required
+// before authenticating with a username.
+func sendEmail(ctx context.Context, cfg SMTPConfig, n Notification) error {
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("failed to start TLS: %w", err)
+			}
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(cfg.From); err != nil {
+		return fmt.Errorf("SMTP server rejected sender: %w", err)
+	}
+	for _, to := range cfg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP server rejected recipient %s: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if _, err := w.Write(emailMessage(cfg, n)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return client.Quit()
+}
+
+func emailMessage(cfg SMTPConfig, n Notification) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&b, "Subject: [Hubble] %s\r\n", strings.NewReplacer("\r", "", "\n", " ").Replace(n.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", n.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+
+	b.WriteString(n.Message + "\r\n\r\n")
+	fmt.Fprintf(&b, "Event: %s\r\n", n.Event)
+	if n.Project != "" {
+		fmt.Fprintf(&b, "Project: %s\r\n", n.Project)
+	}
+	fmt.Fprintf(&b, "Time: %s\r\n", n.Time.Format(time.RFC3339))
+
+	keys := make([]string, 0, len(n.Data))
+	for key := range n.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s: %v\r\n", key, n.Data[key])
+	}
+
+	return []byte(b.String())
+}
@@ -0,0 +1,381 @@
+package notifications
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxAttempts is how many times a delivery is tried before it fails
+const maxAttempts = 5
+
+// maxDeliveries is how many deliveries are kept in memory for listing
+const maxDeliveries = 1000
+
+// DeliveryStatus is the outcome of a delivery
+type DeliveryStatus string
+
+const (
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Delivery records sending one notification through one channel
+type Delivery struct {
+	ID             string         `json:"id"`
+	ChannelID      string         `json:"channel_id"`
+	ChannelName    string         `json:"channel_name"`
+	ChannelType    ChannelType    `json:"channel_type"`
+	NotificationID string         `json:"notification_id"`
+	Event          EventType      `json:"event"`
+	Project        string         `json:"project,omitempty"`
+	Title          string         `json:"title"`
+	Status         DeliveryStatus `json:"status"`
+	Attempts       int            `json:"attempts"`
+	Error          string         `json:"error,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	FinishedAt     time.Time      `json:"finished_at"`
+}
+
+// DeliveryFilter selects deliveries; empty fields match everything
+type DeliveryFilter struct {
+	ChannelID string
+	Status    DeliveryStatus
+	Project   string
+}
+
+func (f DeliveryFilter) match(d Delivery) bool {
+	return (f.ChannelID == "" || f.ChannelID == d.ChannelID) &&
+		(f.Status == "" || f.Status == d.Status) &&
+		(f.Project == "" || f.Project == d.Project)
+}
+
+// Service sends notifications to the channels subscribed to them. Channels
+// are stored in a JSON file and finished deliveries are appended to a JSON
+// lines log.
+type Service struct {
+	channelsPath string
+	client       *http.Client
+	// backoff is the wait before the second attempt; it doubles after each
+	// failed attempt
+	backoff time.Duration
+
+	mu         sync.Mutex
+	channels   []Channel
+	log        *os.File
+	deliveries []Delivery // oldest first
+	wg         sync.WaitGroup
+}
+
+// NewService loads the channels and delivery log kept in dir
+func NewService(dir string) (*Service, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create notifications directory: %w", err)
+	}
+
+	channelsPath := filepath.Join(dir, "channels.json")
+	channels, err := loadChannels(channelsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Service{
+		channelsPath: channelsPath,
+		client:       &http.Client{},
+		backoff:      2 * time.Second,
+		channels:     channels,
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, "deliveries.jsonl"), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open delivery log: %w", err)
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var delivery Delivery
+		if err := json.Unmarshal(scanner.Bytes(), &delivery); err != nil {
+			continue
+		}
+		s.appendDelivery(delivery)
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read delivery log: %w", err)
+	}
+
+	s.log = file
+	return s, nil
+}
+
+// Notify sends a notification to every subscribed channel in the
+// background. It is safe to call on a nil Service, which drops it.
+func (s *Service) Notify(n Notification) {
+	if s == nil {
+		return
+	}
+
+	if n.ID == "" {
+		n.ID = newID()
+	}
+	if n.Time.IsZero() {
+		n.Time = time.Now().UTC()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.channels {
+		if !c.subscribed(n) {
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.deliver(context.Background(), c, n, maxAttempts)
+		}()
+	}
+}
+
+// Test sends a test notification through a channel once and returns the
+// delivery, whose error says why it failed
+func (s *Service) Test(ctx context.Context, id string) (Delivery, error) {
+	c, err := s.getChannel(id)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	return s.deliver(ctx, c, Notification{
+		ID:      newID(),
+		Event:   EventTest,
+		Title:   "Test notification",
+		Message: fmt.Sprintf("This is a test notification for the %q channel.", c.Name),
+		Time:    time.Now().UTC(),
+	}, 1), nil
+}
+
+// deliver sends a notification through a channel, retrying failures with
+// exponential backoff, and records the outcome
+func (s *Service) deliver(ctx context.Context, c Channel, n Notification, attempts int) Delivery {
+	delivery := Delivery{
+		ID:             newID(),
+		ChannelID:      c.ID,
+		ChannelName:    c.Name,
+		ChannelType:    c.Type,
+		NotificationID: n.ID,
+		Event:          n.Event,
+		Project:        n.Project,
+		Title:          n.Title,
+		CreatedAt:      time.Now().UTC(),
+	}
+
+	wait := s.backoff
+	for attempt := 1; attempt <= attempts; attempt++ {
+		delivery.Attempts = attempt
+		err := send(ctx, s.client, c, n)
+		if err == nil {
+			delivery.Status = DeliveryDelivered
+			delivery.Error = ""
+			break
+		}
+
+		delivery.Status = DeliveryFailed
+		delivery.Error = err.Error()
+
+		var statusErr *statusError
+		if errors.As(err, &statusErr) && statusErr.permanent() {
+			break
+		}
+		if attempt < attempts {
+			time.Sleep(wait)
+			wait *= 2
+		}
+	}
+	delivery.FinishedAt = time.Now().UTC()
+
+	if delivery.Status == DeliveryFailed {
+		log.Printf("Failed to deliver %s notification to channel %s after %d attempts: %s", n.Event, c.Name, delivery.Attempts, delivery.Error)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.appendDelivery(delivery)
+	if data, err := json.Marshal(delivery); err == nil {
+		if _, err := s.log.Write(append(data, '\n')); err != nil {
+			log.Printf("Failed to write delivery log: %v", err)
+		}
+	}
+
+	return delivery
+}
+
+// appendDelivery keeps a delivery in memory, dropping the oldest beyond
+// maxDeliveries; callers must hold the lock or own the service
+func (s *Service) appendDelivery(d Delivery) {
+	s.deliveries = append(s.deliveries, d)
+	if len(s.deliveries) > maxDeliveries {
+		s.deliveries = s.deliveries[len(s.deliveries)-maxDeliveries:]
+	}
+}
+
+// Deliveries returns matching deliveries, newest first. A limit of 0
+// returns all that are kept.
+func (s *Service) Deliveries(filter DeliveryFilter, limit int) []Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]Delivery, 0)
+	for i := len(s.deliveries) - 1; i >= 0; i-- {
+		if !filter.match(s.deliveries[i]) {
+			continue
+		}
+		result = append(result, s.deliveries[i])
+		if limit > 0 && len(result) == limit {
+			break
+		}
+	}
+	return result
+}
+
+// ListChannels returns every channel without secrets
+func (s *Service) ListChannels() []Channel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]Channel, 0, len(s.channels))
+	for _, c := range s.channels {
+		result = append(result, c.redacted())
+	}
+	return result
+}
+
+// GetChannel returns a channel without secrets
+func (s *Service) GetChannel(id string) (Channel, error) {
+	c, err := s.getChannel(id)
+	if err != nil {
+		return Channel{}, err
+	}
+	return c.redacted(), nil
+}
+
+func (s *Service) getChannel(id string) (Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.channels {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return Channel{}, fmt.Errorf("channel not found: %s", id)
+}
+
+// CreateChannel validates and saves a new channel
+func (s *Service) CreateChannel(c Channel) (Channel, error) {
+	if err := c.Validate(); err != nil {
+		return Channel{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	c.ID = newID()
+	c.CreatedAt = now
+	c.UpdatedAt = now
+
+	channels := append(append([]Channel{}, s.channels...), c)
+	if err := saveChannels(s.channelsPath, channels); err != nil {
+		return Channel{}, err
+	}
+	s.channels = channels
+
+	return c.redacted(), nil
+}
+
+// UpdateChannel replaces a channel's settings. An empty webhook secret or
+// SMTP password keeps the current one, since they are never returned.
+func (s *Service) UpdateChannel(id string, c Channel) (Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.channels {
+		if existing.ID != id {
+			continue
+		}
+
+		if c.Secret == "" {
+			c.Secret = existing.Secret
+		}
+		if c.SMTP != nil && c.SMTP.Password == "" && existing.SMTP != nil {
+			c.SMTP.Password = existing.SMTP.Password
+		}
+		if err := c.Validate(); err != nil {
+			return Channel{}, err
+		}
+
+		c.ID = id
+		c.CreatedAt = existing.CreatedAt
+		c.UpdatedAt = time.Now().UTC()
+
+		channels := append([]Channel{}, s.channels...)
+		channels[i] = c
+		if err := saveChannels(s.channelsPath, channels); err != nil {
+			return Channel{}, err
+		}
+		s.channels = channels
+
+		return c.redacted(), nil
+	}
+
+	return Channel{}, fmt.Errorf("channel not found: %s", id)
+}
+
+// DeleteChannel removes a channel. Deliveries already under way finish.
+func (s *Service) DeleteChannel(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, c := range s.channels {
+		if c.ID != id {
+			continue
+		}
+
+		channels := append(append([]Channel{}, s.channels[:i]...), s.channels[i+1:]...)
+		if err := saveChannels(s.channelsPath, channels); err != nil {
+			return err
+		}
+		s.channels = channels
+
+		return nil
+	}
+
+	return fmt.Errorf("channel not found: %s", id)
+}
+
+// Close waits for deliveries under way and closes the delivery log
+func (s *Service) Close() error {
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.Close()
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
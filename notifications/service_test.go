@@ -0,0 +1,314 @@
+package notifications
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/noel-vega/hubble/docker"
+)
+
+func newTestService(t *testing.T, dir string) *Service {
+	t.Helper()
+
+	s, err := NewService(dir)
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	s.backoff = time.Millisecond
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// recorder is an HTTP server that keeps the requests it receives and
+// answers with the queued status codes, then 200
+type recorder struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	statuses []int
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.requests = append(rec.requests, r)
+	rec.bodies = append(rec.bodies, body)
+	if len(rec.statuses) > 0 {
+		w.WriteHeader(rec.statuses[0])
+		rec.statuses = rec.statuses[1:]
+	}
+}
+
+func TestService_DeliversToSubscribedChannels(t *testing.T) {
+	webhook, slack := &recorder{}, &recorder{}
+	webhookServer, slackServer := httptest.NewServer(webhook), httptest.NewServer(slack)
+	defer webhookServer.Close()
+	defer slackServer.Close()
+
+	dir := t.TempDir()
+	s := newTestService(t, dir)
+
+	hook, err := s.CreateChannel(Channel{
+		Name:   "CI",
+		Type:   ChannelWebhook,
+		URL:    webhookServer.URL,
+		Secret: "s3cret",
+		Subscriptions: []Subscription{
+			{Project: "site", Events: []EventType{EventDeploySucceeded, EventDeployFailed}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateChannel failed: %v", err)
+	}
+	if hook.Secret != "" {
+		t.Error("expected the secret to be left out of the response")
+	}
+	if _, err := s.CreateChannel(Channel{
+		Name:          "Team",
+		Type:          ChannelSlack,
+		URL:           slackServer.URL,
+		Subscriptions: []Subscription{{}},
+	}); err != nil {
+		t.Fatalf("CreateChannel failed: %v", err)
+	}
+
+	s.Notify(Notification{Event: EventDeploySucceeded, Project: "site", Title: "Deploy succeeded: site", Message: "site was deployed by admin."})
+	s.Notify(Notification{Event: EventDeploySucceeded, Project: "other", Title: "Deploy succeeded: other", Message: "other was deployed by admin."})
+	s.wg.Wait()
+
+	if len(webhook.requests) != 1 {
+		t.Fatalf("expected 1 webhook request, got %d", len(webhook.requests))
+	}
+	req, body := webhook.requests[0], webhook.bodies[0]
+	if req.Header.Get("X-Hubble-Event") != "deploy.succeeded" {
+		t.Errorf("X-Hubble-Event = %q", req.Header.Get("X-Hubble-Event"))
+	}
+	want := "sha256=" + Sign("s3cret", req.Header.Get("X-Hubble-Timestamp"), body)
+	if req.Header.Get("X-Hubble-Signature") != want {
+		t.Errorf("X-Hubble-Signature = %q, want %q", req.Header.Get("X-Hubble-Signature"), want)
+	}
+	var n Notification
+	if err := json.Unmarshal(body, &n); err != nil || n.Project != "site" || n.ID == "" {
+		t.Errorf("unexpected webhook body %s: %v", body, err)
+	}
+
+	if len(slack.bodies) != 2 {
+		t.Fatalf("expected 2 slack requests, got %d", len(slack.bodies))
+	}
+	var payload map[string]string
+	json.Unmarshal(slack.bodies[0], &payload)
+	if !strings.HasPrefix(payload["text"], "*Deploy succeeded: ") {
+		t.Errorf("unexpected slack payload %s", slack.bodies[0])
+	}
+
+	// Deliveries and channels survive a restart
+	s.Close()
+	reopened := newTestService(t, dir)
+	if deliveries := reopened.Deliveries(DeliveryFilter{ChannelID: hook.ID}, 0); len(deliveries) != 1 || deliveries[0].Status != DeliveryDelivered {
+		t.Errorf("deliveries = %+v", deliveries)
+	}
+	if len(reopened.ListChannels()) != 2 {
+		t.Errorf("expected 2 channels after reopening")
+	}
+
+	// Updating without a secret keeps the existing one
+	update := Channel{Name: "CI", Type: ChannelWebhook, URL: webhookServer.URL, Subscriptions: []Subscription{{}}}
+	if _, err := reopened.UpdateChannel(hook.ID, update); err != nil {
+		t.Fatalf("UpdateChannel failed: %v", err)
+	}
+	if c, _ := reopened.getChannel(hook.ID); c.Secret != "s3cret" {
+		t.Errorf("Secret = %q after update", c.Secret)
+	}
+}
+
+func TestService_Retries(t *testing.T) {
+	flaky := &recorder{statuses: []int{http.StatusServiceUnavailable, http.StatusBadGateway}}
+	rejecting := &recorder{statuses: []int{http.StatusNotFound}}
+	flakyServer, rejectingServer := httptest.NewServer(flaky), httptest.NewServer(rejecting)
+	defer flakyServer.Close()
+	defer rejectingServer.Close()
+
+	s := newTestService(t, t.TempDir())
+	for _, url := range []string{flakyServer.URL, rejectingServer.URL} {
+		if _, err := s.CreateChannel(Channel{Name: url, Type: ChannelDiscord, URL: url, Subscriptions: []Subscription{{}}}); err != nil {
+			t.Fatalf("CreateChannel failed: %v", err)
+		}
+	}
+
+	s.Notify(Notification{Event: EventContainerCrashed, Title: "Container crashed: web", Message: "web exited with code 1."})
+	s.wg.Wait()
+
+	deliveries := make(map[string]Delivery)
+	for _, d := range s.Deliveries(DeliveryFilter{}, 0) {
+		deliveries[d.ChannelName] = d
+	}
+
+	if d := deliveries[flakyServer.URL]; d.Status != DeliveryDelivered || d.Attempts != 3 {
+		t.Errorf("expected delivery on the third attempt, got %+v", d)
+	}
+	// A 404 will not go away by retrying
+	if d := deliveries[rejectingServer.URL]; d.Status != DeliveryFailed || d.Attempts != 1 || !strings.Contains(d.Error, "404") {
+		t.Errorf("expected one failed attempt, got %+v", d)
+	}
+}
+
+// fakeSMTP accepts one message and sends it on the returned channel
+func fakeSMTP(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+		reply("220 localhost ESMTP")
+
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					messages <- data.String()
+					reply("250 OK")
+				} else {
+					data.WriteString(line)
+				}
+				continue
+			}
+
+			switch command := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL"), strings.HasPrefix(command, "RCPT"):
+				reply("250 OK")
+			case command == "DATA":
+				inData = true
+				reply("354 End data with <CR><LF>.<CR><LF>")
+			case command == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Not implemented")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), messages
+}
+
+func TestService_Email(t *testing.T) {
+	addr, messages := fakeSMTP(t)
+	host, port, _ := net.SplitHostPort(addr)
+	portNumber, _ := strconv.Atoi(port)
+
+	s := newTestService(t, t.TempDir())
+	c, err := s.CreateChannel(Channel{
+		Name:          "On-call",
+		Type:          ChannelEmail,
+		SMTP:          &SMTPConfig{Host: host, Port: portNumber, From: "hubble@example.com", To: []string{"ops@example.com"}},
+		Subscriptions: []Subscription{{Events: []EventType{EventAlertFiring}}},
+	})
+	if err != nil {
+		t.Fatalf("CreateChannel failed: %v", err)
+	}
+
+	delivery, err := s.Test(t.Context(), c.ID)
+	if err != nil || delivery.Status != DeliveryDelivered {
+		t.Fatalf("Test = %+v, %v", delivery, err)
+	}
+
+	select {
+	case message := <-messages:
+		if !strings.Contains(message, "Subject: [Hubble] Test notification\r\n") || !strings.Contains(message, "To: ops@example.com\r\n") {
+			t.Errorf("unexpected message:\n%s", message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the email")
+	}
+}
+
+func TestChannel_Validate(t *testing.T) {
+	tests := []struct {
+		channel Channel
+		valid   bool
+	}{
+		{Channel{Name: "a", Type: ChannelSlack, URL: "https://hooks.slack.com/x", Subscriptions: []Subscription{{}}}, true},
+		{Channel{Name: "a", Type: ChannelSlack, URL: "ftp://example.com", Subscriptions: []Subscription{{}}}, false},
+		{Channel{Name: "a", Type: ChannelWebhook, URL: "https://example.com"}, false},
+		{Channel{Name: "a", Type: ChannelWebhook, URL: "https://example.com", Subscriptions: []Subscription{{Events: []EventType{"deploy.started"}}}}, false},
+		{Channel{Name: "a", Type: ChannelEmail, SMTP: &SMTPConfig{Host: "smtp.example.com"}, Subscriptions: []Subscription{{}}}, false},
+		{Channel{Name: "a", Type: "sms", Subscriptions: []Subscription{{}}}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.channel.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %v", tt.channel, err, tt.valid)
+		}
+	}
+}
+
+func TestFromContainerEvent_SkipsRequestedStops(t *testing.T) {
+	container := func(id, action, exitCode string) docker.Event {
+		event := docker.Event{Type: "container", Action: action, ID: id, Name: "site-" + id}
+		if exitCode != "" {
+			event.Attributes = map[string]string{"exitCode": exitCode}
+		}
+		return event
+	}
+
+	tests := []struct {
+		event docker.Event
+		want  bool
+	}{
+		{container("web", "die", "1"), true},
+		{container("web", "die", "0"), false},
+		// docker stop, compose down and restarts kill before the die
+		{container("web", "kill", ""), false},
+		{container("web", "die", "143"), false},
+		// Once started again, a crash is reported
+		{container("web", "start", ""), false},
+		{container("web", "die", "137"), true},
+		// A kill of one container does not hide a crash of another
+		{container("api", "kill", ""), false},
+		{container("worker", "die", "2"), true},
+		{container("api", "die", "137"), false},
+	}
+
+	stops := docker.NewStopTracker()
+	for i, tt := range tests {
+		n, ok := fromContainerEvent(stops, tt.event)
+		if ok != tt.want {
+			t.Errorf("event %d (%s %s): reported %v, want %v", i, tt.event.ID, tt.event.Action, ok, tt.want)
+		}
+		if ok && n.Event != EventContainerCrashed {
+			t.Errorf("event %d: unexpected notification %+v", i, n)
+		}
+	}
+}
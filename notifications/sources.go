@@ -0,0 +1,156 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/noel-vega/hubble/alerts"
+	"github.com/noel-vega/hubble/docker"
+	"github.com/noel-vega/hubble/jobs"
+)
+
+// FromAlert describes an alert firing or resolving
+func FromAlert(a alerts.Alert) Notification {
+	n := Notification{
+		Event:   EventAlertFiring,
+		Project: a.Project,
+		Title:   "Alert firing: " + a.RuleName,
+		Message: a.Message,
+		Time:    a.StartedAt,
+		Data: map[string]any{
+			"alert_id":  a.ID,
+			"rule_id":   a.RuleID,
+			"type":      a.Type,
+			"container": a.Container,
+			"value":     a.Value,
+		},
+	}
+	if a.Status == alerts.StatusResolved {
+		n.Event = EventAlertResolved
+		n.Title = "Alert resolved: " + a.RuleName
+		n.Message = "Resolved: " + a.Message
+		if a.ResolvedAt != nil {
+			n.Time = *a.ResolvedAt
+		}
+	}
+	return n
+}
+
+// FromJob describes the result of a deploy, which is a project "up" job.
+// Other jobs and cancelled deploys are not notified.
+func FromJob(j jobs.Job) (Notification, bool) {
+	if j.Type != "up" {
+		return Notification{}, false
+	}
+
+	n := Notification{
+		Project: j.Project,
+		Data: map[string]any{
+			"job_id": j.ID,
+			"user":   j.User,
+		},
+	}
+	if j.FinishedAt != nil {
+		n.Time = *j.FinishedAt
+	}
+
+	switch j.State {
+	case jobs.StateSucceeded:
+		n.Event = EventDeploySucceeded
+		n.Title = "Deploy succeeded: " + j.Project
+		n.Message = fmt.Sprintf("%s was deployed by %s.", j.Project, j.User)
+	case jobs.StateFailed:
+		n.Event = EventDeployFailed
+		n.Title = "Deploy failed: " + j.Project
+		n.Message = fmt.Sprintf("Deploying %s failed: %s", j.Project, j.Error)
+	default:
+		return Notification{}, false
+	}
+	return n, true
+}
+
+// ProjectCreated describes a new project
+func ProjectCreated(project, user string) Notification {
+	return Notification{
+		Event:   EventProjectCreated,
+		Project: project,
+		Title:   "Project created: " + project,
+		Message: fmt.Sprintf("%s created the project %s.", user, project),
+		Data:    map[string]any{"user": user},
+	}
+}
+
+// ProjectDeleted describes a deleted or archived project
+func ProjectDeleted(project, user string, archived bool) Notification {
+	action := "deleted"
+	if archived {
+		action = "archived"
+	}
+	return Notification{
+		Event:   EventProjectDeleted,
+		Project: project,
+		Title:   "Project " + action + ": " + project,
+		Message: fmt.Sprintf("%s %s the project %s.", user, action, project),
+		Data:    map[string]any{"user": user, "archived": archived},
+	}
+}
+
+// containerEvents are the events WatchContainers needs to tell crashes
+// apart from requested stops
+var containerEvents = docker.EventFilter{
+	Types: []string{"container.die", "container.kill", "container.start", "container.destroy"},
+}
+
+// WatchContainers notifies about containers that exit with a non-zero code
+// until ctx is done. Containers that were stopped, killed or restarted on
+// request are not reported, even though they exit with 137 or 143.
+func (s *Service) WatchContainers(ctx context.Context, dockerService *docker.Service) {
+	events, unsubscribe := dockerService.SubscribeEvents(containerEvents)
+	defer func() { unsubscribe() }()
+
+	stops := docker.NewStopTracker()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				// Fell behind; keep going with a fresh subscription
+				events, unsubscribe = dockerService.SubscribeEvents(containerEvents)
+				continue
+			}
+			if n, ok := fromContainerEvent(stops, event); ok {
+				s.Notify(n)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// fromContainerEvent records event in stops and describes it if it is a
+// crash: a die with a non-zero code that no kill or stop asked for
+func fromContainerEvent(stops *docker.StopTracker, event docker.Event) (Notification, bool) {
+	stops.Observe(event)
+	if event.Action != "die" || stops.Requested(event.ID) {
+		return Notification{}, false
+	}
+
+	exitCode := event.Attributes["exitCode"]
+	if exitCode == "" || exitCode == "0" {
+		return Notification{}, false
+	}
+
+	return Notification{
+		Event:   EventContainerCrashed,
+		Project: event.Project,
+		Title:   "Container crashed: " + event.Name,
+		Message: fmt.Sprintf("%s exited with code %s.", event.Name, exitCode),
+		Time:    event.Time,
+		Data: map[string]any{
+			"container":    event.Name,
+			"container_id": event.ID,
+			"service":      event.Service,
+			"exit_code":    exitCode,
+		},
+	}, true
+}
@@ -1,13 +1,17 @@
 package platform
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 )
 
 const (
@@ -55,3 +59,112 @@ func EnsureRegistry(dockerClient *client.Client) error {
 	log.Printf("✓ Registry started (ID: %s)", registryID[:12])
 	return nil
 }
+
+// RegistryContainer runs commands in the Registry container and switches
+// it in and out of read-only mode, for registry garbage collection
+type RegistryContainer struct {
+	Client *client.Client
+}
+
+// Exec runs cmd in the Registry container and returns its combined output.
+// A non-zero exit code is returned as an error.
+func (r RegistryContainer) Exec(ctx context.Context, cmd []string) (string, error) {
+	exec, err := r.Client.ContainerExecCreate(ctx, RegistryContainerName, container.ExecOptions{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	resp, err := r.Client.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to attach to exec: %w", err)
+	}
+	defer resp.Close()
+
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, resp.Reader); err != nil {
+		return "", fmt.Errorf("failed to read exec output: %w", err)
+	}
+
+	inspect, err := r.Client.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect exec: %w", err)
+	}
+	if inspect.ExitCode != 0 {
+		return "", fmt.Errorf("%s exited with code %d: %s", cmd[0], inspect.ExitCode, strings.TrimSpace(output.String()))
+	}
+
+	return output.String(), nil
+}
+
+// readOnlyEnv puts the registry's storage into maintenance read-only mode,
+// in which pushes are refused and pulls keep working
+const readOnlyEnv = "REGISTRY_STORAGE_MAINTENANCE_READONLY"
+
+// SetReadOnly recreates the Registry container with its storage in
+// read-only mode, or back in normal mode. Environment variables can only
+// be set when a container is created, so the container is replaced with a
+// copy that differs only in readOnlyEnv; the old one is kept until the new
+// one has started, and put back if it does not.
+func (r RegistryContainer) SetReadOnly(ctx context.Context, readOnly bool) error {
+	inspect, err := r.Client.ContainerInspect(ctx, RegistryContainerName)
+	if err != nil {
+		return fmt.Errorf("failed to inspect Registry: %w", err)
+	}
+
+	config := *inspect.Config
+	config.Env = nil
+	for _, env := range inspect.Config.Env {
+		if !strings.HasPrefix(env, readOnlyEnv+"=") {
+			config.Env = append(config.Env, env)
+		}
+	}
+	if readOnly {
+		config.Env = append(config.Env, readOnlyEnv+"={enabled: true}")
+	}
+
+	endpoints := make(map[string]*network.EndpointSettings)
+	for name, endpoint := range inspect.NetworkSettings.Networks {
+		endpoints[name] = &network.EndpointSettings{
+			IPAMConfig: endpoint.IPAMConfig,
+			Links:      endpoint.Links,
+			Aliases:    endpoint.Aliases,
+			DriverOpts: endpoint.DriverOpts,
+		}
+	}
+
+	previous := RegistryContainerName + "-previous"
+	if err := r.Client.ContainerStop(ctx, inspect.ID, container.StopOptions{}); err != nil {
+		return fmt.Errorf("failed to stop Registry: %w", err)
+	}
+	if err := r.Client.ContainerRename(ctx, inspect.ID, previous); err != nil {
+		r.Client.ContainerStart(ctx, inspect.ID, container.StartOptions{})
+		return fmt.Errorf("failed to rename Registry: %w", err)
+	}
+
+	// restore puts the old container back after a failed replacement
+	restore := func() {
+		r.Client.ContainerRename(ctx, inspect.ID, RegistryContainerName)
+		r.Client.ContainerStart(ctx, inspect.ID, container.StartOptions{})
+	}
+
+	created, err := r.Client.ContainerCreate(ctx, &config, inspect.HostConfig,
+		&network.NetworkingConfig{EndpointsConfig: endpoints}, nil, RegistryContainerName)
+	if err != nil {
+		restore()
+		return fmt.Errorf("failed to create Registry: %w", err)
+	}
+	if err := r.Client.ContainerStart(ctx, created.ID, container.StartOptions{}); err != nil {
+		r.Client.ContainerRemove(ctx, created.ID, container.RemoveOptions{Force: true})
+		restore()
+		return fmt.Errorf("failed to start Registry: %w", err)
+	}
+
+	if err := r.Client.ContainerRemove(ctx, inspect.ID, container.RemoveOptions{}); err != nil {
+		log.Printf("Warning: failed to remove previous Registry container: %v", err)
+	}
+	return nil
+}
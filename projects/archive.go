@@ -0,0 +1,309 @@
+package projects
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// DeleteOptions controls what DeleteProject removes besides the containers
+type DeleteOptions struct {
+	// RemoveVolumes removes the named volumes compose created for the project
+	RemoveVolumes bool
+	// RemoveNetworks removes every network labelled for the project, even
+	// ones compose down leaves behind. External networks such as hubble are
+	// never touched.
+	RemoveNetworks bool
+	// Archive moves the project directory and its revision history to the
+	// archive instead of deleting them
+	Archive bool
+}
+
+// ArchivedProject is a deleted project kept in the archive
+type ArchivedProject struct {
+	ID         string    `json:"id"`
+	Project    string    `json:"project"`
+	ArchivedAt time.Time `json:"archived_at"`
+	ArchivedBy string    `json:"archived_by"`
+}
+
+// DeleteProject takes a project down and then deletes or archives its
+// directory, streaming compose output to output. Returns the archive entry
+// when archived.
+func (s *Service) DeleteProject(ctx context.Context, projectName string, opts DeleteOptions, output io.Writer) (*ArchivedProject, error) {
+	if projectName == "" {
+		return nil, fmt.Errorf("project name cannot be empty")
+	}
+
+	unlock := s.lockProject(projectName)
+	defer unlock()
+
+	// Hidden directories (such as Hubble's own .hubble) are never projects
+	if strings.HasPrefix(projectName, ".") || strings.ContainsAny(projectName, `/\`) {
+		return nil, fmt.Errorf("project not found: %s", projectName)
+	}
+
+	projectPath := filepath.Join(s.rootPath, projectName)
+	if _, err := os.Stat(projectPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("project not found: %s", projectName)
+	}
+
+	if err := s.removeProjectResources(ctx, projectName, opts, output); err != nil {
+		return nil, err
+	}
+
+	if !opts.Archive {
+		if err := os.RemoveAll(projectPath); err != nil {
+			return nil, fmt.Errorf("failed to delete project directory: %w", err)
+		}
+		if err := os.RemoveAll(s.revisionsDir(projectName)); err != nil {
+			return nil, fmt.Errorf("failed to delete project revisions: %w", err)
+		}
+		return nil, nil
+	}
+
+	archived := ArchivedProject{
+		ID:         projectName + "-" + time.Now().UTC().Format("20060102T150405Z"),
+		Project:    projectName,
+		ArchivedAt: time.Now().UTC(),
+		ArchivedBy: userFromContext(ctx),
+	}
+
+	if err := os.MkdirAll(s.archiveDir(), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	archivePath := s.archivePath(archived.ID)
+	err := os.Mkdir(archivePath, 0o755)
+	if os.IsExist(err) {
+		// The same project was archived within the same second
+		archived.ID += "-" + randomSuffix()
+		archivePath = s.archivePath(archived.ID)
+		err = os.Mkdir(archivePath, 0o755)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	data, err := json.Marshal(archived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal archive metadata: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(archivePath, "archive.json"), data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write archive metadata: %w", err)
+	}
+
+	if err := os.Rename(projectPath, filepath.Join(archivePath, "project")); err != nil {
+		return nil, fmt.Errorf("failed to archive project directory: %w", err)
+	}
+	if err := os.Rename(s.revisionsDir(projectName), filepath.Join(archivePath, "revisions")); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to archive project revisions: %w", err)
+	}
+
+	return &archived, nil
+}
+
+// removeProjectResources takes the project down with compose, so its
+// containers stop gracefully. If compose cannot (for example because the
+// compose file is broken), the containers found by the project's
+// com.docker.compose.project label are stopped and removed instead.
+func (s *Service) removeProjectResources(ctx context.Context, projectName string, opts DeleteOptions, output io.Writer) error {
+	// Without a docker client there is nothing to tear down
+	if s.dockerClient == nil {
+		return nil
+	}
+
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", fmt.Sprintf("com.docker.compose.project=%s", projectName))
+
+	var removeErrors []string
+
+	down := DownOptions{RemoveVolumes: opts.RemoveVolumes, RemoveOrphans: true}
+	if err := s.DownProject(ctx, projectName, down, output); err != nil {
+		if output != nil {
+			fmt.Fprintf(output, "%v\nStopping the project's containers directly\n", err)
+		}
+
+		containers, err := s.dockerClient.ContainerList(ctx, container.ListOptions{
+			All:     true,
+			Filters: filterArgs,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list containers: %w", err)
+		}
+
+		for _, c := range containers {
+			if err := s.dockerClient.ContainerStop(ctx, c.ID, container.StopOptions{}); err != nil {
+				removeErrors = append(removeErrors, fmt.Sprintf("container %s: %v", c.ID[:12], err))
+				continue
+			}
+			if err := s.dockerClient.ContainerRemove(ctx, c.ID, container.RemoveOptions{
+				RemoveVolumes: opts.RemoveVolumes,
+			}); err != nil {
+				removeErrors = append(removeErrors, fmt.Sprintf("container %s: %v", c.ID[:12], err))
+			}
+		}
+	}
+
+	if opts.RemoveVolumes {
+		volumes, err := s.dockerClient.VolumeList(ctx, volume.ListOptions{Filters: filterArgs})
+		if err != nil {
+			return fmt.Errorf("failed to list volumes: %w", err)
+		}
+		for _, v := range volumes.Volumes {
+			if err := s.dockerClient.VolumeRemove(ctx, v.Name, false); err != nil {
+				removeErrors = append(removeErrors, fmt.Sprintf("volume %s: %v", v.Name, err))
+			}
+		}
+	}
+
+	if opts.RemoveNetworks {
+		networks, err := s.dockerClient.NetworkList(ctx, network.ListOptions{Filters: filterArgs})
+		if err != nil {
+			return fmt.Errorf("failed to list networks: %w", err)
+		}
+		for _, n := range networks {
+			if err := s.dockerClient.NetworkRemove(ctx, n.ID); err != nil {
+				removeErrors = append(removeErrors, fmt.Sprintf("network %s: %v", n.Name, err))
+			}
+		}
+	}
+
+	if len(removeErrors) > 0 {
+		return fmt.Errorf("failed to remove some project resources: %v", removeErrors)
+	}
+
+	return nil
+}
+
+// ListArchivedProjects returns the archived projects, newest first
+func (s *Service) ListArchivedProjects(ctx context.Context) ([]ArchivedProject, error) {
+	entries, err := os.ReadDir(s.archiveDir())
+	if os.IsNotExist(err) {
+		return []ArchivedProject{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive directory: %w", err)
+	}
+
+	archived := make([]ArchivedProject, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		project, err := s.readArchive(entry.Name())
+		if err != nil {
+			continue
+		}
+		archived = append(archived, *project)
+	}
+
+	sort.Slice(archived, func(i, j int) bool {
+		return archived[i].ArchivedAt.After(archived[j].ArchivedAt)
+	})
+
+	return archived, nil
+}
+
+// RestoreArchivedProject moves an archived project back into place. Its
+// containers are not started; use UpProject for that.
+func (s *Service) RestoreArchivedProject(ctx context.Context, id string) (*ArchivedProject, error) {
+	archived, err := s.readArchive(id)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := s.lockProject(archived.Project)
+	defer unlock()
+
+	projectPath := filepath.Join(s.rootPath, archived.Project)
+	if _, err := os.Stat(projectPath); err == nil {
+		return nil, fmt.Errorf("project already exists: %s", archived.Project)
+	}
+
+	archivePath := s.archivePath(id)
+	if err := os.Rename(filepath.Join(archivePath, "project"), projectPath); err != nil {
+		return nil, fmt.Errorf("failed to restore project directory: %w", err)
+	}
+
+	// Any history left behind under this name belongs to another project
+	if err := os.RemoveAll(s.revisionsDir(archived.Project)); err != nil {
+		return nil, fmt.Errorf("failed to restore project revisions: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.revisionsDir(archived.Project)), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to restore project revisions: %w", err)
+	}
+	if err := os.Rename(filepath.Join(archivePath, "revisions"), s.revisionsDir(archived.Project)); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to restore project revisions: %w", err)
+	}
+
+	if err := os.RemoveAll(archivePath); err != nil {
+		return nil, fmt.Errorf("failed to remove archive: %w", err)
+	}
+
+	return archived, nil
+}
+
+// DeleteArchivedProject permanently removes an archived project
+func (s *Service) DeleteArchivedProject(ctx context.Context, id string) error {
+	if _, err := s.readArchive(id); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(s.archivePath(id)); err != nil {
+		return fmt.Errorf("failed to remove archive: %w", err)
+	}
+
+	return nil
+}
+
+// readArchive loads the metadata of an archived project
+func (s *Service) readArchive(id string) (*ArchivedProject, error) {
+	// IDs come from URLs; never let one point outside the archive
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("archived project not found: %s", id)
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.archivePath(id), "archive.json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("archived project not found: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive metadata: %w", err)
+	}
+
+	var archived ArchivedProject
+	if err := json.Unmarshal(data, &archived); err != nil {
+		return nil, fmt.Errorf("failed to parse archive metadata: %w", err)
+	}
+
+	return &archived, nil
+}
+
+// randomSuffix tells apart archive IDs that would otherwise be the same
+func randomSuffix() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (s *Service) archiveDir() string {
+	return filepath.Join(s.rootPath, ".hubble", "archive")
+}
+
+func (s *Service) archivePath(id string) string {
+	return filepath.Join(s.archiveDir(), id)
+}
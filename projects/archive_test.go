@@ -0,0 +1,120 @@
+package projects
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeleteProject_ArchiveAndRestore(t *testing.T) {
+	svc, composePath := newTestProject(t, "commented.yml")
+	ctx := WithUser(context.Background(), "admin")
+
+	// Give the project some history to carry through the archive
+	if err := svc.DeleteService(ctx, "site", "db"); err != nil {
+		t.Fatalf("DeleteService failed: %v", err)
+	}
+	content, err := os.ReadFile(composePath)
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+
+	archived, err := svc.DeleteProject(ctx, "site", DeleteOptions{Archive: true}, nil)
+	if err != nil {
+		t.Fatalf("DeleteProject failed: %v", err)
+	}
+	if archived == nil || archived.Project != "site" || archived.ArchivedBy != "admin" {
+		t.Fatalf("unexpected archive entry: %+v", archived)
+	}
+
+	if _, err := os.Stat(filepath.Join(svc.rootPath, "site")); !os.IsNotExist(err) {
+		t.Errorf("project directory still exists after archiving")
+	}
+	if projects, err := svc.ListProjects(ctx); err != nil || len(projects) != 0 {
+		t.Errorf("ListProjects = %v, %v; want no projects", projects, err)
+	}
+
+	list, err := svc.ListArchivedProjects(ctx)
+	if err != nil {
+		t.Fatalf("ListArchivedProjects failed: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != archived.ID {
+		t.Fatalf("ListArchivedProjects = %+v, want [%s]", list, archived.ID)
+	}
+
+	if _, err := svc.RestoreArchivedProject(ctx, archived.ID); err != nil {
+		t.Fatalf("RestoreArchivedProject failed: %v", err)
+	}
+
+	restored, err := os.ReadFile(composePath)
+	if err != nil {
+		t.Fatalf("Failed to read restored compose file: %v", err)
+	}
+	if string(restored) != string(content) {
+		t.Errorf("restored compose file differs from the archived one")
+	}
+
+	revisions, err := svc.ListRevisions(ctx, "site")
+	if err != nil {
+		t.Fatalf("ListRevisions failed: %v", err)
+	}
+	if len(revisions) != 2 {
+		t.Errorf("got %d revisions after restore, want 2", len(revisions))
+	}
+
+	if list, _ := svc.ListArchivedProjects(ctx); len(list) != 0 {
+		t.Errorf("archive entry still listed after restore: %+v", list)
+	}
+}
+
+func TestDeleteProject_ArchivesTwiceInOneSecond(t *testing.T) {
+	svc, _ := newTestProject(t, "commented.yml")
+	ctx := WithUser(context.Background(), "admin")
+
+	first, err := svc.DeleteProject(ctx, "site", DeleteOptions{Archive: true}, nil)
+	if err != nil {
+		t.Fatalf("DeleteProject failed: %v", err)
+	}
+	if err := svc.CreateProject(ctx, "site"); err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	second, err := svc.DeleteProject(ctx, "site", DeleteOptions{Archive: true}, nil)
+	if err != nil {
+		t.Fatalf("DeleteProject of the recreated project failed: %v", err)
+	}
+
+	if first.ID == second.ID {
+		t.Errorf("both archives got the ID %s", first.ID)
+	}
+	if list, _ := svc.ListArchivedProjects(ctx); len(list) != 2 {
+		t.Errorf("ListArchivedProjects = %+v, want 2 entries", list)
+	}
+}
+
+func TestDeleteProject_RemovesDirectoryAndHistory(t *testing.T) {
+	svc, _ := newTestProject(t, "commented.yml")
+	ctx := context.Background()
+
+	if err := svc.DeleteService(ctx, "site", "db"); err != nil {
+		t.Fatalf("DeleteService failed: %v", err)
+	}
+
+	archived, err := svc.DeleteProject(ctx, "site", DeleteOptions{}, nil)
+	if err != nil {
+		t.Fatalf("DeleteProject failed: %v", err)
+	}
+	if archived != nil {
+		t.Errorf("expected no archive entry, got %+v", archived)
+	}
+
+	for _, path := range []string{filepath.Join(svc.rootPath, "site"), svc.revisionsDir("site")} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s still exists after delete", path)
+		}
+	}
+
+	if _, err := svc.DeleteProject(ctx, ".hubble", DeleteOptions{}, nil); err == nil || err.Error() != "project not found: .hubble" {
+		t.Errorf("expected .hubble to be refused, got %v", err)
+	}
+}
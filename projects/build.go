@@ -0,0 +1,356 @@
+package projects
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// tagPattern is the syntax of an image tag
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+// repositoryPattern is the syntax of a single-component repository name
+var repositoryPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*$`)
+
+// Registry is the Hubble registry that built images can be pushed to
+type Registry struct {
+	// Host is the registry's host and optional port, as used in image
+	// references
+	Host     string
+	Username string
+	Password string
+}
+
+// SetRegistry sets the registry that BuildService pushes images to
+func (s *Service) SetRegistry(registry Registry) {
+	s.registry = &registry
+}
+
+// BuildOptions controls how a service image is built
+type BuildOptions struct {
+	NoCache bool `json:"no_cache,omitempty"`
+	// Pull always pulls newer versions of the base images
+	Pull bool `json:"pull,omitempty"`
+	// Push tags the built image as <registry>/<project>-<service>:<tag>,
+	// lowercased, and pushes it to the Hubble registry
+	Push bool   `json:"push,omitempty"`
+	Tag  string `json:"tag,omitempty"` // default: latest
+}
+
+// BuildStatus is the outcome of a build
+type BuildStatus string
+
+const (
+	BuildSucceeded BuildStatus = "succeeded"
+	BuildFailed    BuildStatus = "failed"
+)
+
+// Build records a service image build
+type Build struct {
+	ID         int         `json:"id"`
+	Project    string      `json:"project"`
+	Service    string      `json:"service"`
+	User       string      `json:"user"`
+	Status     BuildStatus `json:"status"`
+	Image      string      `json:"image"`
+	ImageID    string      `json:"image_id,omitempty"`
+	Size       int64       `json:"size,omitempty"`
+	PushedTo   string      `json:"pushed_to,omitempty"`
+	Digest     string      `json:"digest,omitempty"`
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt time.Time   `json:"finished_at"`
+	Duration   float64     `json:"duration_seconds"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// ValidateBuild checks that a service can be built with opts before a build
+// is started
+func (s *Service) ValidateBuild(projectName, serviceName string, opts BuildOptions) error {
+	_, err := s.validateBuild(projectName, serviceName, opts)
+	return err
+}
+
+// validateBuild checks the options and returns the service, which must
+// have a build section
+func (s *Service) validateBuild(projectName, serviceName string, opts BuildOptions) (ComposeService, error) {
+	if opts.Push && s.registry == nil {
+		return ComposeService{}, fmt.Errorf("registry is not configured")
+	}
+	if opts.Tag != "" && !tagPattern.MatchString(opts.Tag) {
+		return ComposeService{}, fmt.Errorf("invalid tag: %s", opts.Tag)
+	}
+	if opts.Push {
+		if _, err := pushRepository(projectName, serviceName); err != nil {
+			return ComposeService{}, err
+		}
+	}
+
+	if _, err := s.findComposeFile(projectName); err != nil {
+		return ComposeService{}, err
+	}
+
+	services, err := s.readComposeServices(projectName)
+	if err != nil {
+		return ComposeService{}, err
+	}
+
+	for _, service := range services {
+		if service.Name != serviceName {
+			continue
+		}
+		if service.Build.IsZero() {
+			return ComposeService{}, fmt.Errorf("service has no build configuration: %s", serviceName)
+		}
+		return service, nil
+	}
+
+	return ComposeService{}, fmt.Errorf("service not found: %s", serviceName)
+}
+
+// BuildService builds a service's image with `docker compose build`, which
+// applies its build section (context, dockerfile, args, target, ...), and
+// optionally pushes it to the Hubble registry. Build and push output is
+// written to output. The build is recorded whether or not it succeeds.
+func (s *Service) BuildService(ctx context.Context, projectName, serviceName string, opts BuildOptions, output io.Writer) (*Build, error) {
+	if _, err := s.validateBuild(projectName, serviceName, opts); err != nil {
+		return nil, err
+	}
+	if output == nil {
+		output = io.Discard
+	}
+
+	build := &Build{
+		Project:   projectName,
+		Service:   serviceName,
+		User:      userFromContext(ctx),
+		StartedAt: time.Now().UTC(),
+	}
+
+	err := s.runBuild(ctx, build, opts, output)
+
+	build.FinishedAt = time.Now().UTC()
+	build.Duration = build.FinishedAt.Sub(build.StartedAt).Seconds()
+	build.Status = BuildSucceeded
+	if err != nil {
+		build.Status = BuildFailed
+		build.Error = err.Error()
+	}
+
+	if recordErr := s.recordBuild(build); recordErr != nil && err == nil {
+		err = recordErr
+	}
+	return build, err
+}
+
+func (s *Service) runBuild(ctx context.Context, build *Build, opts BuildOptions, output io.Writer) error {
+	// Ask compose for the name it tags the image with, which depends on the
+	// image key, interpolation and the compose version
+	images, err := s.composeOutput(ctx, build.Project, "config", "--images", build.Service)
+	if err != nil {
+		return fmt.Errorf("failed to resolve image name with docker compose: %w", err)
+	}
+	build.Image = strings.TrimSpace(images)
+	if build.Image == "" || strings.Contains(build.Image, "\n") {
+		return fmt.Errorf("unexpected image name from docker compose: %q", build.Image)
+	}
+
+	args := []string{"build"}
+	if opts.NoCache {
+		args = append(args, "--no-cache")
+	}
+	if opts.Pull {
+		args = append(args, "--pull")
+	}
+
+	if err := s.runCompose(ctx, build.Project, output, withServices(args, []string{build.Service})...); err != nil {
+		return fmt.Errorf("failed to build service with docker compose: %w", err)
+	}
+
+	inspect, err := s.dockerClient.ImageInspect(ctx, build.Image)
+	if err != nil {
+		return fmt.Errorf("failed to inspect built image: %w", err)
+	}
+	build.ImageID = inspect.ID
+	build.Size = inspect.Size
+
+	if !opts.Push {
+		return nil
+	}
+
+	tag := opts.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+	repository, err := pushRepository(build.Project, build.Service)
+	if err != nil {
+		return err
+	}
+	target := s.registry.Host + "/" + repository + ":" + tag
+
+	digest, err := s.pushImage(ctx, build.Image, target, output)
+	if err != nil {
+		return err
+	}
+	build.PushedTo = target
+	build.Digest = digest
+
+	return nil
+}
+
+// pushRepository returns the repository a service's image is pushed to.
+// Repository names must be lowercase, unlike service names.
+func pushRepository(projectName, serviceName string) (string, error) {
+	repository := strings.ToLower(projectName + "-" + serviceName)
+	if !repositoryPattern.MatchString(repository) {
+		return "", fmt.Errorf("invalid push repository: %s", repository)
+	}
+	return repository, nil
+}
+
+// pushImage tags an image as target, pushes it to the Hubble registry and
+// returns the digest of the pushed manifest
+func (s *Service) pushImage(ctx context.Context, imageName, target string, output io.Writer) (string, error) {
+	if err := s.dockerClient.ImageTag(ctx, imageName, target); err != nil {
+		return "", fmt.Errorf("failed to tag image: %w", err)
+	}
+
+	auth, err := registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      s.registry.Username,
+		Password:      s.registry.Password,
+		ServerAddress: s.registry.Host,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode registry credentials: %w", err)
+	}
+
+	fmt.Fprintf(output, "Pushing %s\n", target)
+	body, err := s.dockerClient.ImagePush(ctx, target, image.PushOptions{RegistryAuth: auth})
+	if err != nil {
+		return "", fmt.Errorf("failed to push image: %w", err)
+	}
+	defer body.Close()
+
+	digest := ""
+	err = jsonmessage.DisplayJSONMessagesStream(body, output, 0, false, func(msg jsonmessage.JSONMessage) {
+		if msg.Aux == nil {
+			return
+		}
+		var result struct {
+			Digest string `json:"Digest"`
+		}
+		if json.Unmarshal(*msg.Aux, &result) == nil && result.Digest != "" {
+			digest = result.Digest
+		}
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to push image: %w", err)
+	}
+
+	return digest, nil
+}
+
+// ListBuilds returns a project's builds, newest first, optionally only
+// those of one service. A limit of 0 returns all of them.
+func (s *Service) ListBuilds(ctx context.Context, projectName, serviceName string, limit int) ([]Build, error) {
+	if _, err := os.Stat(filepath.Join(s.rootPath, projectName)); os.IsNotExist(err) {
+		return nil, fmt.Errorf("project not found: %s", projectName)
+	}
+
+	builds, err := s.readBuilds(projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Build, 0)
+	for i := len(builds) - 1; i >= 0; i-- {
+		if serviceName != "" && builds[i].Service != serviceName {
+			continue
+		}
+		result = append(result, builds[i])
+		if limit > 0 && len(result) == limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// recordBuild numbers a build and appends it to the project's build log.
+// Builds of a project run as jobs one at a time, so appends do not race.
+func (s *Service) recordBuild(build *Build) error {
+	builds, err := s.readBuilds(build.Project)
+	if err != nil {
+		return err
+	}
+	build.ID = 1
+	if len(builds) > 0 {
+		build.ID = builds[len(builds)-1].ID + 1
+	}
+
+	if err := os.MkdirAll(s.buildsDir(), 0o755); err != nil {
+		return fmt.Errorf("failed to create builds directory: %w", err)
+	}
+
+	data, err := json.Marshal(build)
+	if err != nil {
+		return fmt.Errorf("failed to marshal build: %w", err)
+	}
+
+	file, err := os.OpenFile(s.buildsPath(build.Project), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open build log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write build log: %w", err)
+	}
+	return nil
+}
+
+// readBuilds returns a project's recorded builds, oldest first
+func (s *Service) readBuilds(projectName string) ([]Build, error) {
+	file, err := os.Open(s.buildsPath(projectName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open build log: %w", err)
+	}
+	defer file.Close()
+
+	var builds []Build
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var build Build
+		if err := json.Unmarshal(scanner.Bytes(), &build); err != nil {
+			continue
+		}
+		builds = append(builds, build)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read build log: %w", err)
+	}
+
+	return builds, nil
+}
+
+// buildsDir is kept outside project directories, like revisions
+func (s *Service) buildsDir() string {
+	return filepath.Join(s.rootPath, ".hubble", "builds")
+}
+
+func (s *Service) buildsPath(projectName string) string {
+	return filepath.Join(s.buildsDir(), projectName+".jsonl")
+}
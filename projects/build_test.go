@@ -0,0 +1,88 @@
+package projects
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestValidateBuild(t *testing.T) {
+	svc, _ := newTestProject(t, "longform.yml")
+
+	tests := []struct {
+		project, service string
+		opts             BuildOptions
+		err              string
+	}{
+		{"site", "api", BuildOptions{NoCache: true}, ""},
+		{"site", "api", BuildOptions{Tag: "-bad"}, "invalid tag: -bad"},
+		{"site", "api", BuildOptions{Push: true}, "registry is not configured"},
+		{"site", "worker", BuildOptions{}, "service not found: worker"},
+		{"other", "api", BuildOptions{}, "project not found: other"},
+	}
+	for _, tt := range tests {
+		err := svc.ValidateBuild(tt.project, tt.service, tt.opts)
+		if (err == nil && tt.err != "") || (err != nil && err.Error() != tt.err) {
+			t.Errorf("ValidateBuild(%s, %s, %+v) = %v, want %q", tt.project, tt.service, tt.opts, err, tt.err)
+		}
+	}
+
+	svc.SetRegistry(Registry{Host: "registry.example.com"})
+	if err := svc.ValidateBuild("site", "api", BuildOptions{Push: true, Tag: "v1.2.0"}); err != nil {
+		t.Errorf("expected a push with a registry to be valid, got %v", err)
+	}
+}
+
+func TestPushRepository(t *testing.T) {
+	tests := []struct {
+		project, service, want, err string
+	}{
+		{"site", "api", "site-api", ""},
+		{"Site", "Web_API", "site-web_api", ""},
+		{"site", "api.", "", "invalid push repository: site-api."},
+		{"site", "a..b", "", "invalid push repository: site-a..b"},
+	}
+	for _, tt := range tests {
+		got, err := pushRepository(tt.project, tt.service)
+		if got != tt.want || (err == nil && tt.err != "") || (err != nil && err.Error() != tt.err) {
+			t.Errorf("pushRepository(%s, %s) = %q, %v; want %q, %q", tt.project, tt.service, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestRecordBuild_ListsNewestFirst(t *testing.T) {
+	svc, _ := newTestProject(t, "longform.yml")
+	ctx := context.Background()
+
+	started := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	for i, service := range []string{"api", "worker", "api"} {
+		build := &Build{
+			Project:    "site",
+			Service:    service,
+			Status:     BuildSucceeded,
+			StartedAt:  started.Add(time.Duration(i) * time.Minute),
+			FinishedAt: started.Add(time.Duration(i)*time.Minute + 30*time.Second),
+		}
+		if err := svc.recordBuild(build); err != nil {
+			t.Fatalf("recordBuild failed: %v", err)
+		}
+		if build.ID != i+1 {
+			t.Errorf("build %d got ID %d", i, build.ID)
+		}
+	}
+
+	builds, err := svc.ListBuilds(ctx, "site", "api", 0)
+	if err != nil {
+		t.Fatalf("ListBuilds failed: %v", err)
+	}
+	if len(builds) != 2 || builds[0].ID != 3 || builds[1].ID != 1 {
+		t.Errorf("unexpected api builds %+v", builds)
+	}
+
+	if builds, _ := svc.ListBuilds(ctx, "site", "", 1); len(builds) != 1 || builds[0].ID != 3 {
+		t.Errorf("unexpected limited builds %+v", builds)
+	}
+	if _, err := svc.ListBuilds(ctx, "other", "", 0); err == nil || err.Error() != "project not found: other" {
+		t.Errorf("expected project not found, got %v", err)
+	}
+}
@@ -0,0 +1,444 @@
+package projects
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Compose files are edited as yaml.Node trees rather than through ComposeFile
+// so that comments, key order, anchors, x- extension fields and any keys
+// Hubble doesn't know about survive a write. renderCompose then copies every
+// entry whose content did not change verbatim from the original source, so
+// only the edited section of the file is re-serialized.
+
+// parseComposeDocument parses compose file content into a document node.
+// Empty content yields a document holding an empty mapping.
+func parseComposeDocument(content []byte) (*yaml.Node, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+
+	var doc yaml.Node
+	if err := decoder.Decode(&doc); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	// Compose only reads the first document, but we refuse to write a file
+	// that would silently drop the others
+	var extra yaml.Node
+	if err := decoder.Decode(&extra); err != io.EOF {
+		return nil, fmt.Errorf("multi-document compose files are not supported")
+	}
+
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode}
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("compose file must be a mapping at the top level")
+	}
+
+	return &doc, nil
+}
+
+// composeRoot returns the top-level mapping of a compose document
+func composeRoot(doc *yaml.Node) *yaml.Node {
+	return doc.Content[0]
+}
+
+// mappingValue returns the value stored under key in a mapping node, or nil
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue stores value under key, appending the key if it is new.
+// When the key exists and already holds an equivalent value the original
+// node is kept so its formatting and comments are untouched.
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	// Adding to an empty flow mapping (e.g. "services: {}") switches it to
+	// block style so the result reads like a hand-written compose file
+	if len(m.Content) == 0 && m.Style&yaml.FlowStyle != 0 {
+		m.Style &^= yaml.FlowStyle
+	}
+
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value != key {
+			continue
+		}
+
+		existing := m.Content[i+1]
+		if sameValue(existing, value) {
+			return
+		}
+
+		// Carry comments over so "image: nginx # pinned" keeps its note
+		value.HeadComment = existing.HeadComment
+		value.LineComment = existing.LineComment
+		value.FootComment = existing.FootComment
+		m.Content[i+1] = value
+		return
+	}
+
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// deleteMappingKey removes key from a mapping node, reporting whether it existed
+func deleteMappingKey(m *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ensureMapping returns the mapping stored under key, creating it when the
+// key is missing or null
+func ensureMapping(m *yaml.Node, key string) (*yaml.Node, error) {
+	value := mappingValue(m, key)
+	if value == nil || value.Tag == "!!null" {
+		child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingValue(m, key, child)
+		return child, nil
+	}
+
+	if value.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s must be a mapping", key)
+	}
+
+	return value, nil
+}
+
+// valueNode converts a Go value into a yaml.Node
+func valueNode(v interface{}) (*yaml.Node, error) {
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to encode value: %w", err)
+	}
+	return &node, nil
+}
+
+// sameValue reports whether two nodes decode to the same data
+func sameValue(a, b *yaml.Node) bool {
+	var av, bv interface{}
+	if err := a.Decode(&av); err != nil {
+		return false
+	}
+	if err := b.Decode(&bv); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+// renderCompose serializes updated, reusing the original source text for
+// every mapping entry that is unchanged relative to original. Both arguments
+// are document nodes parsed from source.
+func renderCompose(source []byte, original, updated *yaml.Node) ([]byte, error) {
+	unit := detectIndent(composeRoot(original))
+
+	text := string(source)
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	origRoot := composeRoot(original)
+	if !isBlockMapping(origRoot) || len(origRoot.Content) == 0 {
+		return encodeNode(updated, unit)
+	}
+
+	r := &composeRenderer{lines: lines, unit: unit}
+	spans := r.spans(origRoot, len(lines), commentLines(original.FootComment)+commentLines(origRoot.FootComment))
+	if spans == nil {
+		return encodeNode(updated, unit)
+	}
+
+	var b strings.Builder
+	for _, line := range lines[:spans[0].start] {
+		b.WriteString(line)
+	}
+	if err := r.renderMapping(&b, origRoot, composeRoot(updated), spans, len(lines)); err != nil {
+		return nil, err
+	}
+
+	return []byte(b.String()), nil
+}
+
+// entrySpan is the range of source lines [start, end) holding one mapping
+// entry, including its head comment but excluding trailing blank lines
+type entrySpan struct {
+	start, end int
+}
+
+type composeRenderer struct {
+	lines []string
+	unit  int
+}
+
+// spans computes the source line range of every entry in mapping m, which
+// must end before line limit. foot is the number of comment lines at the
+// end of the region that belong to the enclosing node rather than the last
+// entry. It returns nil if the layout can't be mapped back onto the source.
+func (r *composeRenderer) spans(m *yaml.Node, limit, foot int) []entrySpan {
+	n := len(m.Content) / 2
+	spans := make([]entrySpan, n)
+
+	for i := 0; i < n; i++ {
+		key := m.Content[2*i]
+		start := key.Line - 1 - commentLines(key.HeadComment)
+		if key.Line < 1 || start < 0 || start >= limit {
+			return nil
+		}
+		for j := start; j < key.Line-1; j++ {
+			if !isCommentOrBlank(r.lines[j]) {
+				return nil
+			}
+		}
+		spans[i].start = start
+	}
+
+	for i := 0; i < n; i++ {
+		end := limit
+		if i+1 < n {
+			end = spans[i+1].start
+		} else if foot > 0 {
+			end = r.trimBlank(spans[i].start, end) - foot
+			if end <= spans[i].start {
+				return nil
+			}
+			for j := end; j < end+foot; j++ {
+				if !isCommentOrBlank(r.lines[j]) {
+					return nil
+				}
+			}
+		}
+		end = r.trimBlank(spans[i].start, end)
+		if end <= spans[i].start {
+			return nil
+		}
+		spans[i].end = end
+	}
+
+	return spans
+}
+
+// trimBlank moves end back past any blank lines, stopping at start+1
+func (r *composeRenderer) trimBlank(start, end int) int {
+	for end > start+1 && strings.TrimSpace(r.lines[end-1]) == "" {
+		end--
+	}
+	return end
+}
+
+// renderMapping writes the entries of updated, given the entries of orig
+// occupy spans in the source and the mapping's region ends at limit
+func (r *composeRenderer) renderMapping(b *strings.Builder, orig, updated *yaml.Node, spans []entrySpan, limit int) error {
+	// Text between consecutive entries, and after the last one
+	gaps := make([]string, len(spans))
+	for i, sp := range spans {
+		next := limit
+		if i+1 < len(spans) {
+			next = spans[i+1].start
+		}
+		gaps[i] = strings.Join(r.lines[sp.end:next], "")
+	}
+
+	// New entries get a blank line before them if the existing ones are
+	// separated that way
+	separator := ""
+	if len(spans) > 1 && strings.HasPrefix(gaps[0], "\n") {
+		separator = "\n"
+	}
+
+	origIndex := make(map[string]int, len(spans))
+	for i := 0; i < len(spans); i++ {
+		origIndex[orig.Content[2*i].Value] = i
+	}
+
+	type piece struct {
+		text string
+		gap  string
+	}
+	pieces := make([]piece, 0, len(updated.Content)/2)
+
+	for j := 0; j+1 < len(updated.Content); j += 2 {
+		key, value := updated.Content[j], updated.Content[j+1]
+
+		i, existed := origIndex[key.Value]
+		if !existed {
+			text, err := encodePair(key, value, r.indentOf(orig), r.unit)
+			if err != nil {
+				return err
+			}
+			pieces = append(pieces, piece{text: text, gap: separator})
+			continue
+		}
+
+		var entry strings.Builder
+		if err := r.renderEntry(&entry, orig.Content[2*i], orig.Content[2*i+1], key, value, spans[i]); err != nil {
+			return err
+		}
+		gap := separator
+		if i+1 < len(spans) {
+			gap = gaps[i]
+		}
+		pieces = append(pieces, piece{text: entry.String(), gap: gap})
+	}
+
+	for i, p := range pieces {
+		b.WriteString(p.text)
+		if i+1 < len(pieces) {
+			b.WriteString(p.gap)
+		}
+	}
+	b.WriteString(gaps[len(gaps)-1])
+
+	return nil
+}
+
+// renderEntry writes a single key/value entry, copying it from source when
+// unchanged and descending into block mappings so that only the nested
+// entries that actually changed are re-serialized
+func (r *composeRenderer) renderEntry(b *strings.Builder, origKey, origValue, key, value *yaml.Node, sp entrySpan) error {
+	origText, err := encodePair(origKey, origValue, 0, r.unit)
+	if err != nil {
+		return err
+	}
+	newText, err := encodePair(key, value, 0, r.unit)
+	if err != nil {
+		return err
+	}
+
+	if origText == newText {
+		for _, line := range r.lines[sp.start:sp.end] {
+			b.WriteString(line)
+		}
+		return nil
+	}
+
+	if isBlockMapping(origValue) && isBlockMapping(value) && len(origValue.Content) > 0 && len(value.Content) > 0 && origValue.Line > origKey.Line {
+		foot := commentLines(origKey.FootComment) + commentLines(origValue.FootComment)
+		if spans := r.spans(origValue, sp.end, foot); spans != nil {
+			for _, line := range r.lines[sp.start:spans[0].start] {
+				b.WriteString(line)
+			}
+			return r.renderMapping(b, origValue, value, spans, sp.end)
+		}
+	}
+
+	text, err := encodePair(key, value, origKey.Column-1, r.unit)
+	if err != nil {
+		return err
+	}
+	b.WriteString(text)
+	return nil
+}
+
+// indentOf returns the column at which the keys of mapping m start
+func (r *composeRenderer) indentOf(m *yaml.Node) int {
+	if len(m.Content) > 0 && m.Content[0].Column > 0 {
+		return m.Content[0].Column - 1
+	}
+	return 0
+}
+
+// encodePair serializes a single mapping entry, indenting every line by indent spaces
+func encodePair(key, value *yaml.Node, indent, unit int) (string, error) {
+	m := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{key, value}}
+	output, err := encodeNode(m, unit)
+	if err != nil {
+		return "", err
+	}
+
+	if indent == 0 {
+		return string(output), nil
+	}
+
+	prefix := strings.Repeat(" ", indent)
+	lines := strings.SplitAfter(string(output), "\n")
+	var b strings.Builder
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			b.WriteString(prefix)
+		}
+		b.WriteString(line)
+	}
+	return b.String(), nil
+}
+
+// encodeNode serializes a node with the given indentation unit
+func encodeNode(node *yaml.Node, unit int) ([]byte, error) {
+	fixMergeTags(node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(unit)
+	if err := encoder.Encode(node); err != nil {
+		return nil, fmt.Errorf("failed to marshal compose file: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal compose file: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// fixMergeTags clears the explicit !!merge tag the decoder puts on "<<" keys,
+// which the encoder would otherwise write out as "!!merge <<"
+func fixMergeTags(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Tag == "!!merge" {
+				node.Content[i].Tag = ""
+			}
+		}
+	}
+	for _, child := range node.Content {
+		fixMergeTags(child)
+	}
+}
+
+// detectIndent returns the indentation unit used by a compose file, based on
+// the first nested block mapping. Defaults to two spaces.
+func detectIndent(root *yaml.Node) int {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if isBlockMapping(value) && len(value.Content) > 0 {
+			if indent := value.Content[0].Column - key.Column; indent > 0 {
+				return indent
+			}
+		}
+	}
+	return 2
+}
+
+func isBlockMapping(node *yaml.Node) bool {
+	return node != nil && node.Kind == yaml.MappingNode && node.Style&yaml.FlowStyle == 0
+}
+
+func isCommentOrBlank(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#")
+}
+
+// commentLines returns how many source lines a node comment spans
+func commentLines(comment string) int {
+	if comment == "" {
+		return 0
+	}
+	return strings.Count(comment, "\n") + 1
+}
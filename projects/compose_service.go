@@ -0,0 +1,1333 @@
+package projects
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ComposeService is a service definition covering the Compose Specification.
+// Fields that the spec allows in several forms (short/long port syntax,
+// list/map environment, string/array command, ...) accept every form when
+// read and are normalized into a single Go representation, so two services
+// that mean the same thing compare equal.
+//
+// Name and Status are Hubble metadata and never written to the compose file.
+// JSON keys match the compose keys so a request body reads like the YAML.
+type ComposeService struct {
+	Name   string `json:"name" yaml:"-"`
+	Status string `json:"status,omitempty" yaml:"-"`
+
+	Image         string      `json:"image" yaml:"image,omitempty"`
+	Build         BuildConfig `json:"build" yaml:"build,omitempty"`
+	ContainerName string      `json:"container_name" yaml:"container_name,omitempty"`
+	Hostname      string      `json:"hostname,omitempty" yaml:"hostname,omitempty"`
+	Domainname    string      `json:"domainname,omitempty" yaml:"domainname,omitempty"`
+	Platform      string      `json:"platform,omitempty" yaml:"platform,omitempty"`
+	PullPolicy    string      `json:"pull_policy,omitempty" yaml:"pull_policy,omitempty"`
+	Profiles      []string    `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+
+	Command    ShellCommand `json:"command" yaml:"command,omitempty"`
+	Entrypoint ShellCommand `json:"entrypoint,omitempty" yaml:"entrypoint,omitempty"`
+	WorkingDir string       `json:"working_dir,omitempty" yaml:"working_dir,omitempty"`
+	User       string       `json:"user,omitempty" yaml:"user,omitempty"`
+	GroupAdd   StringList   `json:"group_add,omitempty" yaml:"group_add,omitempty"`
+	Init       *bool        `json:"init,omitempty" yaml:"init,omitempty"`
+	StdinOpen  bool         `json:"stdin_open,omitempty" yaml:"stdin_open,omitempty"`
+	Tty        bool         `json:"tty,omitempty" yaml:"tty,omitempty"`
+
+	Restart         string `json:"restart" yaml:"restart,omitempty"`
+	StopSignal      string `json:"stop_signal,omitempty" yaml:"stop_signal,omitempty"`
+	StopGracePeriod string `json:"stop_grace_period,omitempty" yaml:"stop_grace_period,omitempty"`
+
+	Environment MappingWithEquals `json:"environment" yaml:"environment,omitempty"`
+	EnvFile     EnvFiles          `json:"env_file,omitempty" yaml:"env_file,omitempty"`
+	Labels      Labels            `json:"labels" yaml:"labels,omitempty"`
+	Annotations Mapping           `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+
+	Ports         []ServicePort   `json:"ports" yaml:"ports,omitempty"`
+	Expose        StringList      `json:"expose,omitempty" yaml:"expose,omitempty"`
+	Networks      ServiceNetworks `json:"networks" yaml:"networks,omitempty"`
+	NetworkMode   string          `json:"network_mode,omitempty" yaml:"network_mode,omitempty"`
+	Links         []string        `json:"links,omitempty" yaml:"links,omitempty"`
+	ExternalLinks []string        `json:"external_links,omitempty" yaml:"external_links,omitempty"`
+	ExtraHosts    HostsList       `json:"extra_hosts,omitempty" yaml:"extra_hosts,omitempty"`
+	DNS           StringList      `json:"dns,omitempty" yaml:"dns,omitempty"`
+	DNSSearch     StringList      `json:"dns_search,omitempty" yaml:"dns_search,omitempty"`
+	DNSOpt        []string        `json:"dns_opt,omitempty" yaml:"dns_opt,omitempty"`
+	MacAddress    string          `json:"mac_address,omitempty" yaml:"mac_address,omitempty"`
+
+	Volumes     []ServiceVolume  `json:"volumes" yaml:"volumes,omitempty"`
+	VolumesFrom []string         `json:"volumes_from,omitempty" yaml:"volumes_from,omitempty"`
+	Tmpfs       StringList       `json:"tmpfs,omitempty" yaml:"tmpfs,omitempty"`
+	ReadOnly    bool             `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+	Secrets     []ServiceFileRef `json:"secrets,omitempty" yaml:"secrets,omitempty"`
+	Configs     []ServiceFileRef `json:"configs,omitempty" yaml:"configs,omitempty"`
+
+	DependsOn   DependsOn      `json:"depends_on" yaml:"depends_on,omitempty"`
+	Healthcheck *Healthcheck   `json:"healthcheck,omitempty" yaml:"healthcheck,omitempty"`
+	Deploy      *DeployConfig  `json:"deploy,omitempty" yaml:"deploy,omitempty"`
+	Scale       *int           `json:"scale,omitempty" yaml:"scale,omitempty"`
+	Logging     *LoggingConfig `json:"logging,omitempty" yaml:"logging,omitempty"`
+
+	CapAdd       []string          `json:"cap_add,omitempty" yaml:"cap_add,omitempty"`
+	CapDrop      []string          `json:"cap_drop,omitempty" yaml:"cap_drop,omitempty"`
+	Privileged   bool              `json:"privileged,omitempty" yaml:"privileged,omitempty"`
+	SecurityOpt  []string          `json:"security_opt,omitempty" yaml:"security_opt,omitempty"`
+	Devices      []string          `json:"devices,omitempty" yaml:"devices,omitempty"`
+	Ulimits      map[string]Ulimit `json:"ulimits,omitempty" yaml:"ulimits,omitempty"`
+	Sysctls      Mapping           `json:"sysctls,omitempty" yaml:"sysctls,omitempty"`
+	StorageOpt   map[string]string `json:"storage_opt,omitempty" yaml:"storage_opt,omitempty"`
+	Ipc          string            `json:"ipc,omitempty" yaml:"ipc,omitempty"`
+	Pid          string            `json:"pid,omitempty" yaml:"pid,omitempty"`
+	Cgroup       string            `json:"cgroup,omitempty" yaml:"cgroup,omitempty"`
+	CgroupParent string            `json:"cgroup_parent,omitempty" yaml:"cgroup_parent,omitempty"`
+	UsernsMode   string            `json:"userns_mode,omitempty" yaml:"userns_mode,omitempty"`
+	Isolation    string            `json:"isolation,omitempty" yaml:"isolation,omitempty"`
+	Runtime      string            `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+
+	ShmSize        string `json:"shm_size,omitempty" yaml:"shm_size,omitempty"`
+	MemLimit       string `json:"mem_limit,omitempty" yaml:"mem_limit,omitempty"`
+	MemReservation string `json:"mem_reservation,omitempty" yaml:"mem_reservation,omitempty"`
+	MemswapLimit   string `json:"memswap_limit,omitempty" yaml:"memswap_limit,omitempty"`
+	Cpus           string `json:"cpus,omitempty" yaml:"cpus,omitempty"`
+	CPUShares      int64  `json:"cpu_shares,omitempty" yaml:"cpu_shares,omitempty"`
+	CPUSet         string `json:"cpuset,omitempty" yaml:"cpuset,omitempty"`
+	PidsLimit      int64  `json:"pids_limit,omitempty" yaml:"pids_limit,omitempty"`
+	OomKillDisable bool   `json:"oom_kill_disable,omitempty" yaml:"oom_kill_disable,omitempty"`
+	OomScoreAdj    int    `json:"oom_score_adj,omitempty" yaml:"oom_score_adj,omitempty"`
+
+	// Extensions holds x- extension fields and any key not modelled above
+	Extensions map[string]interface{} `json:"extensions,omitempty" yaml:",inline"`
+
+	// fields records which keys were present when decoded from JSON, so a
+	// partial update only touches what the caller sent. nil means all keys.
+	fields map[string]bool
+}
+
+// UnmarshalJSON decodes a service and records which keys were supplied
+func (s *ComposeService) UnmarshalJSON(data []byte) error {
+	type plain ComposeService
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+
+	*s = ComposeService(p)
+	s.fields = make(map[string]bool, len(keys))
+	for key := range keys {
+		s.fields[key] = true
+	}
+	return nil
+}
+
+// hasField reports whether key should be written by an update
+func (s *ComposeService) hasField(key string) bool {
+	return s.fields == nil || s.fields[key]
+}
+
+// serviceKey is a compose key modelled by ComposeService and the index of the
+// struct field holding it
+type serviceKey struct {
+	key   string
+	index int
+}
+
+// serviceKeys lists the compose keys modelled by ComposeService in
+// declaration order, which is also the order new services are written in
+var serviceKeys = func() []serviceKey {
+	var keys []serviceKey
+	t := reflect.TypeOf(ComposeService{})
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		keys = append(keys, serviceKey{key: tag, index: i})
+	}
+	return keys
+}()
+
+// isServiceKey reports whether key is modelled by ComposeService
+func isServiceKey(key string) bool {
+	for _, k := range serviceKeys {
+		if k.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// withDefaults replaces nil collections the web UI expects with empty ones
+func (s ComposeService) withDefaults() ComposeService {
+	if s.Ports == nil {
+		s.Ports = []ServicePort{}
+	}
+	if s.Environment == nil {
+		s.Environment = MappingWithEquals{}
+	}
+	if s.Volumes == nil {
+		s.Volumes = []ServiceVolume{}
+	}
+	if s.DependsOn == nil {
+		s.DependsOn = DependsOn{}
+	}
+	if s.Networks == nil {
+		s.Networks = ServiceNetworks{}
+	}
+	if s.Labels == nil {
+		s.Labels = Labels{}
+	}
+	if s.Command == nil {
+		s.Command = ShellCommand{}
+	}
+	return s
+}
+
+// BuildConfig is the build section of a service. The short form is a
+// context path, which is how it is written back when nothing else is set.
+type BuildConfig struct {
+	Context            string            `json:"context,omitempty" yaml:"context,omitempty"`
+	Dockerfile         string            `json:"dockerfile,omitempty" yaml:"dockerfile,omitempty"`
+	DockerfileInline   string            `json:"dockerfile_inline,omitempty" yaml:"dockerfile_inline,omitempty"`
+	Args               MappingWithEquals `json:"args,omitempty" yaml:"args,omitempty"`
+	SSH                StringList        `json:"ssh,omitempty" yaml:"ssh,omitempty"`
+	Target             string            `json:"target,omitempty" yaml:"target,omitempty"`
+	Labels             Labels            `json:"labels,omitempty" yaml:"labels,omitempty"`
+	CacheFrom          []string          `json:"cache_from,omitempty" yaml:"cache_from,omitempty"`
+	CacheTo            []string          `json:"cache_to,omitempty" yaml:"cache_to,omitempty"`
+	AdditionalContexts Mapping           `json:"additional_contexts,omitempty" yaml:"additional_contexts,omitempty"`
+	ExtraHosts         HostsList         `json:"extra_hosts,omitempty" yaml:"extra_hosts,omitempty"`
+	Network            string            `json:"network,omitempty" yaml:"network,omitempty"`
+	NoCache            bool              `json:"no_cache,omitempty" yaml:"no_cache,omitempty"`
+	Pull               bool              `json:"pull,omitempty" yaml:"pull,omitempty"`
+	ShmSize            string            `json:"shm_size,omitempty" yaml:"shm_size,omitempty"`
+	Isolation          string            `json:"isolation,omitempty" yaml:"isolation,omitempty"`
+	Privileged         bool              `json:"privileged,omitempty" yaml:"privileged,omitempty"`
+	Secrets            []ServiceFileRef  `json:"secrets,omitempty" yaml:"secrets,omitempty"`
+	Tags               []string          `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Platforms          []string          `json:"platforms,omitempty" yaml:"platforms,omitempty"`
+}
+
+// IsZero reports whether no build configuration is set
+func (b BuildConfig) IsZero() bool {
+	return reflect.DeepEqual(b, BuildConfig{})
+}
+
+// contextOnly reports whether the build can be written in its short form
+func (b BuildConfig) contextOnly() bool {
+	return reflect.DeepEqual(b, BuildConfig{Context: b.Context})
+}
+
+func (b BuildConfig) MarshalYAML() (interface{}, error) {
+	if b.contextOnly() {
+		return b.Context, nil
+	}
+	type plain BuildConfig
+	return plain(b), nil
+}
+
+func (b *BuildConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*b = BuildConfig{Context: node.Value}
+		return nil
+	}
+	type plain BuildConfig
+	return node.Decode((*plain)(b))
+}
+
+func (b BuildConfig) MarshalJSON() ([]byte, error) {
+	if b.contextOnly() {
+		return json.Marshal(b.Context)
+	}
+	type plain BuildConfig
+	return json.Marshal(plain(b))
+}
+
+func (b *BuildConfig) UnmarshalJSON(data []byte) error {
+	var context string
+	if err := json.Unmarshal(data, &context); err == nil {
+		*b = BuildConfig{Context: context}
+		return nil
+	}
+	type plain BuildConfig
+	return json.Unmarshal(data, (*plain)(b))
+}
+
+// ShellCommand is a command or entrypoint. The string form is split into
+// arguments the way compose does, and always written back as a list.
+type ShellCommand []string
+
+func (c ShellCommand) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+	for _, arg := range c {
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: arg, Style: yaml.DoubleQuotedStyle})
+	}
+	return node, nil
+}
+
+func (c *ShellCommand) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		args, err := splitCommand(node.Value)
+		if err != nil {
+			return err
+		}
+		*c = args
+		return nil
+	}
+	var args []string
+	if err := node.Decode(&args); err != nil {
+		return err
+	}
+	*c = args
+	return nil
+}
+
+func (c *ShellCommand) UnmarshalJSON(data []byte) error {
+	var command string
+	if err := json.Unmarshal(data, &command); err == nil {
+		args, err := splitCommand(command)
+		if err != nil {
+			return err
+		}
+		*c = args
+		return nil
+	}
+	var args []string
+	if err := json.Unmarshal(data, &args); err != nil {
+		return err
+	}
+	*c = args
+	return nil
+}
+
+// splitCommand splits a command string into arguments on whitespace,
+// honouring single quotes, double quotes and backslash escapes
+func splitCommand(command string) ([]string, error) {
+	args := []string{}
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, r := range command {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in command: %s", command)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+// StringList is a list of strings that may also be given as a single string
+type StringList []string
+
+func (l *StringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = StringList{node.Value}
+		return nil
+	}
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*l = values
+	return nil
+}
+
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*l = StringList{value}
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*l = values
+	return nil
+}
+
+// MappingWithEquals is an environment-style mapping, given either as a map
+// or as a list of KEY=VALUE strings. A nil value is a bare KEY, which takes
+// its value from the host environment.
+type MappingWithEquals map[string]*string
+
+func (m MappingWithEquals) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, key := range sortedKeys(m) {
+		value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+		if m[key] != nil {
+			value = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: *m[key]}
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	}
+	return node, nil
+}
+
+func (m *MappingWithEquals) UnmarshalYAML(node *yaml.Node) error {
+	result := MappingWithEquals{}
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			key, value, found := strings.Cut(item.Value, "=")
+			if found {
+				result[key] = &value
+			} else {
+				result[key] = nil
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value := node.Content[i+1]
+			if value.Tag == "!!null" {
+				result[node.Content[i].Value] = nil
+				continue
+			}
+			v := value.Value
+			result[node.Content[i].Value] = &v
+		}
+	default:
+		return fmt.Errorf("line %d: expected a mapping or list of KEY=VALUE", node.Line)
+	}
+	*m = result
+	return nil
+}
+
+func (m *MappingWithEquals) UnmarshalJSON(data []byte) error {
+	var values []string
+	if err := json.Unmarshal(data, &values); err == nil {
+		result := MappingWithEquals{}
+		for _, item := range values {
+			key, value, found := strings.Cut(item, "=")
+			if found {
+				result[key] = &value
+			} else {
+				result[key] = nil
+			}
+		}
+		*m = result
+		return nil
+	}
+	var result map[string]*string
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+	*m = result
+	return nil
+}
+
+// Mapping is a string map given either as a map or a list of key=value strings
+type Mapping map[string]string
+
+func (m *Mapping) UnmarshalYAML(node *yaml.Node) error {
+	var env MappingWithEquals
+	if err := env.UnmarshalYAML(node); err != nil {
+		return err
+	}
+	result := Mapping{}
+	for key, value := range env {
+		if value != nil {
+			result[key] = *value
+		} else {
+			result[key] = ""
+		}
+	}
+	*m = result
+	return nil
+}
+
+// Labels are key=value strings in file order. They may be given as a map or a
+// list and are written back as a list.
+type Labels []string
+
+func (l *Labels) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		var values []string
+		if err := node.Decode(&values); err != nil {
+			return err
+		}
+		*l = values
+	case yaml.MappingNode:
+		result := Labels{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			result = append(result, node.Content[i].Value+"="+node.Content[i+1].Value)
+		}
+		*l = result
+	default:
+		return fmt.Errorf("line %d: expected a mapping or list of labels", node.Line)
+	}
+	return nil
+}
+
+func (l *Labels) UnmarshalJSON(data []byte) error {
+	var values []string
+	if err := json.Unmarshal(data, &values); err == nil {
+		*l = values
+		return nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	result := Labels{}
+	for _, key := range sortedKeys(m) {
+		result = append(result, key+"="+m[key])
+	}
+	*l = result
+	return nil
+}
+
+// HostsList is extra_hosts, given as a list of host:ip strings or a map
+type HostsList []string
+
+func (h *HostsList) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		var values []string
+		if err := node.Decode(&values); err != nil {
+			return err
+		}
+		*h = values
+	case yaml.MappingNode:
+		result := HostsList{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			host, value := node.Content[i].Value, node.Content[i+1]
+			var ips StringList
+			if err := ips.UnmarshalYAML(value); err != nil {
+				return err
+			}
+			for _, ip := range ips {
+				result = append(result, host+":"+ip)
+			}
+		}
+		*h = result
+	default:
+		return fmt.Errorf("line %d: expected a mapping or list of hosts", node.Line)
+	}
+	return nil
+}
+
+// EnvFiles is env_file: a single path, a list of paths, or a list of
+// {path, required, format} entries
+type EnvFiles []EnvFile
+
+type EnvFile struct {
+	Path     string `json:"path" yaml:"path"`
+	Required *bool  `json:"required,omitempty" yaml:"required,omitempty"`
+	Format   string `json:"format,omitempty" yaml:"format,omitempty"`
+}
+
+func (e *EnvFiles) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*e = EnvFiles{{Path: node.Value}}
+		return nil
+	}
+	var files []EnvFile
+	if err := node.Decode(&files); err != nil {
+		return err
+	}
+	*e = files
+	return nil
+}
+
+func (f EnvFile) MarshalYAML() (interface{}, error) {
+	if f.Required == nil && f.Format == "" {
+		return f.Path, nil
+	}
+	type plain EnvFile
+	return plain(f), nil
+}
+
+func (f *EnvFile) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*f = EnvFile{Path: node.Value}
+		return nil
+	}
+	type plain EnvFile
+	return node.Decode((*plain)(f))
+}
+
+func (f EnvFile) MarshalJSON() ([]byte, error) {
+	if f.Required == nil && f.Format == "" {
+		return json.Marshal(f.Path)
+	}
+	type plain EnvFile
+	return json.Marshal(plain(f))
+}
+
+func (f *EnvFile) UnmarshalJSON(data []byte) error {
+	var path string
+	if err := json.Unmarshal(data, &path); err == nil {
+		*f = EnvFile{Path: path}
+		return nil
+	}
+	type plain EnvFile
+	return json.Unmarshal(data, (*plain)(f))
+}
+
+// ServicePort is a published port. The short syntax
+// "[[ip:]published:]target[/protocol]" is parsed into the long-form fields
+// and used again when writing if nothing beyond it is set. Target and
+// Published are strings because the short syntax allows ranges.
+type ServicePort struct {
+	Name        string `json:"name,omitempty" yaml:"name,omitempty"`
+	Target      string `json:"target" yaml:"target"`
+	Published   string `json:"published,omitempty" yaml:"published,omitempty"`
+	HostIP      string `json:"host_ip,omitempty" yaml:"host_ip,omitempty"`
+	Protocol    string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	AppProtocol string `json:"app_protocol,omitempty" yaml:"app_protocol,omitempty"`
+	Mode        string `json:"mode,omitempty" yaml:"mode,omitempty"`
+}
+
+// parsePort parses the short port syntax
+func parsePort(spec string) (ServicePort, error) {
+	var port ServicePort
+
+	rest := spec
+	if i := strings.LastIndex(rest, "/"); i >= 0 {
+		port.Protocol = rest[i+1:]
+		rest = rest[:i]
+	}
+
+	// A bracketed IPv6 host address may contain colons of its own
+	if strings.HasPrefix(rest, "[") {
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return port, fmt.Errorf("invalid port: %s", spec)
+		}
+		port.HostIP = rest[1:end]
+		rest = strings.TrimPrefix(rest[end+1:], ":")
+		published, target, found := strings.Cut(rest, ":")
+		if !found {
+			return port, fmt.Errorf("invalid port: %s", spec)
+		}
+		port.Published, port.Target = published, target
+		return port, nil
+	}
+
+	parts := strings.Split(rest, ":")
+	switch len(parts) {
+	case 1:
+		port.Target = parts[0]
+	case 2:
+		port.Published, port.Target = parts[0], parts[1]
+	case 3:
+		port.HostIP, port.Published, port.Target = parts[0], parts[1], parts[2]
+	default:
+		return port, fmt.Errorf("invalid port: %s", spec)
+	}
+
+	if port.Target == "" {
+		return port, fmt.Errorf("invalid port: %s", spec)
+	}
+
+	return port, nil
+}
+
+// short returns the short syntax for the port, if it can be expressed that way
+func (p ServicePort) short() (string, bool) {
+	if p.Name != "" || p.AppProtocol != "" || p.Mode != "" {
+		return "", false
+	}
+
+	spec := p.Target
+	if p.Published != "" || p.HostIP != "" {
+		spec = p.Published + ":" + spec
+	}
+	if p.HostIP != "" {
+		host := p.HostIP
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		spec = host + ":" + spec
+	}
+	if p.Protocol != "" {
+		spec += "/" + p.Protocol
+	}
+	return spec, true
+}
+
+// String returns the port in short syntax where possible
+func (p ServicePort) String() string {
+	if spec, ok := p.short(); ok {
+		return spec
+	}
+	return fmt.Sprintf("%s:%s/%s (%s)", p.Published, p.Target, p.Protocol, p.Mode)
+}
+
+func (p ServicePort) MarshalYAML() (interface{}, error) {
+	if spec, ok := p.short(); ok {
+		return spec, nil
+	}
+	type plain ServicePort
+	node, err := valueNode(plain(p))
+	if err != nil {
+		return nil, err
+	}
+	numericScalars(node, "target", "published")
+	return node, nil
+}
+
+func (p *ServicePort) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		port, err := parsePort(node.Value)
+		if err != nil {
+			return err
+		}
+		*p = port
+		return nil
+	}
+	type plain ServicePort
+	return node.Decode((*plain)(p))
+}
+
+func (p ServicePort) MarshalJSON() ([]byte, error) {
+	if spec, ok := p.short(); ok {
+		return json.Marshal(spec)
+	}
+	type plain ServicePort
+	return json.Marshal(plain(p))
+}
+
+func (p *ServicePort) UnmarshalJSON(data []byte) error {
+	var spec string
+	if err := json.Unmarshal(data, &spec); err == nil {
+		port, err := parsePort(spec)
+		if err != nil {
+			return err
+		}
+		*p = port
+		return nil
+	}
+	type plain ServicePort
+	return json.Unmarshal(data, (*plain)(p))
+}
+
+// ServiceVolume is a volume or bind mount. The short syntax
+// "[source:]target[:options]" is parsed into the long-form fields and used
+// again when writing if nothing beyond it is set.
+type ServiceVolume struct {
+	Type        string         `json:"type" yaml:"type"`
+	Source      string         `json:"source,omitempty" yaml:"source,omitempty"`
+	Target      string         `json:"target" yaml:"target"`
+	ReadOnly    bool           `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+	Consistency string         `json:"consistency,omitempty" yaml:"consistency,omitempty"`
+	Bind        *VolumeBind    `json:"bind,omitempty" yaml:"bind,omitempty"`
+	Volume      *VolumeOptions `json:"volume,omitempty" yaml:"volume,omitempty"`
+	Tmpfs       *VolumeTmpfs   `json:"tmpfs,omitempty" yaml:"tmpfs,omitempty"`
+}
+
+type VolumeBind struct {
+	Propagation    string `json:"propagation,omitempty" yaml:"propagation,omitempty"`
+	CreateHostPath *bool  `json:"create_host_path,omitempty" yaml:"create_host_path,omitempty"`
+	SELinux        string `json:"selinux,omitempty" yaml:"selinux,omitempty"`
+}
+
+type VolumeOptions struct {
+	NoCopy  bool   `json:"nocopy,omitempty" yaml:"nocopy,omitempty"`
+	Subpath string `json:"subpath,omitempty" yaml:"subpath,omitempty"`
+}
+
+type VolumeTmpfs struct {
+	Size string `json:"size,omitempty" yaml:"size,omitempty"`
+	Mode string `json:"mode,omitempty" yaml:"mode,omitempty"`
+}
+
+// isHostPath reports whether a short-syntax volume source is a host path
+// rather than a named volume
+func isHostPath(source string) bool {
+	return strings.HasPrefix(source, "/") || strings.HasPrefix(source, ".") ||
+		strings.HasPrefix(source, "~") || strings.HasPrefix(source, "$")
+}
+
+// parseVolume parses the short volume syntax
+func parseVolume(spec string) (ServiceVolume, error) {
+	parts := strings.Split(spec, ":")
+	volume := ServiceVolume{Type: "volume"}
+
+	switch len(parts) {
+	case 1:
+		volume.Target = parts[0]
+	case 2:
+		volume.Source, volume.Target = parts[0], parts[1]
+	case 3:
+		volume.Source, volume.Target = parts[0], parts[1]
+		for _, option := range strings.Split(parts[2], ",") {
+			switch option {
+			case "ro":
+				volume.ReadOnly = true
+			case "rw":
+			case "z", "Z":
+				volume.bind().SELinux = option
+			case "nocopy":
+				volume.Volume = &VolumeOptions{NoCopy: true}
+			case "cached", "delegated", "consistent":
+				volume.Consistency = option
+			case "shared", "rshared", "slave", "rslave", "private", "rprivate":
+				volume.bind().Propagation = option
+			default:
+				return volume, fmt.Errorf("invalid volume option %q in %s", option, spec)
+			}
+		}
+	default:
+		return volume, fmt.Errorf("invalid volume: %s", spec)
+	}
+
+	if volume.Target == "" {
+		return volume, fmt.Errorf("invalid volume: %s", spec)
+	}
+
+	if isHostPath(volume.Source) {
+		volume.Type = "bind"
+	}
+
+	return volume, nil
+}
+
+func (v *ServiceVolume) bind() *VolumeBind {
+	if v.Bind == nil {
+		v.Bind = &VolumeBind{}
+	}
+	return v.Bind
+}
+
+// short returns the short syntax for the volume, if it can be expressed that way
+func (v ServiceVolume) short() (string, bool) {
+	if v.Tmpfs != nil || (v.Type != "bind" && v.Type != "volume") {
+		return "", false
+	}
+	// The short syntax infers the type from the source
+	if (v.Type == "bind") != isHostPath(v.Source) {
+		return "", false
+	}
+	if v.Bind != nil && v.Bind.CreateHostPath != nil {
+		return "", false
+	}
+	if v.Volume != nil && v.Volume.Subpath != "" {
+		return "", false
+	}
+
+	var options []string
+	if v.ReadOnly {
+		options = append(options, "ro")
+	}
+	if v.Bind != nil && v.Bind.SELinux != "" {
+		options = append(options, v.Bind.SELinux)
+	}
+	if v.Bind != nil && v.Bind.Propagation != "" {
+		options = append(options, v.Bind.Propagation)
+	}
+	if v.Volume != nil && v.Volume.NoCopy {
+		options = append(options, "nocopy")
+	}
+	if v.Consistency != "" {
+		options = append(options, v.Consistency)
+	}
+
+	if v.Source == "" {
+		if len(options) > 0 {
+			return "", false
+		}
+		return v.Target, true
+	}
+
+	spec := v.Source + ":" + v.Target
+	if len(options) > 0 {
+		spec += ":" + strings.Join(options, ",")
+	}
+	return spec, true
+}
+
+// String returns the volume in short syntax where possible
+func (v ServiceVolume) String() string {
+	if spec, ok := v.short(); ok {
+		return spec
+	}
+	if v.Source == "" {
+		return fmt.Sprintf("%s (%s)", v.Target, v.Type)
+	}
+	return fmt.Sprintf("%s:%s (%s)", v.Source, v.Target, v.Type)
+}
+
+func (v ServiceVolume) MarshalYAML() (interface{}, error) {
+	if spec, ok := v.short(); ok {
+		return spec, nil
+	}
+	type plain ServiceVolume
+	return plain(v), nil
+}
+
+func (v *ServiceVolume) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		volume, err := parseVolume(node.Value)
+		if err != nil {
+			return err
+		}
+		*v = volume
+		return nil
+	}
+	type plain ServiceVolume
+	return node.Decode((*plain)(v))
+}
+
+func (v ServiceVolume) MarshalJSON() ([]byte, error) {
+	if spec, ok := v.short(); ok {
+		return json.Marshal(spec)
+	}
+	type plain ServiceVolume
+	return json.Marshal(plain(v))
+}
+
+func (v *ServiceVolume) UnmarshalJSON(data []byte) error {
+	var spec string
+	if err := json.Unmarshal(data, &spec); err == nil {
+		volume, err := parseVolume(spec)
+		if err != nil {
+			return err
+		}
+		*v = volume
+		return nil
+	}
+	type plain ServiceVolume
+	return json.Unmarshal(data, (*plain)(v))
+}
+
+// ServiceFileRef is a secret or config granted to a service, given either as
+// a name or in long form
+type ServiceFileRef struct {
+	Source string `json:"source" yaml:"source"`
+	Target string `json:"target,omitempty" yaml:"target,omitempty"`
+	UID    string `json:"uid,omitempty" yaml:"uid,omitempty"`
+	GID    string `json:"gid,omitempty" yaml:"gid,omitempty"`
+	Mode   string `json:"mode,omitempty" yaml:"mode,omitempty"`
+}
+
+func (r ServiceFileRef) MarshalYAML() (interface{}, error) {
+	if r == (ServiceFileRef{Source: r.Source}) {
+		return r.Source, nil
+	}
+	type plain ServiceFileRef
+	node, err := valueNode(plain(r))
+	if err != nil {
+		return nil, err
+	}
+	numericScalars(node, "mode")
+	return node, nil
+}
+
+func (r *ServiceFileRef) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*r = ServiceFileRef{Source: node.Value}
+		return nil
+	}
+	type plain ServiceFileRef
+	return node.Decode((*plain)(r))
+}
+
+func (r ServiceFileRef) MarshalJSON() ([]byte, error) {
+	if r == (ServiceFileRef{Source: r.Source}) {
+		return json.Marshal(r.Source)
+	}
+	type plain ServiceFileRef
+	return json.Marshal(plain(r))
+}
+
+func (r *ServiceFileRef) UnmarshalJSON(data []byte) error {
+	var source string
+	if err := json.Unmarshal(data, &source); err == nil {
+		*r = ServiceFileRef{Source: source}
+		return nil
+	}
+	type plain ServiceFileRef
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+// DependsOn lists the services a service depends on, in file order. It may be
+// given as a list of names or as a map with conditions.
+type DependsOn []ServiceDependency
+
+type ServiceDependency struct {
+	Service   string `json:"service" yaml:"-"`
+	Condition string `json:"condition,omitempty" yaml:"condition,omitempty"`
+	Restart   bool   `json:"restart,omitempty" yaml:"restart,omitempty"`
+	Required  *bool  `json:"required,omitempty" yaml:"required,omitempty"`
+}
+
+func (d ServiceDependency) plain() bool {
+	return d.Condition == "" && !d.Restart && d.Required == nil
+}
+
+func (d DependsOn) MarshalYAML() (interface{}, error) {
+	long := false
+	for _, dep := range d {
+		long = long || !dep.plain()
+	}
+
+	if !long {
+		names := make([]string, 0, len(d))
+		for _, dep := range d {
+			names = append(names, dep.Service)
+		}
+		return names, nil
+	}
+
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, dep := range d {
+		if dep.Condition == "" {
+			dep.Condition = "service_started"
+		}
+		value, err := valueNode(dep)
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: dep.Service}, value)
+	}
+	return node, nil
+}
+
+func (d *DependsOn) UnmarshalYAML(node *yaml.Node) error {
+	result := DependsOn{}
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			result = append(result, ServiceDependency{Service: item.Value})
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			dep := ServiceDependency{}
+			if err := node.Content[i+1].Decode(&dep); err != nil {
+				return err
+			}
+			dep.Service = node.Content[i].Value
+			// service_started is the default, so it is equivalent to the list form
+			if dep.Condition == "service_started" {
+				dep.Condition = ""
+			}
+			result = append(result, dep)
+		}
+	default:
+		return fmt.Errorf("line %d: expected a mapping or list of services", node.Line)
+	}
+	*d = result
+	return nil
+}
+
+func (d ServiceDependency) MarshalJSON() ([]byte, error) {
+	if d.plain() {
+		return json.Marshal(d.Service)
+	}
+	type plain ServiceDependency
+	return json.Marshal(plain(d))
+}
+
+func (d *ServiceDependency) UnmarshalJSON(data []byte) error {
+	var service string
+	if err := json.Unmarshal(data, &service); err == nil {
+		*d = ServiceDependency{Service: service}
+		return nil
+	}
+	type plain ServiceDependency
+	if err := json.Unmarshal(data, (*plain)(d)); err != nil {
+		return err
+	}
+	if d.Condition == "service_started" {
+		d.Condition = ""
+	}
+	return nil
+}
+
+// ServiceNetworks lists the networks a service joins, in file order. It may
+// be given as a list of names or as a map with per-network settings.
+type ServiceNetworks []ServiceNetwork
+
+type ServiceNetwork struct {
+	Name         string            `json:"name" yaml:"-"`
+	Aliases      []string          `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	IPv4Address  string            `json:"ipv4_address,omitempty" yaml:"ipv4_address,omitempty"`
+	IPv6Address  string            `json:"ipv6_address,omitempty" yaml:"ipv6_address,omitempty"`
+	LinkLocalIPs []string          `json:"link_local_ips,omitempty" yaml:"link_local_ips,omitempty"`
+	MacAddress   string            `json:"mac_address,omitempty" yaml:"mac_address,omitempty"`
+	DriverOpts   map[string]string `json:"driver_opts,omitempty" yaml:"driver_opts,omitempty"`
+	Priority     int               `json:"priority,omitempty" yaml:"priority,omitempty"`
+	GwPriority   int               `json:"gw_priority,omitempty" yaml:"gw_priority,omitempty"`
+}
+
+func (n ServiceNetwork) plain() bool {
+	return reflect.DeepEqual(n, ServiceNetwork{Name: n.Name})
+}
+
+func (n ServiceNetworks) MarshalYAML() (interface{}, error) {
+	long := false
+	for _, network := range n {
+		long = long || !network.plain()
+	}
+
+	if !long {
+		names := make([]string, 0, len(n))
+		for _, network := range n {
+			names = append(names, network.Name)
+		}
+		return names, nil
+	}
+
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, network := range n {
+		value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+		if !network.plain() {
+			var err error
+			if value, err = valueNode(network); err != nil {
+				return nil, err
+			}
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: network.Name}, value)
+	}
+	return node, nil
+}
+
+func (n *ServiceNetworks) UnmarshalYAML(node *yaml.Node) error {
+	result := ServiceNetworks{}
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			result = append(result, ServiceNetwork{Name: item.Value})
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			network := ServiceNetwork{}
+			if node.Content[i+1].Tag != "!!null" {
+				if err := node.Content[i+1].Decode(&network); err != nil {
+					return err
+				}
+			}
+			network.Name = node.Content[i].Value
+			result = append(result, network)
+		}
+	default:
+		return fmt.Errorf("line %d: expected a mapping or list of networks", node.Line)
+	}
+	*n = result
+	return nil
+}
+
+func (n ServiceNetwork) MarshalJSON() ([]byte, error) {
+	if n.plain() {
+		return json.Marshal(n.Name)
+	}
+	type plain ServiceNetwork
+	return json.Marshal(plain(n))
+}
+
+func (n *ServiceNetwork) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*n = ServiceNetwork{Name: name}
+		return nil
+	}
+	type plain ServiceNetwork
+	return json.Unmarshal(data, (*plain)(n))
+}
+
+// Healthcheck configures a container health check. A string test is run
+// through the shell and is stored as ["CMD-SHELL", test].
+type Healthcheck struct {
+	Test          HealthcheckTest `json:"test,omitempty" yaml:"test,omitempty"`
+	Interval      string          `json:"interval,omitempty" yaml:"interval,omitempty"`
+	Timeout       string          `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Retries       *int            `json:"retries,omitempty" yaml:"retries,omitempty"`
+	StartPeriod   string          `json:"start_period,omitempty" yaml:"start_period,omitempty"`
+	StartInterval string          `json:"start_interval,omitempty" yaml:"start_interval,omitempty"`
+	Disable       bool            `json:"disable,omitempty" yaml:"disable,omitempty"`
+}
+
+type HealthcheckTest []string
+
+func (t HealthcheckTest) MarshalYAML() (interface{}, error) {
+	return ShellCommand(t).MarshalYAML()
+}
+
+func (t *HealthcheckTest) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*t = HealthcheckTest{"CMD-SHELL", node.Value}
+		return nil
+	}
+	var test []string
+	if err := node.Decode(&test); err != nil {
+		return err
+	}
+	*t = test
+	return nil
+}
+
+func (t *HealthcheckTest) UnmarshalJSON(data []byte) error {
+	var command string
+	if err := json.Unmarshal(data, &command); err == nil {
+		*t = HealthcheckTest{"CMD-SHELL", command}
+		return nil
+	}
+	var test []string
+	if err := json.Unmarshal(data, &test); err != nil {
+		return err
+	}
+	*t = test
+	return nil
+}
+
+// LoggingConfig selects the logging driver for a service
+type LoggingConfig struct {
+	Driver  string  `json:"driver,omitempty" yaml:"driver,omitempty"`
+	Options Mapping `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// Ulimit is either a single limit or a soft/hard pair
+type Ulimit struct {
+	Single int `json:"single,omitempty" yaml:"-"`
+	Soft   int `json:"soft,omitempty" yaml:"soft,omitempty"`
+	Hard   int `json:"hard,omitempty" yaml:"hard,omitempty"`
+}
+
+func (u Ulimit) MarshalYAML() (interface{}, error) {
+	if u.Single != 0 {
+		return u.Single, nil
+	}
+	type plain Ulimit
+	return plain(u), nil
+}
+
+func (u *Ulimit) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var single int
+		if err := node.Decode(&single); err != nil {
+			return err
+		}
+		*u = Ulimit{Single: single}
+		return nil
+	}
+	type plain Ulimit
+	return node.Decode((*plain)(u))
+}
+
+// DeployConfig is the deploy section of a service
+type DeployConfig struct {
+	Mode           string         `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Replicas       *int           `json:"replicas,omitempty" yaml:"replicas,omitempty"`
+	Labels         Labels         `json:"labels,omitempty" yaml:"labels,omitempty"`
+	EndpointMode   string         `json:"endpoint_mode,omitempty" yaml:"endpoint_mode,omitempty"`
+	Resources      *Resources     `json:"resources,omitempty" yaml:"resources,omitempty"`
+	RestartPolicy  *RestartPolicy `json:"restart_policy,omitempty" yaml:"restart_policy,omitempty"`
+	UpdateConfig   *UpdateConfig  `json:"update_config,omitempty" yaml:"update_config,omitempty"`
+	RollbackConfig *UpdateConfig  `json:"rollback_config,omitempty" yaml:"rollback_config,omitempty"`
+	Placement      *Placement     `json:"placement,omitempty" yaml:"placement,omitempty"`
+}
+
+type Resources struct {
+	Limits       *ResourceSpec `json:"limits,omitempty" yaml:"limits,omitempty"`
+	Reservations *ResourceSpec `json:"reservations,omitempty" yaml:"reservations,omitempty"`
+}
+
+type ResourceSpec struct {
+	Cpus    string          `json:"cpus,omitempty" yaml:"cpus,omitempty"`
+	Memory  string          `json:"memory,omitempty" yaml:"memory,omitempty"`
+	Pids    int64           `json:"pids,omitempty" yaml:"pids,omitempty"`
+	Devices []DeviceRequest `json:"devices,omitempty" yaml:"devices,omitempty"`
+}
+
+type DeviceRequest struct {
+	Capabilities []string          `json:"capabilities,omitempty" yaml:"capabilities,omitempty"`
+	Driver       string            `json:"driver,omitempty" yaml:"driver,omitempty"`
+	Count        string            `json:"count,omitempty" yaml:"count,omitempty"`
+	DeviceIDs    []string          `json:"device_ids,omitempty" yaml:"device_ids,omitempty"`
+	Options      map[string]string `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+type RestartPolicy struct {
+	Condition   string `json:"condition,omitempty" yaml:"condition,omitempty"`
+	Delay       string `json:"delay,omitempty" yaml:"delay,omitempty"`
+	MaxAttempts *int   `json:"max_attempts,omitempty" yaml:"max_attempts,omitempty"`
+	Window      string `json:"window,omitempty" yaml:"window,omitempty"`
+}
+
+type UpdateConfig struct {
+	Parallelism     *int   `json:"parallelism,omitempty" yaml:"parallelism,omitempty"`
+	Delay           string `json:"delay,omitempty" yaml:"delay,omitempty"`
+	FailureAction   string `json:"failure_action,omitempty" yaml:"failure_action,omitempty"`
+	Monitor         string `json:"monitor,omitempty" yaml:"monitor,omitempty"`
+	MaxFailureRatio string `json:"max_failure_ratio,omitempty" yaml:"max_failure_ratio,omitempty"`
+	Order           string `json:"order,omitempty" yaml:"order,omitempty"`
+}
+
+type Placement struct {
+	Constraints        []string              `json:"constraints,omitempty" yaml:"constraints,omitempty"`
+	Preferences        []PlacementPreference `json:"preferences,omitempty" yaml:"preferences,omitempty"`
+	MaxReplicasPerNode int                   `json:"max_replicas_per_node,omitempty" yaml:"max_replicas_per_node,omitempty"`
+}
+
+type PlacementPreference struct {
+	Spread string `json:"spread" yaml:"spread"`
+}
+
+// numericScalars drops the string tag from the named keys of a mapping when
+// their values are numbers, so e.g. "target: 80" isn't written as "80"
+func numericScalars(node *yaml.Node, keys ...string) {
+	for _, key := range keys {
+		value := mappingValue(node, key)
+		if value == nil {
+			continue
+		}
+		if _, err := strconv.Atoi(value.Value); err == nil {
+			value.Tag = "!!int"
+			value.Style = 0
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,256 @@
+package projects
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestReadComposeServices_LongForms(t *testing.T) {
+	svc, _ := newTestProject(t, "longform.yml")
+
+	services, err := svc.readComposeServices("site")
+	if err != nil {
+		t.Fatalf("readComposeServices failed: %v", err)
+	}
+	if len(services) != 1 {
+		t.Fatalf("expected 1 service, got %d", len(services))
+	}
+	api := services[0]
+
+	if want := (BuildConfig{Context: "./api", Dockerfile: "Dockerfile.prod", Target: "runtime", Args: MappingWithEquals{"GO_VERSION": strPtr("1.24")}}); !reflect.DeepEqual(api.Build, want) {
+		t.Errorf("build = %+v, want %+v", api.Build, want)
+	}
+	if want := (ShellCommand{"./api", "--listen", ":8080"}); !reflect.DeepEqual(api.Command, want) {
+		t.Errorf("command = %q, want %q", api.Command, want)
+	}
+	if want := (ShellCommand{"/docker-entrypoint.sh"}); !reflect.DeepEqual(api.Entrypoint, want) {
+		t.Errorf("entrypoint = %q, want %q", api.Entrypoint, want)
+	}
+	if want := (MappingWithEquals{"LOG_LEVEL": strPtr("debug"), "API_TOKEN": nil}); !reflect.DeepEqual(api.Environment, want) {
+		t.Errorf("environment = %v, want %v", api.Environment, want)
+	}
+
+	wantPorts := []ServicePort{
+		{Target: "8080", Published: "8080", Protocol: "tcp", Mode: "host"},
+		{Target: "9090", Published: "9090", HostIP: "127.0.0.1", Protocol: "udp"},
+	}
+	if !reflect.DeepEqual(api.Ports, wantPorts) {
+		t.Errorf("ports = %+v, want %+v", api.Ports, wantPorts)
+	}
+
+	wantVolumes := []ServiceVolume{
+		{Type: "bind", Source: "./config", Target: "/etc/api", ReadOnly: true},
+		{Type: "volume", Source: "data", Target: "/var/lib/api", Volume: &VolumeOptions{NoCopy: true}},
+	}
+	if !reflect.DeepEqual(api.Volumes, wantVolumes) {
+		t.Errorf("volumes = %+v, want %+v", api.Volumes, wantVolumes)
+	}
+
+	wantDeps := DependsOn{
+		{Service: "db", Condition: "service_healthy", Restart: true},
+		{Service: "cache"},
+	}
+	if !reflect.DeepEqual(api.DependsOn, wantDeps) {
+		t.Errorf("depends_on = %+v, want %+v", api.DependsOn, wantDeps)
+	}
+
+	wantNetworks := ServiceNetworks{
+		{Name: "backend", Aliases: []string{"api.internal"}},
+		{Name: "hubble"},
+	}
+	if !reflect.DeepEqual(api.Networks, wantNetworks) {
+		t.Errorf("networks = %+v, want %+v", api.Networks, wantNetworks)
+	}
+
+	if api.Healthcheck == nil || !reflect.DeepEqual(api.Healthcheck.Test, HealthcheckTest{"CMD-SHELL", "curl -f http://localhost:8080/health"}) {
+		t.Errorf("healthcheck = %+v", api.Healthcheck)
+	}
+	if api.Deploy == nil || api.Deploy.Resources.Limits.Cpus != "0.5" || api.Deploy.Resources.Limits.Memory != "512M" {
+		t.Errorf("deploy = %+v", api.Deploy)
+	}
+	if want := (map[string]Ulimit{"nofile": {Soft: 20000, Hard: 40000}, "nproc": {Single: 65535}}); !reflect.DeepEqual(api.Ulimits, want) {
+		t.Errorf("ulimits = %+v, want %+v", api.Ulimits, want)
+	}
+	if want := (Labels{"com.example.team=platform"}); !reflect.DeepEqual(api.Labels, want) {
+		t.Errorf("labels = %v, want %v", api.Labels, want)
+	}
+	if api.Extensions["x-hubble-owner"] != "platform-team" {
+		t.Errorf("extensions = %v", api.Extensions)
+	}
+}
+
+func TestUpdateService_EquivalentFormsLeaveFileUntouched(t *testing.T) {
+	svc, composePath := newTestProject(t, "longform.yml")
+
+	before, err := os.ReadFile(composePath)
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+
+	// What the web UI sends back when saving without changes
+	var service ComposeService
+	body := `{
+		"name": "api",
+		"image": "",
+		"build": "./api",
+		"ports": ["8080:8080/tcp", "127.0.0.1:9090:9090/udp"],
+		"environment": {"LOG_LEVEL": "debug", "API_TOKEN": null},
+		"volumes": ["./config:/etc/api:ro", "data:/var/lib/api:nocopy"],
+		"labels": ["com.example.team=platform"],
+		"depends_on": [{"service": "db", "condition": "service_healthy", "restart": true}, "cache"],
+		"networks": [{"name": "backend", "aliases": ["api.internal"]}, "hubble"],
+		"restart": "",
+		"command": "./api --listen :8080"
+	}`
+	if err := json.Unmarshal([]byte(body), &service); err != nil {
+		t.Fatalf("Failed to decode service: %v", err)
+	}
+	// The long-form port and build are not expressible in the UI's short
+	// syntax; send what the API returned for them instead
+	services, err := svc.readComposeServices("site")
+	if err != nil {
+		t.Fatalf("readComposeServices failed: %v", err)
+	}
+	service.Ports = services[0].Ports
+	service.Build = services[0].Build
+
+	if err := svc.UpdateService(context.Background(), "site", service); err != nil {
+		t.Fatalf("UpdateService failed: %v", err)
+	}
+
+	after, err := os.ReadFile(composePath)
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+	if string(before) != string(after) {
+		t.Errorf("equivalent update changed the file\n--- got ---\n%s\n--- want ---\n%s", after, before)
+	}
+}
+
+func TestUpdateService_PartialUpdate(t *testing.T) {
+	svc, composePath := newTestProject(t, "longform.yml")
+
+	var service ComposeService
+	body := `{"name": "api", "command": ["./api", "--listen", ":9000"], "user": ""}`
+	if err := json.Unmarshal([]byte(body), &service); err != nil {
+		t.Fatalf("Failed to decode service: %v", err)
+	}
+
+	if err := svc.UpdateService(context.Background(), "site", service); err != nil {
+		t.Fatalf("UpdateService failed: %v", err)
+	}
+
+	checkGolden(t, composePath, "longform.partial_update.golden")
+}
+
+func TestUpdateService_Alias(t *testing.T) {
+	svc, _ := newTestProject(t, "alias.yml")
+
+	var service ComposeService
+	if err := json.Unmarshal([]byte(`{"name": "web", "image": "nginx:1.28"}`), &service); err != nil {
+		t.Fatalf("Failed to decode service: %v", err)
+	}
+
+	if err := svc.UpdateService(context.Background(), "site", service); err != nil {
+		t.Fatalf("UpdateService failed: %v", err)
+	}
+
+	services, err := svc.readComposeServices("site")
+	if err != nil {
+		t.Fatalf("readComposeServices failed: %v", err)
+	}
+	images := make(map[string]string)
+	for _, s := range services {
+		images[s.Name] = s.Image + " " + s.Restart
+	}
+	// web keeps the rest of the anchored config; admin still uses the anchor
+	if images["web"] != "nginx:1.28 unless-stopped" || images["admin"] != "nginx:1.27 unless-stopped" {
+		t.Errorf("unexpected services after update: %v", images)
+	}
+}
+
+func TestComposeService_JSONRoundTrip(t *testing.T) {
+	svc, _ := newTestProject(t, "longform.yml")
+
+	services, err := svc.readComposeServices("site")
+	if err != nil {
+		t.Fatalf("readComposeServices failed: %v", err)
+	}
+
+	data, err := json.Marshal(services[0])
+	if err != nil {
+		t.Fatalf("Failed to encode service: %v", err)
+	}
+
+	var decoded ComposeService
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to decode service: %v", err)
+	}
+	decoded.fields = nil
+
+	if !reflect.DeepEqual(decoded, services[0]) {
+		t.Errorf("JSON round trip mismatch\n got: %+v\nwant: %+v", decoded, services[0])
+	}
+}
+
+func TestParsePort(t *testing.T) {
+	tests := []struct {
+		spec string
+		want ServicePort
+	}{
+		{"80", ServicePort{Target: "80"}},
+		{"8080:80", ServicePort{Published: "8080", Target: "80"}},
+		{"127.0.0.1:8080:80/udp", ServicePort{HostIP: "127.0.0.1", Published: "8080", Target: "80", Protocol: "udp"}},
+		{"127.0.0.1::80", ServicePort{HostIP: "127.0.0.1", Target: "80"}},
+		{"[::1]:8080:80", ServicePort{HostIP: "::1", Published: "8080", Target: "80"}},
+		{"3000-3005:3000-3005", ServicePort{Published: "3000-3005", Target: "3000-3005"}},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePort(tt.spec)
+		if err != nil {
+			t.Errorf("parsePort(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parsePort(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+		if short, ok := got.short(); !ok || short != tt.spec {
+			t.Errorf("short(%q) = %q, %v", tt.spec, short, ok)
+		}
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"npm start", []string{"npm", "start"}},
+		{`sh -c "echo hello world"`, []string{"sh", "-c", "echo hello world"}},
+		{`echo 'a b' c\ d`, []string{"echo", "a b", "c d"}},
+		{"  ", []string{}},
+	}
+
+	for _, tt := range tests {
+		got, err := splitCommand(tt.command)
+		if err != nil {
+			t.Errorf("splitCommand(%q) failed: %v", tt.command, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCommand(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+
+	if _, err := splitCommand(`echo "unterminated`); err == nil {
+		t.Error("expected error for unterminated quote")
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
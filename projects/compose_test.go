@@ -0,0 +1,148 @@
+package projects
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+// newTestProject copies a compose fixture from testdata into a fresh project
+// directory and returns a Service rooted at its parent
+func newTestProject(t *testing.T, fixture string) (*Service, string) {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "site"), 0o755); err != nil {
+		t.Fatalf("Failed to create project directory: %v", err)
+	}
+	composePath := filepath.Join(root, "site", "docker-compose.yml")
+	if err := os.WriteFile(composePath, content, 0o644); err != nil {
+		t.Fatalf("Failed to write compose file: %v", err)
+	}
+
+	return &Service{rootPath: root}, composePath
+}
+
+// checkGolden compares the compose file at path against testdata/golden
+func checkGolden(t *testing.T, path, golden string) {
+	t.Helper()
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+
+	goldenPath := filepath.Join("testdata", golden)
+	if *updateGolden {
+		if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+			t.Fatalf("Failed to update golden file: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("Failed to read golden file: %v", err)
+	}
+
+	if string(got) != string(want) {
+		t.Errorf("compose file does not match %s\n--- got ---\n%s\n--- want ---\n%s", golden, got, want)
+	}
+}
+
+func TestAddService_PreservesComposeFile(t *testing.T) {
+	svc, composePath := newTestProject(t, "commented.yml")
+
+	err := svc.AddService(context.Background(), "site", ComposeService{
+		Name:     "cache",
+		Image:    "redis:7",
+		Restart:  "unless-stopped",
+		Networks: ServiceNetworks{{Name: "backend"}},
+	})
+	if err != nil {
+		t.Fatalf("AddService failed: %v", err)
+	}
+
+	checkGolden(t, composePath, "commented.add_service.golden")
+}
+
+func TestUpdateNetwork_PreservesComposeFile(t *testing.T) {
+	svc, composePath := newTestProject(t, "commented.yml")
+
+	err := svc.UpdateNetwork(context.Background(), "site", NetworkConfig{
+		Name:   "backend",
+		Driver: "overlay",
+		Config: map[string]interface{}{"attachable": true},
+	})
+	if err != nil {
+		t.Fatalf("UpdateNetwork failed: %v", err)
+	}
+
+	checkGolden(t, composePath, "commented.update_network.golden")
+}
+
+func TestUpdateService_KeepsUnmanagedKeys(t *testing.T) {
+	svc, composePath := newTestProject(t, "commented.yml")
+
+	var service ComposeService
+	body := `{"name": "web", "image": "nginx:1.27", "restart": "unless-stopped", "ports": ["8080:80"], "networks": ["hubble", "backend"]}`
+	if err := json.Unmarshal([]byte(body), &service); err != nil {
+		t.Fatalf("Failed to decode service: %v", err)
+	}
+
+	err := svc.UpdateService(context.Background(), "site", service)
+	if err != nil {
+		t.Fatalf("UpdateService failed: %v", err)
+	}
+
+	checkGolden(t, composePath, "commented.update_service.golden")
+}
+
+func TestUpdateComposeFile_NoopIsLossless(t *testing.T) {
+	svc, composePath := newTestProject(t, "commented.yml")
+
+	before, err := os.ReadFile(composePath)
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+
+	err = svc.UpdateNetwork(context.Background(), "site", NetworkConfig{
+		Name:     "hubble",
+		External: true,
+	})
+	if err != nil {
+		t.Fatalf("UpdateNetwork failed: %v", err)
+	}
+
+	after, err := os.ReadFile(composePath)
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+
+	if string(before) != string(after) {
+		t.Errorf("no-op update changed the file\n--- got ---\n%s\n--- want ---\n%s", after, before)
+	}
+}
+
+func TestAddService_NewProject(t *testing.T) {
+	svc := &Service{rootPath: t.TempDir()}
+	ctx := context.Background()
+
+	if err := svc.CreateProject(ctx, "site"); err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	if err := svc.AddService(ctx, "site", ComposeService{Name: "web", Image: "nginx"}); err != nil {
+		t.Fatalf("AddService failed: %v", err)
+	}
+
+	checkGolden(t, filepath.Join(svc.rootPath, "site", "docker-compose.yml"), "new_project.add_service.golden")
+}
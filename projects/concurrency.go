@@ -0,0 +1,139 @@
+package projects
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type ifMatchContextKey struct{}
+
+type etagContextKey struct{}
+
+// WithIfMatch returns a context whose compose writes only go ahead if the
+// compose file still matches ifMatch, the value of an If-Match header. An
+// empty value, from a request without the header, refuses every write to
+// an existing file. Without WithIfMatch, writes are unconditional.
+func WithIfMatch(ctx context.Context, ifMatch string) context.Context {
+	return context.WithValue(ctx, ifMatchContextKey{}, ifMatch)
+}
+
+func ifMatchFromContext(ctx context.Context) (string, bool) {
+	ifMatch, ok := ctx.Value(ifMatchContextKey{}).(string)
+	return ifMatch, ok
+}
+
+// WithETagFunc returns a context whose compose writes call fn with the
+// entity tag of the compose file they leave on disk, so a response can
+// carry the ETag of exactly the content that was written
+func WithETagFunc(ctx context.Context, fn func(etag string)) context.Context {
+	return context.WithValue(ctx, etagContextKey{}, fn)
+}
+
+func reportETag(ctx context.Context, content []byte) {
+	if fn, ok := ctx.Value(etagContextKey{}).(func(string)); ok {
+		fn(ComposeETag(content))
+	}
+}
+
+// ComposeETag returns the entity tag of a compose file's content
+func ComposeETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// GetComposeETag returns the entity tag of a project's compose file as it is
+// on disk now
+func (s *Service) GetComposeETag(ctx context.Context, projectName string) (string, error) {
+	composeFilePath, err := s.findComposeFile(projectName)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(composeFilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read compose file: %w", err)
+	}
+
+	return ComposeETag(content), nil
+}
+
+// checkIfMatch verifies the If-Match precondition carried by ctx against the
+// current compose file content
+func checkIfMatch(ctx context.Context, projectName string, current []byte) error {
+	ifMatch, ok := ifMatchFromContext(ctx)
+	if !ok || etagMatches(ifMatch, ComposeETag(current)) {
+		return nil
+	}
+	if ifMatch == "" {
+		return fmt.Errorf("compose file precondition required: %s", projectName)
+	}
+	return fmt.Errorf("compose file has been modified: %s", projectName)
+}
+
+// etagMatches reports whether an If-Match header value matches etag. Weak
+// validators are compared by their opaque tag.
+func etagMatches(ifMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// lockProject serializes compose file mutations within a project and
+// returns the function that releases the lock
+func (s *Service) lockProject(projectName string) func() {
+	lock, _ := s.locks.LoadOrStore(projectName, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never observe a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	// Keep the permissions of the file being replaced
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
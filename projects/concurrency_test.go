@@ -0,0 +1,111 @@
+package projects
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestAddService_ConcurrentWritesAreSerialized(t *testing.T) {
+	svc, _ := newTestProject(t, "commented.yml")
+	ctx := context.Background()
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- svc.AddService(ctx, "site", ComposeService{Name: fmt.Sprintf("worker%d", i), Image: "busybox"})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("AddService failed: %v", err)
+		}
+	}
+
+	services, err := svc.readComposeServices("site")
+	if err != nil {
+		t.Fatalf("Failed to read services: %v", err)
+	}
+	// commented.yml defines web and db
+	if len(services) != n+2 {
+		t.Errorf("got %d services, want %d: concurrent writes were lost", len(services), n+2)
+	}
+}
+
+func TestUpdateComposeFile_IfMatch(t *testing.T) {
+	svc, composePath := newTestProject(t, "commented.yml")
+
+	etag, err := svc.GetComposeETag(context.Background(), "site")
+	if err != nil {
+		t.Fatalf("GetComposeETag failed: %v", err)
+	}
+
+	// A write with the current ETag goes ahead
+	ctx := WithIfMatch(context.Background(), etag)
+	if err := svc.DeleteService(ctx, "site", "db"); err != nil {
+		t.Fatalf("DeleteService with matching If-Match failed: %v", err)
+	}
+
+	before, err := os.ReadFile(composePath)
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+
+	// Reusing the now stale ETag is refused and leaves the file alone
+	err = svc.DeleteService(ctx, "site", "web")
+	if err == nil || err.Error() != "compose file has been modified: site" {
+		t.Fatalf("expected precondition error, got %v", err)
+	}
+
+	after, err := os.ReadFile(composePath)
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+	if string(before) != string(after) {
+		t.Errorf("refused write changed the compose file")
+	}
+
+	// A request without If-Match is refused as well
+	err = svc.DeleteService(WithIfMatch(context.Background(), ""), "site", "web")
+	if err == nil || err.Error() != "compose file precondition required: site" {
+		t.Fatalf("expected precondition required error, got %v", err)
+	}
+
+	var written string
+	ctx = WithETagFunc(WithIfMatch(context.Background(), "*"), func(etag string) { written = etag })
+	if err := svc.DeleteService(ctx, "site", "web"); err != nil {
+		t.Fatalf("DeleteService with If-Match * failed: %v", err)
+	}
+	if current, err := svc.GetComposeETag(context.Background(), "site"); err != nil || written != current {
+		t.Errorf("reported ETag %s, want %s (%v)", written, current, err)
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		ifMatch string
+		want    bool
+	}{
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"xyz", "abc"`, true},
+		{`*`, true},
+		{`"xyz"`, false},
+		{`abc`, false},
+	}
+
+	for _, tt := range tests {
+		if got := etagMatches(tt.ifMatch, `"abc"`); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.ifMatch, got, tt.want)
+		}
+	}
+}
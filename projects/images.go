@@ -0,0 +1,52 @@
+package projects
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// ImageReference is the image a project's compose service runs, as
+// written in the compose file
+type ImageReference struct {
+	Project string `json:"project"`
+	Service string `json:"service"`
+	Image   string `json:"image"`
+}
+
+// ImageReferences returns the image of every service that sets one, across
+// all projects. A compose file that cannot be read fails the whole call,
+// so callers deciding what is safe to delete never miss a reference.
+func (s *Service) ImageReferences(ctx context.Context) ([]ImageReference, error) {
+	entries, err := os.ReadDir(s.rootPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read projects directory: %w", err)
+	}
+
+	references := []ImageReference{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := s.findComposeFile(entry.Name()); err != nil {
+			continue
+		}
+
+		services, err := s.readComposeServices(entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read services of %s: %w", entry.Name(), err)
+		}
+		for _, service := range services {
+			if service.Image == "" {
+				continue
+			}
+			references = append(references, ImageReference{
+				Project: entry.Name(),
+				Service: service.Name,
+				Image:   service.Image,
+			})
+		}
+	}
+
+	return references, nil
+}
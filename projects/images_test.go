@@ -0,0 +1,37 @@
+package projects
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImageReferences(t *testing.T) {
+	svc, _ := newTestProject(t, "commented.yml")
+	ctx := t.Context()
+
+	// Directories without a compose file are not projects
+	if err := os.MkdirAll(filepath.Join(svc.rootPath, ".hubble", "builds"), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	references, err := svc.ImageReferences(ctx)
+	if err != nil {
+		t.Fatalf("ImageReferences failed: %v", err)
+	}
+	if fmt.Sprint(references) != "[{site web nginx:1.25} {site db postgres:16}]" {
+		t.Errorf("unexpected references %v", references)
+	}
+
+	if err := os.MkdirAll(filepath.Join(svc.rootPath, "broken"), 0o755); err != nil {
+		t.Fatalf("Failed to create project directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(svc.rootPath, "broken", "docker-compose.yml"), []byte("services: [\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write compose file: %v", err)
+	}
+	if _, err := svc.ImageReferences(ctx); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("expected an unreadable compose file to fail, got %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package projects
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+)
+
+// UpOptions controls how a project is brought up
+type UpOptions struct {
+	// Services limits the operation to these services; empty means all
+	Services      []string `json:"services,omitempty"`
+	Pull          string   `json:"pull,omitempty"` // always, missing or never
+	Build         bool     `json:"build,omitempty"`
+	ForceRecreate bool     `json:"force_recreate,omitempty"`
+	RemoveOrphans bool     `json:"remove_orphans,omitempty"`
+}
+
+// Validate checks the options before anything is run
+func (o UpOptions) Validate() error {
+	switch o.Pull {
+	case "", "always", "missing", "never":
+		return nil
+	default:
+		return fmt.Errorf("invalid pull policy: %s", o.Pull)
+	}
+}
+
+// DownOptions controls how a project is taken down
+type DownOptions struct {
+	RemoveVolumes bool `json:"remove_volumes,omitempty"`
+	RemoveOrphans bool `json:"remove_orphans,omitempty"`
+}
+
+// UpProject creates and starts the project's containers with
+// `docker compose up -d`, recreating those whose configuration changed
+func (s *Service) UpProject(ctx context.Context, projectName string, opts UpOptions, output io.Writer) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	args := []string{"up", "-d"}
+
+	if opts.Pull != "" {
+		args = append(args, "--pull", opts.Pull)
+	}
+
+	if opts.Build {
+		args = append(args, "--build")
+	}
+	if opts.ForceRecreate {
+		args = append(args, "--force-recreate")
+	}
+	if opts.RemoveOrphans {
+		args = append(args, "--remove-orphans")
+	}
+
+	if err := s.runCompose(ctx, projectName, output, withServices(args, opts.Services)...); err != nil {
+		return fmt.Errorf("failed to start project with docker compose: %w", err)
+	}
+
+	return nil
+}
+
+// DownProject stops and removes the project's containers and networks with
+// `docker compose down`, optionally removing its named volumes as well
+func (s *Service) DownProject(ctx context.Context, projectName string, opts DownOptions, output io.Writer) error {
+	args := []string{"down"}
+
+	if opts.RemoveVolumes {
+		args = append(args, "--volumes")
+	}
+	if opts.RemoveOrphans {
+		args = append(args, "--remove-orphans")
+	}
+
+	if err := s.runCompose(ctx, projectName, output, args...); err != nil {
+		return fmt.Errorf("failed to stop project with docker compose: %w", err)
+	}
+
+	return nil
+}
+
+// RestartProject restarts the project's containers with `docker compose restart`
+func (s *Service) RestartProject(ctx context.Context, projectName string, services []string, output io.Writer) error {
+	if err := s.runCompose(ctx, projectName, output, withServices([]string{"restart"}, services)...); err != nil {
+		return fmt.Errorf("failed to restart project with docker compose: %w", err)
+	}
+
+	return nil
+}
+
+// PullProject pulls the images of the project's services with
+// `docker compose pull` without touching running containers
+func (s *Service) PullProject(ctx context.Context, projectName string, services []string, output io.Writer) error {
+	if err := s.runCompose(ctx, projectName, output, withServices([]string{"pull"}, services)...); err != nil {
+		return fmt.Errorf("failed to pull project images with docker compose: %w", err)
+	}
+
+	return nil
+}
+
+// ValidateProject checks that a project exists and has a compose file
+func (s *Service) ValidateProject(projectName string) error {
+	_, err := s.findComposeFile(projectName)
+	return err
+}
+
+// runCompose runs a docker compose subcommand in the project directory,
+// writing its combined stdout and stderr to output (which may be nil). The
+// process is killed when ctx is cancelled.
+func (s *Service) runCompose(ctx context.Context, projectName string, output io.Writer, args ...string) error {
+	cmd, err := s.composeCommand(ctx, projectName, args...)
+	if err != nil {
+		return err
+	}
+
+	// Keep the tail of the output for the error message
+	var tail tailBuffer
+	var w io.Writer = &tail
+	if output != nil {
+		w = io.MultiWriter(&tail, output)
+	}
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w (output: %s)", err, tail.String())
+	}
+
+	return nil
+}
+
+// composeOutput runs a docker compose command and returns its standard
+// output, keeping warnings on standard error out of it
+func (s *Service) composeOutput(ctx context.Context, projectName string, args ...string) (string, error) {
+	cmd, err := s.composeCommand(ctx, projectName, args...)
+	if err != nil {
+		return "", err
+	}
+
+	var stdout bytes.Buffer
+	var stderr tailBuffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%w (output: %s)", err, stderr.String())
+	}
+
+	return stdout.String(), nil
+}
+
+func (s *Service) composeCommand(ctx context.Context, projectName string, args ...string) (*exec.Cmd, error) {
+	composeFilePath, err := s.findComposeFile(projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Using "docker compose" (modern plugin) instead of "docker-compose" (legacy)
+	cmdArgs := append([]string{"compose", "-f", filepath.Base(composeFilePath), "-p", projectName}, args...)
+	cmd := exec.CommandContext(ctx, "docker", cmdArgs...)
+	cmd.Dir = filepath.Dir(composeFilePath)
+	return cmd, nil
+}
+
+// tailBufferSize bounds how much compose output ends up in error messages
+const tailBufferSize = 4096
+
+// tailBuffer keeps the last tailBufferSize bytes written to it
+type tailBuffer struct {
+	buf []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > tailBufferSize {
+		b.buf = b.buf[len(b.buf)-tailBufferSize:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	return string(b.buf)
+}
+
+// withServices appends service names after "--" so they are never parsed
+// as flags
+func withServices(args, services []string) []string {
+	if len(services) == 0 {
+		return args
+	}
+	return append(append(args, "--"), services...)
+}
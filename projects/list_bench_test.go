@@ -0,0 +1,171 @@
+package projects
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/noel-vega/hubble/docker"
+)
+
+const benchProjects = 100
+
+// fakeDaemon serves the parts of the Docker API that listing projects and
+// seeding the container cache use, for a fixed set of containers
+type fakeDaemon struct {
+	containers []container.Summary
+}
+
+var versionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+
+func (d *fakeDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := versionPrefix.ReplaceAllString(r.URL.Path, "")
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case path == "/_ping":
+		w.Header().Set("API-Version", "1.47")
+		fmt.Fprint(w, "OK")
+	case path == "/events":
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	case path == "/containers/json":
+		args, err := filters.FromJSON(r.URL.Query().Get("filters"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result := make([]container.Summary, 0)
+		for _, c := range d.containers {
+			if args.MatchKVList("label", c.Labels) {
+				result = append(result, c)
+			}
+		}
+		json.NewEncoder(w).Encode(result)
+	case strings.HasPrefix(path, "/containers/") && strings.HasSuffix(path, "/json"):
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "/containers/"), "/json")
+		for _, c := range d.containers {
+			if strings.HasPrefix(c.ID, id) {
+				json.NewEncoder(w).Encode(container.InspectResponse{
+					ContainerJSONBase: &container.ContainerJSONBase{
+						ID:      c.ID,
+						Name:    c.Names[0],
+						Created: time.Unix(c.Created, 0).Format(time.RFC3339Nano),
+						State: &container.State{
+							Status:    c.State,
+							StartedAt: time.Unix(c.Created, 0).Format(time.RFC3339Nano),
+						},
+					},
+					Config:          &container.Config{Image: c.Image, Labels: c.Labels},
+					NetworkSettings: &container.NetworkSettings{},
+				})
+				return
+			}
+		}
+		http.Error(w, `{"message":"No such container"}`, http.StatusNotFound)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newBenchProjects creates benchProjects projects with a web and db service
+// each, and a fake Docker daemon running their containers
+func newBenchProjects(b *testing.B) (string, *docker.Service) {
+	b.Helper()
+
+	root := b.TempDir()
+	compose := []byte("services:\n  web:\n    image: nginx\n  db:\n    image: postgres\n")
+	daemon := &fakeDaemon{}
+	for i := range benchProjects {
+		name := fmt.Sprintf("project-%03d", i)
+		if err := os.MkdirAll(filepath.Join(root, name), 0o755); err != nil {
+			b.Fatalf("Failed to create project directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name, "docker-compose.yml"), compose, 0o644); err != nil {
+			b.Fatalf("Failed to write compose file: %v", err)
+		}
+
+		for j, service := range []string{"web", "db"} {
+			daemon.containers = append(daemon.containers, container.Summary{
+				ID:      fmt.Sprintf("%062x%02d", i, j),
+				Names:   []string{fmt.Sprintf("/%s-%s-1", name, service)},
+				Image:   "nginx",
+				State:   "running",
+				Status:  "Up 1 hour",
+				Created: time.Now().Add(-time.Hour).Unix(),
+				Labels: map[string]string{
+					"com.docker.compose.project": name,
+					"com.docker.compose.service": service,
+				},
+			})
+		}
+	}
+
+	server := httptest.NewServer(daemon)
+	b.Cleanup(server.Close)
+	b.Setenv("DOCKER_HOST", "tcp://"+server.Listener.Addr().String())
+
+	dockerService, err := docker.NewService()
+	if err != nil {
+		b.Fatalf("Failed to create docker service: %v", err)
+	}
+	b.Cleanup(func() { dockerService.Close() })
+
+	return root, dockerService
+}
+
+func benchmarkListProjects(b *testing.B, s *Service) {
+	ctx := context.Background()
+
+	for b.Loop() {
+		projects, err := s.ListProjects(ctx)
+		if err != nil {
+			b.Fatalf("ListProjects failed: %v", err)
+		}
+		if len(projects) != benchProjects || projects[0].ContainersRunning != 2 {
+			b.Fatalf("unexpected projects: %d, first %+v", len(projects), projects[0])
+		}
+	}
+}
+
+// BenchmarkListProjects compares listing 100 projects when container counts
+// come from one Docker API call per project against the container cache
+func BenchmarkListProjects(b *testing.B) {
+	b.Run("docker_api", func(b *testing.B) {
+		root, dockerService := newBenchProjects(b)
+		benchmarkListProjects(b, &Service{rootPath: root, dockerClient: dockerService.Client()})
+	})
+
+	b.Run("cache", func(b *testing.B) {
+		root, dockerService := newBenchProjects(b)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		b.Cleanup(cancel)
+		go dockerService.WatchEvents(ctx)
+
+		deadline := time.Now().Add(5 * time.Second)
+		for !dockerService.Containers().Synced() {
+			if time.Now().After(deadline) {
+				b.Fatal("container cache did not sync")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		benchmarkListProjects(b, &Service{
+			rootPath:     root,
+			dockerClient: dockerService.Client(),
+			containers:   dockerService.Containers(),
+		})
+	})
+}
@@ -0,0 +1,311 @@
+package projects
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRevisions is how many revisions are kept per project; older ones are
+// pruned as new ones are recorded
+const maxRevisions = 200
+
+// Revision is a snapshot of a project's compose file taken after a write.
+// Edits made outside Hubble are captured as their own revision (with no
+// user) the next time Hubble writes the file.
+type Revision struct {
+	ID        int       `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	User      string    `json:"user"`
+	Action    string    `json:"action"`
+	Size      int       `json:"size"`
+	Content   string    `json:"content,omitempty"`
+}
+
+type userContextKey struct{}
+
+// WithUser returns a context that attributes compose writes to username
+func WithUser(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, userContextKey{}, username)
+}
+
+func userFromContext(ctx context.Context) string {
+	username, _ := ctx.Value(userContextKey{}).(string)
+	return username
+}
+
+// ListRevisions returns a project's revisions, newest first, without content
+func (s *Service) ListRevisions(ctx context.Context, projectName string) ([]Revision, error) {
+	if _, err := os.Stat(filepath.Join(s.rootPath, projectName)); os.IsNotExist(err) {
+		return nil, fmt.Errorf("project not found: %s", projectName)
+	}
+
+	revisions, err := s.readRevisions(projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Revision, 0, len(revisions))
+	for i := len(revisions) - 1; i >= 0; i-- {
+		revision := revisions[i]
+		revision.Content = ""
+		list = append(list, revision)
+	}
+
+	return list, nil
+}
+
+// GetRevision returns a single revision including its content
+func (s *Service) GetRevision(ctx context.Context, projectName string, id int) (*Revision, error) {
+	if _, err := os.Stat(filepath.Join(s.rootPath, projectName)); os.IsNotExist(err) {
+		return nil, fmt.Errorf("project not found: %s", projectName)
+	}
+
+	content, err := os.ReadFile(s.revisionPath(projectName, id))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("revision not found: %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revision: %w", err)
+	}
+
+	var revision Revision
+	if err := json.Unmarshal(content, &revision); err != nil {
+		return nil, fmt.Errorf("failed to parse revision %d: %w", id, err)
+	}
+
+	return &revision, nil
+}
+
+// DiffRevision returns a unified diff between a revision and another
+// state of the compose file. against is a revision ID, "current" for the
+// file on disk, or empty for the revision before id (i.e. what that
+// revision changed).
+func (s *Service) DiffRevision(ctx context.Context, projectName string, id int, against string) (string, error) {
+	revision, err := s.GetRevision(ctx, projectName, id)
+	if err != nil {
+		return "", err
+	}
+	toName := fmt.Sprintf("revision %d", revision.ID)
+
+	switch against {
+	case "":
+		// Compare with the closest earlier revision, if any survived pruning
+		revisions, err := s.readRevisions(projectName)
+		if err != nil {
+			return "", err
+		}
+		fromName, from := "/dev/null", ""
+		for _, r := range revisions {
+			if r.ID < id {
+				fromName, from = fmt.Sprintf("revision %d", r.ID), r.Content
+			}
+		}
+		return unifiedDiff(fromName, toName, from, revision.Content), nil
+
+	case "current":
+		composeFilePath, err := s.findComposeFile(projectName)
+		if err != nil {
+			return "", err
+		}
+		current, err := os.ReadFile(composeFilePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read compose file: %w", err)
+		}
+		return unifiedDiff(toName, filepath.Base(composeFilePath), revision.Content, string(current)), nil
+
+	default:
+		otherID, err := strconv.Atoi(against)
+		if err != nil {
+			return "", fmt.Errorf("invalid revision: %s", against)
+		}
+		other, err := s.GetRevision(ctx, projectName, otherID)
+		if err != nil {
+			return "", err
+		}
+		return unifiedDiff(fmt.Sprintf("revision %d", other.ID), toName, other.Content, revision.Content), nil
+	}
+}
+
+// RestoreRevision writes a revision's content back to the compose file.
+// The restore is itself recorded as a new revision, so it can be undone.
+func (s *Service) RestoreRevision(ctx context.Context, projectName string, id int) (*Revision, error) {
+	unlock := s.lockProject(projectName)
+	defer unlock()
+
+	revision, err := s.GetRevision(ctx, projectName, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := parseComposeDocument([]byte(revision.Content)); err != nil {
+		return nil, fmt.Errorf("revision %d is not a valid compose file: %w", id, err)
+	}
+
+	composeFilePath, err := s.findComposeFile(projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := os.ReadFile(composeFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compose file: %w", err)
+	}
+
+	action := fmt.Sprintf("restore revision %d", id)
+	return s.writeComposeFile(ctx, projectName, composeFilePath, current, []byte(revision.Content), action)
+}
+
+// writeComposeFile replaces the compose file and records the new content as
+// a revision. If the file on disk differs from the latest revision it was
+// edited outside Hubble, so that state is recorded first to keep it
+// restorable. current is nil when the file is being created. Callers must
+// hold the project lock. Returns the recorded revision, or nil when nothing
+// changed.
+func (s *Service) writeComposeFile(ctx context.Context, projectName, composeFilePath string, current, output []byte, action string) (*Revision, error) {
+	if current != nil {
+		if err := checkIfMatch(ctx, projectName, current); err != nil {
+			return nil, err
+		}
+	}
+
+	if current != nil && string(current) == string(output) {
+		reportETag(ctx, current)
+		return nil, nil
+	}
+
+	latest, err := s.latestRevision(projectName)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && (latest == nil || latest.Content != string(current)) {
+		externalAction := "external edit"
+		if latest == nil {
+			externalAction = "initial version"
+		}
+		if _, err := s.recordRevision(projectName, current, "", externalAction); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writeFileAtomic(composeFilePath, output, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write compose file: %w", err)
+	}
+	reportETag(ctx, output)
+
+	return s.recordRevision(projectName, output, userFromContext(ctx), action)
+}
+
+// recordRevision stores content as the next revision of a project
+func (s *Service) recordRevision(projectName string, content []byte, user, action string) (*Revision, error) {
+	dir := s.revisionsDir(projectName)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create revisions directory: %w", err)
+	}
+
+	ids, err := s.revisionIDs(projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	id := 1
+	if len(ids) > 0 {
+		id = ids[len(ids)-1] + 1
+	}
+
+	revision := Revision{
+		ID:        id,
+		Timestamp: time.Now().UTC(),
+		User:      user,
+		Action:    action,
+		Size:      len(content),
+		Content:   string(content),
+	}
+
+	data, err := json.Marshal(revision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal revision: %w", err)
+	}
+	if err := writeFileAtomic(s.revisionPath(projectName, id), data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write revision: %w", err)
+	}
+
+	// Prune the oldest revisions beyond the limit
+	ids = append(ids, id)
+	for len(ids) > maxRevisions {
+		if err := os.Remove(s.revisionPath(projectName, ids[0])); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to prune revision: %w", err)
+		}
+		ids = ids[1:]
+	}
+
+	revision.Content = ""
+	return &revision, nil
+}
+
+// latestRevision returns the newest revision, or nil if there are none
+func (s *Service) latestRevision(projectName string) (*Revision, error) {
+	ids, err := s.revisionIDs(projectName)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	return s.GetRevision(context.Background(), projectName, ids[len(ids)-1])
+}
+
+// readRevisions loads every revision of a project, oldest first
+func (s *Service) readRevisions(projectName string) ([]Revision, error) {
+	ids, err := s.revisionIDs(projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]Revision, 0, len(ids))
+	for _, id := range ids {
+		revision, err := s.GetRevision(context.Background(), projectName, id)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *revision)
+	}
+
+	return revisions, nil
+}
+
+// revisionIDs returns the stored revision IDs of a project in ascending order
+func (s *Service) revisionIDs(projectName string) ([]int, error) {
+	entries, err := os.ReadDir(s.revisionsDir(projectName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revisions directory: %w", err)
+	}
+
+	ids := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		id, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	return ids, nil
+}
+
+// revisionsDir keeps history outside the project directory so it never ends
+// up in the project's own build context or version control
+func (s *Service) revisionsDir(projectName string) string {
+	return filepath.Join(s.rootPath, ".hubble", "revisions", projectName)
+}
+
+func (s *Service) revisionPath(projectName string, id int) string {
+	return filepath.Join(s.revisionsDir(projectName), fmt.Sprintf("%06d.json", id))
+}
@@ -0,0 +1,142 @@
+package projects
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestUpdateComposeFile_RecordsRevisions(t *testing.T) {
+	svc, composePath := newTestProject(t, "commented.yml")
+	ctx := WithUser(context.Background(), "admin")
+
+	if err := svc.UpdateNetwork(ctx, "site", NetworkConfig{Name: "backend", Driver: "overlay"}); err != nil {
+		t.Fatalf("UpdateNetwork failed: %v", err)
+	}
+
+	// Simulate a hand edit between two writes through Hubble
+	content, err := os.ReadFile(composePath)
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+	if err := os.WriteFile(composePath, append(content, "# edited by hand\n"...), 0o644); err != nil {
+		t.Fatalf("Failed to write compose file: %v", err)
+	}
+
+	if err := svc.DeleteService(ctx, "site", "web"); err != nil {
+		t.Fatalf("DeleteService failed: %v", err)
+	}
+
+	revisions, err := svc.ListRevisions(ctx, "site")
+	if err != nil {
+		t.Fatalf("ListRevisions failed: %v", err)
+	}
+
+	want := []struct {
+		id     int
+		user   string
+		action string
+	}{
+		{4, "admin", "delete service web"},
+		{3, "", "external edit"},
+		{2, "admin", "update network backend"},
+		{1, "", "initial version"},
+	}
+	if len(revisions) != len(want) {
+		t.Fatalf("got %d revisions, want %d: %+v", len(revisions), len(want), revisions)
+	}
+	for i, w := range want {
+		got := revisions[i]
+		if got.ID != w.id || got.User != w.user || got.Action != w.action {
+			t.Errorf("revision %d = {%d %q %q}, want {%d %q %q}", i, got.ID, got.User, got.Action, w.id, w.user, w.action)
+		}
+		if got.Content != "" {
+			t.Errorf("revision %d: list should not include content", got.ID)
+		}
+	}
+}
+
+func TestUpdateComposeFile_NoopRecordsNothing(t *testing.T) {
+	svc, _ := newTestProject(t, "commented.yml")
+	ctx := context.Background()
+
+	if err := svc.UpdateNetwork(ctx, "site", NetworkConfig{Name: "hubble", External: true}); err != nil {
+		t.Fatalf("UpdateNetwork failed: %v", err)
+	}
+
+	revisions, err := svc.ListRevisions(ctx, "site")
+	if err != nil {
+		t.Fatalf("ListRevisions failed: %v", err)
+	}
+	if len(revisions) != 0 {
+		t.Errorf("no-op update recorded %d revisions", len(revisions))
+	}
+}
+
+func TestRestoreRevision(t *testing.T) {
+	svc, composePath := newTestProject(t, "commented.yml")
+	ctx := WithUser(context.Background(), "admin")
+
+	original, err := os.ReadFile(composePath)
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+
+	if err := svc.DeleteService(ctx, "site", "web"); err != nil {
+		t.Fatalf("DeleteService failed: %v", err)
+	}
+
+	revision, err := svc.RestoreRevision(ctx, "site", 1)
+	if err != nil {
+		t.Fatalf("RestoreRevision failed: %v", err)
+	}
+	if revision == nil || revision.ID != 3 || revision.Action != "restore revision 1" {
+		t.Errorf("unexpected restore revision: %+v", revision)
+	}
+
+	restored, err := os.ReadFile(composePath)
+	if err != nil {
+		t.Fatalf("Failed to read compose file: %v", err)
+	}
+	if string(restored) != string(original) {
+		t.Errorf("restored file differs from original\n--- got ---\n%s\n--- want ---\n%s", restored, original)
+	}
+
+	diff, err := svc.DiffRevision(ctx, "site", 1, "current")
+	if err != nil {
+		t.Fatalf("DiffRevision failed: %v", err)
+	}
+	if diff != "" {
+		t.Errorf("expected no diff against current file, got:\n%s", diff)
+	}
+
+	if _, err := svc.RestoreRevision(ctx, "site", 42); err == nil || err.Error() != "revision not found: 42" {
+		t.Errorf("expected revision not found error, got %v", err)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "services:\n  web:\n    image: nginx:1.25\n    restart: always\n  db:\n    image: postgres\n"
+	to := "services:\n  web:\n    image: nginx:1.27\n    restart: always\n  db:\n    image: postgres\n  cache:\n    image: redis\n"
+
+	want := `--- a
++++ b
+@@ -1,6 +1,8 @@
+ services:
+   web:
+-    image: nginx:1.25
++    image: nginx:1.27
+     restart: always
+   db:
+     image: postgres
++  cache:
++    image: redis
+`
+	if got := unifiedDiff("a", "b", from, to); got != want {
+		t.Errorf("unexpected diff\n--- got ---\n%s\n--- want ---\n%s", got, want)
+	}
+
+	if got := unifiedDiff("a", "b", from, from); got != "" {
+		t.Errorf("expected empty diff for identical input, got:\n%s", got)
+	}
+}
@@ -3,19 +3,29 @@ package projects
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"reflect"
+	"sync"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
+	"github.com/noel-vega/hubble/docker"
 	"gopkg.in/yaml.v3"
 )
 
 type Service struct {
 	rootPath     string
 	dockerClient *client.Client
+	// containers, when synced, answers container lookups without calling
+	// the Docker API
+	containers *docker.ContainerCache
+	locks      sync.Map // project name -> *sync.Mutex
+	// registry is where built images are pushed; nil when no registry is
+	// configured
+	registry *Registry
 }
 
 type ProjectInfo struct {
@@ -57,22 +67,6 @@ type NetworkConfig struct {
 	Config   map[string]interface{} `json:"config,omitempty"`
 }
 
-type ComposeService struct {
-	Name          string            `json:"name"`
-	Image         string            `json:"image"`
-	Build         string            `json:"build"`
-	Ports         []string          `json:"ports"`
-	Environment   map[string]string `json:"environment"`
-	Volumes       []string          `json:"volumes"`
-	DependsOn     []string          `json:"depends_on"`
-	Networks      []string          `json:"networks"`
-	Restart       string            `json:"restart"`
-	Command       string            `json:"command"`
-	Labels        []string          `json:"labels"`
-	ContainerName string            `json:"container_name"`
-	Status        string            `json:"status"`
-}
-
 type ComposeFile struct {
 	Version  string                 `yaml:"version,omitempty"`
 	Services map[string]interface{} `yaml:"services"`
@@ -82,7 +76,7 @@ type ComposeFile struct {
 	Configs  map[string]interface{} `yaml:"configs,omitempty"`
 }
 
-func NewService(dockerClient *client.Client) (*Service, error) {
+func NewService(dockerClient *client.Client, containers *docker.ContainerCache) (*Service, error) {
 	rootPath := os.Getenv("PROJECTS_ROOT_PATH")
 	if rootPath == "" {
 		return nil, fmt.Errorf("PROJECTS_ROOT_PATH environment variable is not set")
@@ -96,6 +90,7 @@ func NewService(dockerClient *client.Client) (*Service, error) {
 	return &Service{
 		rootPath:     rootPath,
 		dockerClient: dockerClient,
+		containers:   containers,
 	}, nil
 }
 
@@ -224,6 +219,21 @@ func (s *Service) GetProjectCompose(ctx context.Context, projectName string) (st
 }
 
 func (s *Service) GetProjectContainers(ctx context.Context, projectName string) ([]ProjectContainerInfo, error) {
+	if s.containers.Synced() {
+		cached := s.containers.Project(projectName)
+		result := make([]ProjectContainerInfo, 0, len(cached))
+		for _, c := range cached {
+			result = append(result, ProjectContainerInfo{
+				ID:      c.ID,
+				Name:    c.Name,
+				Service: c.Service(),
+				State:   c.State,
+				Status:  c.Status(),
+			})
+		}
+		return result, nil
+	}
+
 	if s.dockerClient == nil {
 		return []ProjectContainerInfo{}, nil
 	}
@@ -264,44 +274,18 @@ func (s *Service) GetProjectContainers(ctx context.Context, projectName string)
 }
 
 func (s *Service) GetProjectVolumes(ctx context.Context, projectName string) ([]ProjectVolume, error) {
-	projectPath := filepath.Join(s.rootPath, projectName)
-
-	// Find and read compose file
-	var composeFilePath string
-	for _, filename := range []string{"docker-compose.yml", "docker-compose.yaml"} {
-		path := filepath.Join(projectPath, filename)
-		if _, err := os.Stat(path); err == nil {
-			composeFilePath = path
-			break
-		}
-	}
-
-	if composeFilePath == "" {
-		return nil, fmt.Errorf("no docker-compose file found in project: %s", projectName)
-	}
-
-	content, err := os.ReadFile(composeFilePath)
+	services, err := s.readComposeServices(projectName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read compose file: %w", err)
+		return nil, err
 	}
 
-	var compose ComposeFile
 	volumes := []ProjectVolume{}
-
-	if err := yaml.Unmarshal(content, &compose); err == nil {
-		for serviceName, serviceData := range compose.Services {
-			if svcMap, ok := serviceData.(map[string]interface{}); ok {
-				if vols, ok := svcMap["volumes"].([]interface{}); ok {
-					for _, vol := range vols {
-						if volStr, ok := vol.(string); ok {
-							volumes = append(volumes, ProjectVolume{
-								Service: serviceName,
-								Volume:  volStr,
-							})
-						}
-					}
-				}
-			}
+	for _, service := range services {
+		for _, volume := range service.Volumes {
+			volumes = append(volumes, ProjectVolume{
+				Service: service.Name,
+				Volume:  volume.String(),
+			})
 		}
 	}
 
@@ -309,64 +293,31 @@ func (s *Service) GetProjectVolumes(ctx context.Context, projectName string) ([]
 }
 
 func (s *Service) GetProjectEnvironment(ctx context.Context, projectName string) ([]ProjectEnvironment, error) {
-	projectPath := filepath.Join(s.rootPath, projectName)
-
-	// Find and read compose file
-	var composeFilePath string
-	for _, filename := range []string{"docker-compose.yml", "docker-compose.yaml"} {
-		path := filepath.Join(projectPath, filename)
-		if _, err := os.Stat(path); err == nil {
-			composeFilePath = path
-			break
-		}
-	}
-
-	if composeFilePath == "" {
-		return nil, fmt.Errorf("no docker-compose file found in project: %s", projectName)
-	}
-
-	content, err := os.ReadFile(composeFilePath)
+	services, err := s.readComposeServices(projectName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read compose file: %w", err)
+		return nil, err
 	}
 
-	var compose ComposeFile
 	environments := []ProjectEnvironment{}
+	for _, service := range services {
+		if len(service.Environment) == 0 {
+			continue
+		}
 
-	if err := yaml.Unmarshal(content, &compose); err == nil {
-		for serviceName, serviceData := range compose.Services {
-			if svcMap, ok := serviceData.(map[string]interface{}); ok {
-				envVars := make(map[string]string)
-
-				// Handle environment as map
-				if env, ok := svcMap["environment"].(map[string]interface{}); ok {
-					for k, v := range env {
-						if vStr, ok := v.(string); ok {
-							envVars[k] = vStr
-						}
-					}
-				}
-
-				// Handle environment as array
-				if envArray, ok := svcMap["environment"].([]interface{}); ok {
-					for _, envItem := range envArray {
-						if envStr, ok := envItem.(string); ok {
-							// Parse KEY=VALUE format
-							if idx := filepath.Base(envStr); idx != "" {
-								envVars[envStr] = ""
-							}
-						}
-					}
-				}
-
-				if len(envVars) > 0 {
-					environments = append(environments, ProjectEnvironment{
-						Service: serviceName,
-						Env:     envVars,
-					})
-				}
+		// Variables passed through from the host have no value in the file
+		envVars := make(map[string]string, len(service.Environment))
+		for key, value := range service.Environment {
+			if value != nil {
+				envVars[key] = *value
+			} else {
+				envVars[key] = ""
 			}
 		}
+
+		environments = append(environments, ProjectEnvironment{
+			Service: service.Name,
+			Env:     envVars,
+		})
 	}
 
 	return environments, nil
@@ -421,6 +372,30 @@ func (s *Service) GetProjectNetworks(ctx context.Context, projectName string) ([
 }
 
 func (s *Service) GetProjectServices(ctx context.Context, projectName string) ([]ComposeService, error) {
+	services, err := s.readComposeServices(projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get status for all services in this project
+	serviceStatuses := s.getServiceStatuses(ctx, projectName)
+
+	for i := range services {
+		services[i] = services[i].withDefaults()
+		services[i].Status = "not_created" // Default status
+
+		// Set actual status if container exists
+		if status, exists := serviceStatuses[services[i].Name]; exists {
+			services[i].Status = status
+		}
+	}
+
+	return services, nil
+}
+
+// readComposeServices reads and decodes every service in a project's compose
+// file, in file order
+func (s *Service) readComposeServices(projectName string) ([]ComposeService, error) {
 	projectPath := filepath.Join(s.rootPath, projectName)
 
 	// Find and read compose file
@@ -442,197 +417,44 @@ func (s *Service) GetProjectServices(ctx context.Context, projectName string) ([
 		return nil, fmt.Errorf("failed to read compose file: %w", err)
 	}
 
-	var compose ComposeFile
-	services := []ComposeService{}
-
-	// Get status for all services in this project
-	serviceStatuses := s.getServiceStatuses(ctx, projectName)
-
-	if err := yaml.Unmarshal(content, &compose); err == nil {
-		for serviceName, serviceData := range compose.Services {
-			// Initialize with empty slices and maps
-			service := ComposeService{
-				Name:        serviceName,
-				Ports:       []string{},
-				Environment: map[string]string{},
-				Volumes:     []string{},
-				DependsOn:   []string{},
-				Networks:    []string{},
-				Labels:      []string{},
-				Status:      "not_created", // Default status
-			}
-
-			// Set actual status if container exists
-			if status, exists := serviceStatuses[serviceName]; exists {
-				service.Status = status
-			}
-
-			if svcMap, ok := serviceData.(map[string]interface{}); ok {
-				// Image
-				if image, ok := svcMap["image"].(string); ok {
-					service.Image = image
-				}
-
-				// Build (can be string or map)
-				if build, ok := svcMap["build"].(string); ok {
-					service.Build = build
-				} else if buildMap, ok := svcMap["build"].(map[string]interface{}); ok {
-					if context, ok := buildMap["context"].(string); ok {
-						service.Build = context
-					}
-				}
-
-				// Ports
-				if ports, ok := svcMap["ports"].([]interface{}); ok {
-					portsList := []string{}
-					for _, port := range ports {
-						if portStr, ok := port.(string); ok {
-							portsList = append(portsList, portStr)
-						}
-					}
-					if len(portsList) > 0 {
-						service.Ports = portsList
-					}
-				}
-
-				// Environment (map or array format)
-				if env, ok := svcMap["environment"].(map[string]interface{}); ok {
-					envMap := map[string]string{}
-					for k, v := range env {
-						if vStr, ok := v.(string); ok {
-							envMap[k] = vStr
-						}
-					}
-					if len(envMap) > 0 {
-						service.Environment = envMap
-					}
-				} else if envArray, ok := svcMap["environment"].([]interface{}); ok {
-					envMap := map[string]string{}
-					for _, envItem := range envArray {
-						if envStr, ok := envItem.(string); ok {
-							envMap[envStr] = ""
-						}
-					}
-					if len(envMap) > 0 {
-						service.Environment = envMap
-					}
-				}
-
-				// Volumes
-				if volumes, ok := svcMap["volumes"].([]interface{}); ok {
-					volumesList := []string{}
-					for _, vol := range volumes {
-						if volStr, ok := vol.(string); ok {
-							volumesList = append(volumesList, volStr)
-						}
-					}
-					if len(volumesList) > 0 {
-						service.Volumes = volumesList
-					}
-				}
-
-				// DependsOn (array or map format)
-				if dependsOn, ok := svcMap["depends_on"].([]interface{}); ok {
-					depsList := []string{}
-					for _, dep := range dependsOn {
-						if depStr, ok := dep.(string); ok {
-							depsList = append(depsList, depStr)
-						}
-					}
-					if len(depsList) > 0 {
-						service.DependsOn = depsList
-					}
-				} else if dependsOnMap, ok := svcMap["depends_on"].(map[string]interface{}); ok {
-					depsList := []string{}
-					for dep := range dependsOnMap {
-						depsList = append(depsList, dep)
-					}
-					if len(depsList) > 0 {
-						service.DependsOn = depsList
-					}
-				}
-
-				// Networks
-				if networks, ok := svcMap["networks"].([]interface{}); ok {
-					netsList := []string{}
-					for _, net := range networks {
-						if netStr, ok := net.(string); ok {
-							netsList = append(netsList, netStr)
-						}
-					}
-					if len(netsList) > 0 {
-						service.Networks = netsList
-					}
-				} else if networksMap, ok := svcMap["networks"].(map[string]interface{}); ok {
-					netsList := []string{}
-					for net := range networksMap {
-						netsList = append(netsList, net)
-					}
-					if len(netsList) > 0 {
-						service.Networks = netsList
-					}
-				}
-
-				// Restart
-				if restart, ok := svcMap["restart"].(string); ok {
-					service.Restart = restart
-				}
-
-				// Command (string or array)
-				if command, ok := svcMap["command"].(string); ok {
-					service.Command = command
-				} else if commandArray, ok := svcMap["command"].([]interface{}); ok {
-					cmdParts := []string{}
-					for _, cmd := range commandArray {
-						if cmdStr, ok := cmd.(string); ok {
-							cmdParts = append(cmdParts, cmdStr)
-						}
-					}
-					if len(cmdParts) > 0 {
-						service.Command = fmt.Sprintf("%v", cmdParts)
-					}
-				}
-
-				// Labels
-				if labels, ok := svcMap["labels"].([]interface{}); ok {
-					labelsList := []string{}
-					for _, label := range labels {
-						if labelStr, ok := label.(string); ok {
-							labelsList = append(labelsList, labelStr)
-						}
-					}
-					if len(labelsList) > 0 {
-						service.Labels = labelsList
-					}
-				}
+	doc, err := parseComposeDocument(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
 
-				// Container name
-				if containerName, ok := svcMap["container_name"].(string); ok {
-					service.ContainerName = containerName
-				}
-			}
+	services := []ComposeService{}
+	servicesNode := mappingValue(composeRoot(doc), "services")
+	if servicesNode == nil || servicesNode.Kind != yaml.MappingNode {
+		return services, nil
+	}
 
-			services = append(services, service)
+	for i := 0; i+1 < len(servicesNode.Content); i += 2 {
+		service, err := decodeService(servicesNode.Content[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse service %s: %w", servicesNode.Content[i].Value, err)
 		}
+		service.Name = servicesNode.Content[i].Value
+		services = append(services, service)
 	}
 
 	return services, nil
 }
 
-func (s *Service) getContainerCounts(ctx context.Context, projectName string) (running, stopped int) {
-	// If docker client is not available, return zeros
-	if s.dockerClient == nil {
-		return 0, 0
+// decodeService decodes a service definition node. A service declared with
+// no body decodes to an empty service.
+func decodeService(node *yaml.Node) (ComposeService, error) {
+	var service ComposeService
+	if node.Kind != yaml.MappingNode && node.Kind != yaml.AliasNode {
+		return service, nil
 	}
+	if err := node.Decode(&service); err != nil {
+		return service, err
+	}
+	return service, nil
+}
 
-	// Filter containers by project label (docker-compose project label)
-	filterArgs := filters.NewArgs()
-	filterArgs.Add("label", fmt.Sprintf("com.docker.compose.project=%s", projectName))
-
-	containers, err := s.dockerClient.ContainerList(ctx, container.ListOptions{
-		All:     true,
-		Filters: filterArgs,
-	})
+func (s *Service) getContainerCounts(ctx context.Context, projectName string) (running, stopped int) {
+	containers, err := s.GetProjectContainers(ctx, projectName)
 	if err != nil {
 		return 0, 0
 	}
@@ -651,17 +473,7 @@ func (s *Service) getContainerCounts(ctx context.Context, projectName string) (r
 func (s *Service) getServiceStatuses(ctx context.Context, projectName string) map[string]string {
 	statuses := make(map[string]string)
 
-	if s.dockerClient == nil {
-		return statuses
-	}
-
-	filterArgs := filters.NewArgs()
-	filterArgs.Add("label", fmt.Sprintf("com.docker.compose.project=%s", projectName))
-
-	containers, err := s.dockerClient.ContainerList(ctx, container.ListOptions{
-		All:     true,
-		Filters: filterArgs,
-	})
+	containers, err := s.GetProjectContainers(ctx, projectName)
 	if err != nil {
 		return statuses
 	}
@@ -669,7 +481,7 @@ func (s *Service) getServiceStatuses(ctx context.Context, projectName string) ma
 	// Map service name to highest priority status
 	// Priority: running > stopped > not_created
 	for _, c := range containers {
-		serviceName := c.Labels["com.docker.compose.service"]
+		serviceName := c.Service
 		if serviceName == "" {
 			continue
 		}
@@ -688,12 +500,24 @@ func (s *Service) getServiceStatuses(ctx context.Context, projectName string) ma
 	return statuses
 }
 
-func (s *Service) StartService(ctx context.Context, projectName, serviceName string) error {
-	if s.dockerClient == nil {
-		return fmt.Errorf("docker client not available")
+// serviceContainer is the ID and state of one of a service's containers
+type serviceContainer struct {
+	ID    string
+	State string
+}
+
+// serviceContainers returns the containers of a compose service, read from
+// the container cache once it is synced
+func (s *Service) serviceContainers(ctx context.Context, projectName, serviceName string) ([]serviceContainer, error) {
+	if s.containers.Synced() {
+		cached := s.containers.Service(projectName, serviceName)
+		result := make([]serviceContainer, 0, len(cached))
+		for _, c := range cached {
+			result = append(result, serviceContainer{ID: c.ID, State: c.State})
+		}
+		return result, nil
 	}
 
-	// Find containers for this service
 	filterArgs := filters.NewArgs()
 	filterArgs.Add("label", fmt.Sprintf("com.docker.compose.project=%s", projectName))
 	filterArgs.Add("label", fmt.Sprintf("com.docker.compose.service=%s", serviceName))
@@ -703,12 +527,32 @@ func (s *Service) StartService(ctx context.Context, projectName, serviceName str
 		Filters: filterArgs,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to list containers: %w", err)
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	result := make([]serviceContainer, 0, len(containers))
+	for _, c := range containers {
+		result = append(result, serviceContainer{ID: c.ID, State: c.State})
+	}
+	return result, nil
+}
+
+// StartService starts a service's containers, creating them with docker
+// compose if they do not exist yet. Compose output is written to output,
+// which may be nil.
+func (s *Service) StartService(ctx context.Context, projectName, serviceName string, output io.Writer) error {
+	if s.dockerClient == nil {
+		return fmt.Errorf("docker client not available")
+	}
+
+	containers, err := s.serviceContainers(ctx, projectName, serviceName)
+	if err != nil {
+		return err
 	}
 
 	if len(containers) == 0 {
 		// No containers found - create and start using docker-compose
-		return s.dockerComposeUp(ctx, projectName, serviceName)
+		return s.dockerComposeUp(ctx, projectName, serviceName, output)
 	}
 
 	// Containers exist - start them
@@ -728,37 +572,10 @@ func (s *Service) StartService(ctx context.Context, projectName, serviceName str
 	return nil
 }
 
-func (s *Service) dockerComposeUp(ctx context.Context, projectName, serviceName string) error {
-	projectPath := filepath.Join(s.rootPath, projectName)
-
-	// Verify project directory exists
-	if _, err := os.Stat(projectPath); os.IsNotExist(err) {
-		return fmt.Errorf("project directory not found: %s", projectPath)
-	}
-
-	// Verify docker-compose file exists
-	composeFiles := []string{"docker-compose.yml", "docker-compose.yaml"}
-	var composeFile string
-	for _, filename := range composeFiles {
-		path := filepath.Join(projectPath, filename)
-		if _, err := os.Stat(path); err == nil {
-			composeFile = filename
-			break
-		}
-	}
-
-	if composeFile == "" {
-		return fmt.Errorf("no docker-compose file found in project: %s", projectName)
-	}
-
+func (s *Service) dockerComposeUp(ctx context.Context, projectName, serviceName string, output io.Writer) error {
 	// Run docker compose up -d <service>
-	// Using "docker compose" (modern plugin) instead of "docker-compose" (legacy)
-	cmd := exec.CommandContext(ctx, "docker", "compose", "-f", composeFile, "-p", projectName, "up", "-d", serviceName)
-	cmd.Dir = projectPath
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to start service with docker compose: %w (output: %s)", err, string(output))
+	if err := s.runCompose(ctx, projectName, output, withServices([]string{"up", "-d"}, []string{serviceName})...); err != nil {
+		return fmt.Errorf("failed to start service with docker compose: %w", err)
 	}
 
 	return nil
@@ -769,17 +586,9 @@ func (s *Service) StopService(ctx context.Context, projectName, serviceName stri
 		return fmt.Errorf("docker client not available")
 	}
 
-	// Find containers for this service
-	filterArgs := filters.NewArgs()
-	filterArgs.Add("label", fmt.Sprintf("com.docker.compose.project=%s", projectName))
-	filterArgs.Add("label", fmt.Sprintf("com.docker.compose.service=%s", serviceName))
-
-	containers, err := s.dockerClient.ContainerList(ctx, container.ListOptions{
-		All:     true,
-		Filters: filterArgs,
-	})
+	containers, err := s.serviceContainers(ctx, projectName, serviceName)
 	if err != nil {
-		return fmt.Errorf("failed to list containers: %w", err)
+		return err
 	}
 
 	if len(containers) == 0 {
@@ -810,6 +619,9 @@ func (s *Service) CreateProject(ctx context.Context, name string) error {
 		return fmt.Errorf("project name cannot be empty")
 	}
 
+	unlock := s.lockProject(name)
+	defer unlock()
+
 	// Check if project already exists
 	projectPath := filepath.Join(s.rootPath, name)
 	if _, err := os.Stat(projectPath); err == nil {
@@ -833,8 +645,8 @@ func (s *Service) CreateProject(ctx context.Context, name string) error {
 		return fmt.Errorf("failed to marshal compose file: %w", err)
 	}
 
-	if err := os.WriteFile(composeFilePath, output, 0o644); err != nil {
-		return fmt.Errorf("failed to write compose file: %w", err)
+	if _, err := s.writeComposeFile(ctx, name, composeFilePath, nil, output, "create project"); err != nil {
+		return err
 	}
 
 	return nil
@@ -847,125 +659,114 @@ func (s *Service) AddService(ctx context.Context, projectName string, service Co
 		return fmt.Errorf("service name cannot be empty")
 	}
 
-	return s.updateComposeFile(projectName, func(compose *ComposeFile) error {
+	return s.updateComposeFile(ctx, projectName, "add service "+service.Name, func(root *yaml.Node) error {
+		services, err := ensureMapping(root, "services")
+		if err != nil {
+			return err
+		}
+
 		// Check if service already exists
-		if _, exists := compose.Services[service.Name]; exists {
+		if mappingValue(services, service.Name) != nil {
 			return fmt.Errorf("service already exists: %s", service.Name)
 		}
 
 		// Build service configuration
-		serviceConfig := make(map[string]interface{})
-
-		if service.Image != "" {
-			serviceConfig["image"] = service.Image
-		}
-
-		if service.Build != "" {
-			serviceConfig["build"] = service.Build
-		}
-
-		if len(service.Ports) > 0 {
-			serviceConfig["ports"] = service.Ports
-		}
-
-		if len(service.Environment) > 0 {
-			serviceConfig["environment"] = service.Environment
-		}
-
-		if len(service.Volumes) > 0 {
-			serviceConfig["volumes"] = service.Volumes
-		}
-
-		if len(service.DependsOn) > 0 {
-			serviceConfig["depends_on"] = service.DependsOn
-		}
-
-		if len(service.Networks) > 0 {
-			serviceConfig["networks"] = service.Networks
-		}
-
-		if service.Restart != "" {
-			serviceConfig["restart"] = service.Restart
+		serviceConfig, err := valueNode(service)
+		if err != nil {
+			return err
 		}
 
-		if service.Command != "" {
-			serviceConfig["command"] = service.Command
-		}
-
-		if len(service.Labels) > 0 {
-			serviceConfig["labels"] = service.Labels
-		}
-
-		if service.ContainerName != "" {
-			serviceConfig["container_name"] = service.ContainerName
-		}
-
-		compose.Services[service.Name] = serviceConfig
+		setMappingValue(services, service.Name, serviceConfig)
 		return nil
 	})
 }
 
-// UpdateService updates an existing service in a project
+// UpdateService updates an existing service in a project. Only the keys the
+// caller supplied are changed, and only where their value actually differs;
+// everything else in the service definition is kept as it is.
 func (s *Service) UpdateService(ctx context.Context, projectName string, service ComposeService) error {
 	// Validate service name
 	if service.Name == "" {
 		return fmt.Errorf("service name cannot be empty")
 	}
 
-	return s.updateComposeFile(projectName, func(compose *ComposeFile) error {
-		// Check if service exists
-		if _, exists := compose.Services[service.Name]; !exists {
+	return s.updateComposeFile(ctx, projectName, "update service "+service.Name, func(root *yaml.Node) error {
+		services := mappingValue(root, "services")
+		if services == nil || services.Kind != yaml.MappingNode {
 			return fmt.Errorf("service not found: %s", service.Name)
 		}
 
-		// Build updated service configuration
-		serviceConfig := make(map[string]interface{})
-
-		if service.Image != "" {
-			serviceConfig["image"] = service.Image
-		}
-
-		if service.Build != "" {
-			serviceConfig["build"] = service.Build
-		}
-
-		if len(service.Ports) > 0 {
-			serviceConfig["ports"] = service.Ports
-		}
-
-		if len(service.Environment) > 0 {
-			serviceConfig["environment"] = service.Environment
+		// Check if service exists
+		serviceConfig := mappingValue(services, service.Name)
+		if serviceConfig == nil {
+			return fmt.Errorf("service not found: %s", service.Name)
 		}
 
-		if len(service.Volumes) > 0 {
-			serviceConfig["volumes"] = service.Volumes
+		current, err := decodeService(serviceConfig)
+		if err != nil {
+			return fmt.Errorf("failed to parse service %s: %w", service.Name, err)
 		}
 
-		if len(service.DependsOn) > 0 {
-			serviceConfig["depends_on"] = service.DependsOn
+		switch {
+		case serviceConfig.Kind == yaml.MappingNode:
+		case serviceConfig.Kind == yaml.AliasNode && serviceConfig.Alias.Kind == yaml.MappingNode:
+			// An alias ("web: *base") shares its anchor with whatever else
+			// uses it; turn it into a copy so only this service changes
+			*serviceConfig = yaml.Node{
+				Kind:    yaml.MappingNode,
+				Tag:     "!!map",
+				Content: append([]*yaml.Node{}, serviceConfig.Alias.Content...),
+			}
+		case serviceConfig.Kind == yaml.ScalarNode && serviceConfig.Tag == "!!null":
+			// A service declared as "name:" with no body; give it a mapping
+			serviceConfig = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			setMappingValue(services, service.Name, serviceConfig)
+		default:
+			return fmt.Errorf("service %s is not a mapping", service.Name)
 		}
 
-		if len(service.Networks) > 0 {
-			serviceConfig["networks"] = service.Networks
+		updated, err := valueNode(service)
+		if err != nil {
+			return err
 		}
 
-		if service.Restart != "" {
-			serviceConfig["restart"] = service.Restart
-		}
+		currentValue := reflect.ValueOf(current)
+		updatedValue := reflect.ValueOf(service)
+		for _, field := range serviceKeys {
+			if !service.hasField(field.key) {
+				continue
+			}
 
-		if service.Command != "" {
-			serviceConfig["command"] = service.Command
-		}
+			// Equal after normalization (e.g. "8080:80" vs the long port
+			// syntax) means nothing to do; leave the original text alone
+			if reflect.DeepEqual(currentValue.Field(field.index).Interface(), updatedValue.Field(field.index).Interface()) {
+				continue
+			}
 
-		if len(service.Labels) > 0 {
-			serviceConfig["labels"] = service.Labels
+			if value := mappingValue(updated, field.key); value != nil {
+				setMappingValue(serviceConfig, field.key, value)
+			} else {
+				deleteMappingKey(serviceConfig, field.key)
+			}
 		}
 
-		if service.ContainerName != "" {
-			serviceConfig["container_name"] = service.ContainerName
+		// Extension keys are only replaced when the caller sent them
+		if service.hasField("extensions") && !reflect.DeepEqual(current.Extensions, service.Extensions) {
+			for i := 0; i+1 < len(serviceConfig.Content); {
+				key := serviceConfig.Content[i].Value
+				if _, keep := service.Extensions[key]; !keep && key != "<<" && !isServiceKey(key) {
+					deleteMappingKey(serviceConfig, key)
+					continue
+				}
+				i += 2
+			}
+			for _, key := range sortedKeys(service.Extensions) {
+				if value := mappingValue(updated, key); value != nil {
+					setMappingValue(serviceConfig, key, value)
+				}
+			}
 		}
 
-		compose.Services[service.Name] = serviceConfig
 		return nil
 	})
 }
@@ -976,13 +777,17 @@ func (s *Service) DeleteService(ctx context.Context, projectName, serviceName st
 		return fmt.Errorf("service name cannot be empty")
 	}
 
-	return s.updateComposeFile(projectName, func(compose *ComposeFile) error {
+	return s.updateComposeFile(ctx, projectName, "delete service "+serviceName, func(root *yaml.Node) error {
+		services := mappingValue(root, "services")
+		if services == nil || services.Kind != yaml.MappingNode {
+			return fmt.Errorf("service not found: %s", serviceName)
+		}
+
 		// Check if service exists
-		if _, exists := compose.Services[serviceName]; !exists {
+		if !deleteMappingKey(services, serviceName) {
 			return fmt.Errorf("service not found: %s", serviceName)
 		}
 
-		delete(compose.Services, serviceName)
 		return nil
 	})
 }
@@ -998,40 +803,24 @@ func (s *Service) AddNetwork(ctx context.Context, projectName string, network Ne
 		return fmt.Errorf("external networks cannot specify a driver (driver is managed by the existing network)")
 	}
 
-	return s.updateComposeFile(projectName, func(compose *ComposeFile) error {
+	return s.updateComposeFile(ctx, projectName, "add network "+network.Name, func(root *yaml.Node) error {
 		// Initialize networks map if needed
-		if compose.Networks == nil {
-			compose.Networks = make(map[string]interface{})
+		networks, err := ensureMapping(root, "networks")
+		if err != nil {
+			return err
 		}
 
 		// Check if network already exists
-		if _, exists := compose.Networks[network.Name]; exists {
+		if mappingValue(networks, network.Name) != nil {
 			return fmt.Errorf("network already exists: %s", network.Name)
 		}
 
-		// Build network configuration
-		networkConfig := make(map[string]interface{})
-
-		if network.External {
-			networkConfig["external"] = true
-		}
-
-		if network.Driver != "" {
-			networkConfig["driver"] = network.Driver
-		}
-
-		// Merge any additional config
-		for k, v := range network.Config {
-			networkConfig[k] = v
-		}
-
-		// If no config specified, set to nil (minimal network definition)
-		if len(networkConfig) == 0 {
-			compose.Networks[network.Name] = nil
-		} else {
-			compose.Networks[network.Name] = networkConfig
+		networkConfig, err := networkNode(network)
+		if err != nil {
+			return err
 		}
 
+		setMappingValue(networks, network.Name, networkConfig)
 		return nil
 	})
 }
@@ -1047,42 +836,50 @@ func (s *Service) UpdateNetwork(ctx context.Context, projectName string, network
 		return fmt.Errorf("external networks cannot specify a driver (driver is managed by the existing network)")
 	}
 
-	return s.updateComposeFile(projectName, func(compose *ComposeFile) error {
-		// Initialize networks map if needed
-		if compose.Networks == nil {
-			compose.Networks = make(map[string]interface{})
+	return s.updateComposeFile(ctx, projectName, "update network "+network.Name, func(root *yaml.Node) error {
+		networks := mappingValue(root, "networks")
+		if networks == nil || networks.Kind != yaml.MappingNode {
+			return fmt.Errorf("network not found: %s", network.Name)
 		}
 
 		// Check if network exists
-		if _, exists := compose.Networks[network.Name]; !exists {
+		if mappingValue(networks, network.Name) == nil {
 			return fmt.Errorf("network not found: %s", network.Name)
 		}
 
-		// Build updated network configuration
-		networkConfig := make(map[string]interface{})
-
-		if network.External {
-			networkConfig["external"] = true
+		networkConfig, err := networkNode(network)
+		if err != nil {
+			return err
 		}
 
-		if network.Driver != "" {
-			networkConfig["driver"] = network.Driver
-		}
+		setMappingValue(networks, network.Name, networkConfig)
+		return nil
+	})
+}
 
-		// Merge any additional config
-		for k, v := range network.Config {
-			networkConfig[k] = v
-		}
+// networkNode builds the compose definition for a network
+func networkNode(network NetworkConfig) (*yaml.Node, error) {
+	networkConfig := make(map[string]interface{})
 
-		// If no config specified, set to nil (minimal network definition)
-		if len(networkConfig) == 0 {
-			compose.Networks[network.Name] = nil
-		} else {
-			compose.Networks[network.Name] = networkConfig
-		}
+	if network.External {
+		networkConfig["external"] = true
+	}
 
-		return nil
-	})
+	if network.Driver != "" {
+		networkConfig["driver"] = network.Driver
+	}
+
+	// Merge any additional config
+	for k, v := range network.Config {
+		networkConfig[k] = v
+	}
+
+	// If no config specified, set to nil (minimal network definition)
+	if len(networkConfig) == 0 {
+		return valueNode(nil)
+	}
+
+	return valueNode(networkConfig)
 }
 
 // DeleteNetwork removes a network from a project
@@ -1091,43 +888,40 @@ func (s *Service) DeleteNetwork(ctx context.Context, projectName, networkName st
 		return fmt.Errorf("network name cannot be empty")
 	}
 
-	return s.updateComposeFile(projectName, func(compose *ComposeFile) error {
+	return s.updateComposeFile(ctx, projectName, "delete network "+networkName, func(root *yaml.Node) error {
 		// Check if networks map exists
-		if compose.Networks == nil {
+		networks := mappingValue(root, "networks")
+		if networks == nil || networks.Kind != yaml.MappingNode {
 			return fmt.Errorf("network not found: %s", networkName)
 		}
 
 		// Check if network exists
-		if _, exists := compose.Networks[networkName]; !exists {
+		if !deleteMappingKey(networks, networkName) {
 			return fmt.Errorf("network not found: %s", networkName)
 		}
 
-		delete(compose.Networks, networkName)
+		// Drop the networks section once it is empty
+		if len(networks.Content) == 0 {
+			deleteMappingKey(root, "networks")
+		}
+
 		return nil
 	})
 }
 
-// updateComposeFile is a helper function to safely update docker-compose.yml
-func (s *Service) updateComposeFile(projectName string, updateFn func(*ComposeFile) error) error {
-	projectPath := filepath.Join(s.rootPath, projectName)
-
-	// Check if project exists
-	if _, err := os.Stat(projectPath); os.IsNotExist(err) {
-		return fmt.Errorf("project not found: %s", projectName)
-	}
-
-	// Find compose file
-	var composeFilePath string
-	for _, filename := range []string{"docker-compose.yml", "docker-compose.yaml"} {
-		path := filepath.Join(projectPath, filename)
-		if _, err := os.Stat(path); err == nil {
-			composeFilePath = path
-			break
-		}
-	}
-
-	if composeFilePath == "" {
-		return fmt.Errorf("no docker-compose file found in project: %s", projectName)
+// updateComposeFile is a helper function to safely update docker-compose.yml.
+// updateFn edits the top-level mapping of the compose file in place; entries
+// it leaves untouched are written back exactly as they were. Each write is
+// recorded as a revision described by action. Mutations of the same project
+// are serialized, and the write is refused if ctx carries an If-Match that
+// no longer matches the file.
+func (s *Service) updateComposeFile(ctx context.Context, projectName, action string, updateFn func(root *yaml.Node) error) error {
+	unlock := s.lockProject(projectName)
+	defer unlock()
+
+	composeFilePath, err := s.findComposeFile(projectName)
+	if err != nil {
+		return err
 	}
 
 	// Read existing compose file
@@ -1136,32 +930,47 @@ func (s *Service) updateComposeFile(projectName string, updateFn func(*ComposeFi
 		return fmt.Errorf("failed to read compose file: %w", err)
 	}
 
-	// Parse compose file
-	var compose ComposeFile
-	if err := yaml.Unmarshal(content, &compose); err != nil {
+	// Parse compose file twice: one tree to edit, one to compare against
+	original, err := parseComposeDocument(content)
+	if err != nil {
 		return fmt.Errorf("failed to parse compose file: %w", err)
 	}
-
-	// Ensure services map is initialized
-	if compose.Services == nil {
-		compose.Services = make(map[string]interface{})
+	updated, err := parseComposeDocument(content)
+	if err != nil {
+		return fmt.Errorf("failed to parse compose file: %w", err)
 	}
 
 	// Apply update function
-	if err := updateFn(&compose); err != nil {
+	if err := updateFn(composeRoot(updated)); err != nil {
 		return err
 	}
 
-	// Marshal back to YAML
-	output, err := yaml.Marshal(&compose)
+	// Render back to YAML, keeping unchanged sections verbatim
+	output, err := renderCompose(content, original, updated)
 	if err != nil {
-		return fmt.Errorf("failed to marshal compose file: %w", err)
+		return err
 	}
 
 	// Write back to file
-	if err := os.WriteFile(composeFilePath, output, 0o644); err != nil {
-		return fmt.Errorf("failed to write compose file: %w", err)
+	_, err = s.writeComposeFile(ctx, projectName, composeFilePath, content, output, action)
+	return err
+}
+
+// findComposeFile returns the path of a project's compose file
+func (s *Service) findComposeFile(projectName string) (string, error) {
+	projectPath := filepath.Join(s.rootPath, projectName)
+
+	// Check if project exists
+	if _, err := os.Stat(projectPath); os.IsNotExist(err) {
+		return "", fmt.Errorf("project not found: %s", projectName)
 	}
 
-	return nil
+	for _, filename := range []string{"docker-compose.yml", "docker-compose.yaml"} {
+		path := filepath.Join(projectPath, filename)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("no docker-compose file found in project: %s", projectName)
 }
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,6 +18,10 @@ type Client struct {
 	username string
 	password string
 	client   *http.Client
+
+	// container runs garbage collection, see SetContainer
+	container Container
+	gcMu      sync.Mutex
 }
 
 type Repository struct {
@@ -69,35 +74,45 @@ func NewClient() (*Client, error) {
 }
 
 func (c *Client) doRequest(ctx context.Context, path string) ([]byte, error) {
-	url := fmt.Sprintf("%s%s", c.baseURL, path)
+	body, _, err := c.fetch(ctx, path, "")
+	return body, err
+}
+
+// fetch GETs a registry path with an optional Accept header and returns
+// the body and headers
+func (c *Client) fetch(ctx context.Context, path, accept string) ([]byte, http.Header, error) {
+	return c.send(ctx, "GET", path, accept)
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// send makes a request to the registry and returns the body and headers of
+// a 200 OK or 202 Accepted response
+func (c *Client) send(ctx context.Context, method, path, accept string) ([]byte, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
 	}
-
-	// Add basic auth if credentials are provided
 	if c.username != "" && c.password != "" {
 		req.SetBasicAuth(c.username, c.password)
 	}
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("registry returned status %d: %s", resp.StatusCode, string(body))
-	}
-
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return nil, nil, fmt.Errorf("registry returned status %d: %s", resp.StatusCode, string(body))
 	}
 
-	return body, nil
+	return body, resp.Header, nil
 }
 
 func (c *Client) ListRepositories(ctx context.Context) ([]string, error) {
@@ -171,3 +186,14 @@ func (c *Client) Close() error {
 func (c *Client) GetRegistryURL() string {
 	return c.baseURL
 }
+
+// Host returns the registry's host and port, as used in image references
+func (c *Client) Host() string {
+	host := strings.TrimPrefix(c.baseURL, "https://")
+	return strings.TrimPrefix(host, "http://")
+}
+
+// Credentials returns the username and password used for the registry
+func (c *Client) Credentials() (string, string) {
+	return c.username, c.password
+}
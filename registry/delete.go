@@ -0,0 +1,125 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// repositoryPattern is the syntax of a repository name, which also keeps
+// names from escaping the registry's storage directory
+var repositoryPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:\.|_|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:\.|_|__|-+)[a-z0-9]+)*)*$`)
+
+// tagPattern is the syntax of a tag
+var tagPattern = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+
+// validateTag checks a repository name and tag before they are put into a
+// registry API path
+func validateTag(repository, tag string) error {
+	if !repositoryPattern.MatchString(repository) {
+		return fmt.Errorf("invalid repository name: %s", repository)
+	}
+	if !tagPattern.MatchString(tag) {
+		return fmt.Errorf("invalid tag: %s", tag)
+	}
+	return nil
+}
+
+// RepositoryDeletion reports what DeleteRepository removed
+type RepositoryDeletion struct {
+	Repository string   `json:"repository"`
+	Tags       []string `json:"tags"`
+	Manifests  []string `json:"manifests"`
+}
+
+// ResolveDigest returns the digest of the manifest a tag points to
+func (c *Client) ResolveDigest(ctx context.Context, repository, tag string) (string, error) {
+	_, header, err := c.send(ctx, "HEAD", fmt.Sprintf("/v2/%s/manifests/%s", repository, tag), manifestAccept)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s:%s: %w", repository, tag, err)
+	}
+
+	digest := header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("failed to resolve %s:%s: registry returned no digest", repository, tag)
+	}
+	return digest, nil
+}
+
+// DeleteTag deletes the manifest a tag points to and returns its digest.
+// The registry deletes manifests rather than tags, so every other tag of
+// the same manifest is deleted too. The image's blobs stay on disk until
+// the next garbage collection.
+func (c *Client) DeleteTag(ctx context.Context, repository, tag string) (string, error) {
+	if err := validateTag(repository, tag); err != nil {
+		return "", err
+	}
+
+	digest, err := c.ResolveDigest(ctx, repository, tag)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.DeleteManifest(ctx, repository, digest); err != nil {
+		return "", fmt.Errorf("failed to delete %s:%s: %w", repository, tag, err)
+	}
+	return digest, nil
+}
+
+// DeleteRepository deletes every tagged manifest of a repository. When the
+// registry container is available, the repository's own data is removed as
+// well so it no longer appears in the catalog. Repositories nested beneath
+// it, such as team/app under team, share its directory and are kept.
+func (c *Client) DeleteRepository(ctx context.Context, repository string) (*RepositoryDeletion, error) {
+	if !repositoryPattern.MatchString(repository) {
+		return nil, fmt.Errorf("invalid repository name: %s", repository)
+	}
+
+	tags, err := c.ListTags(ctx, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	deletion := &RepositoryDeletion{
+		Repository: repository,
+		Tags:       tags,
+		Manifests:  []string{},
+	}
+
+	deleted := make(map[string]bool)
+	for _, tag := range tags {
+		digest, err := c.ResolveDigest(ctx, repository, tag)
+		if err != nil {
+			return nil, err
+		}
+		if deleted[digest] {
+			continue
+		}
+		if err := c.DeleteManifest(ctx, repository, digest); err != nil {
+			return nil, fmt.Errorf("failed to delete %s@%s: %w", repository, digest, err)
+		}
+		deleted[digest] = true
+		deletion.Manifests = append(deletion.Manifests, digest)
+	}
+
+	if c.container != nil {
+		dir := storagePath + "/docker/registry/v2/repositories/" + repository
+		cmd := []string{"rm", "-rf", dir + "/_manifests", dir + "/_layers", dir + "/_uploads"}
+		if _, err := c.container.Exec(ctx, cmd); err != nil {
+			return nil, fmt.Errorf("failed to remove repository directory: %w", err)
+		}
+	}
+
+	return deletion, nil
+}
+
+// DeleteManifest deletes a manifest by digest, and with it every tag that
+// points to it
+func (c *Client) DeleteManifest(ctx context.Context, repository, digest string) error {
+	_, _, err := c.send(ctx, "DELETE", fmt.Sprintf("/v2/%s/manifests/%s", repository, digest), "")
+	if err != nil && strings.Contains(err.Error(), "status 405") {
+		return fmt.Errorf("deletion is disabled on the registry (REGISTRY_STORAGE_DELETE_ENABLED)")
+	}
+	return err
+}
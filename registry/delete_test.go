@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDeleteRepository_DeletesEachManifestOnce(t *testing.T) {
+	digests := map[string]string{"latest": "sha256:aaa", "v1": "sha256:aaa", "v0": "sha256:bbb"}
+	var deleted []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/v2/team/app/tags/list":
+			fmt.Fprint(w, `{"name": "team/app", "tags": ["latest", "v1", "v0"]}`)
+		case r.Method == "HEAD" && strings.HasPrefix(r.URL.Path, "/v2/team/app/manifests/"):
+			w.Header().Set("Docker-Content-Digest", digests[strings.TrimPrefix(r.URL.Path, "/v2/team/app/manifests/")])
+		case r.Method == "DELETE":
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/v2/team/app/manifests/"))
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	container := &fakeContainer{}
+	client := &Client{baseURL: server.URL, client: server.Client()}
+	client.SetContainer(container)
+
+	deletion, err := client.DeleteRepository(t.Context(), "team/app")
+	if err != nil {
+		t.Fatalf("DeleteRepository failed: %v", err)
+	}
+	if fmt.Sprint(deleted) != "[sha256:aaa sha256:bbb]" || fmt.Sprint(deletion.Manifests) != fmt.Sprint(deleted) {
+		t.Errorf("deleted %v, reported %v", deleted, deletion.Manifests)
+	}
+	dir := "/var/lib/registry/docker/registry/v2/repositories/team/app"
+	if len(container.commands) != 1 || container.commands[0] != "rm -rf "+dir+"/_manifests "+dir+"/_layers "+dir+"/_uploads" {
+		t.Errorf("unexpected commands %q", container.commands)
+	}
+
+	if _, err := client.DeleteRepository(t.Context(), "../../blobs"); err == nil || !strings.Contains(err.Error(), "invalid repository name") {
+		t.Errorf("expected an invalid name error, got %v", err)
+	}
+}
+
+func TestDeleteRepository_KeepsNestedRepositories(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/v2/team/tags/list":
+			fmt.Fprint(w, `{"name": "team", "tags": ["latest"]}`)
+		case r.Method == "HEAD" && r.URL.Path == "/v2/team/manifests/latest":
+			w.Header().Set("Docker-Content-Digest", "sha256:aaa")
+		case r.Method == "DELETE" && r.URL.Path == "/v2/team/manifests/sha256:aaa":
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	container := &fakeContainer{}
+	client := &Client{baseURL: server.URL, client: server.Client()}
+	client.SetContainer(container)
+
+	if _, err := client.DeleteRepository(t.Context(), "team"); err != nil {
+		t.Fatalf("DeleteRepository failed: %v", err)
+	}
+
+	// team/app and team/worker live in team's directory and must survive
+	dir := "/var/lib/registry/docker/registry/v2/repositories/team"
+	if len(container.commands) != 1 {
+		t.Fatalf("unexpected commands %q", container.commands)
+	}
+	for _, arg := range strings.Fields(container.commands[0])[2:] {
+		if arg == dir || !strings.HasPrefix(arg, dir+"/_") {
+			t.Errorf("removed %s, which may hold nested repositories", arg)
+		}
+	}
+}
+
+func TestDeleteTag_DeletionDisabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			w.Header().Set("Docker-Content-Digest", "sha256:aaa")
+			return
+		}
+		http.Error(w, `{"errors":[{"code":"UNSUPPORTED"}]}`, http.StatusMethodNotAllowed)
+	}))
+	defer server.Close()
+
+	client := &Client{baseURL: server.URL, client: server.Client()}
+	if _, err := client.DeleteTag(t.Context(), "app", "v1"); err == nil || !strings.Contains(err.Error(), "deletion is disabled") {
+		t.Errorf("expected deletion to be disabled, got %v", err)
+	}
+}
+
+func TestValidateTag(t *testing.T) {
+	tests := []struct {
+		repository, tag string
+		err             string
+	}{
+		{"team/app", "v1.2.0", ""},
+		{"app", "main_42", ""},
+		{"../../blobs", "latest", "invalid repository name: ../../blobs"},
+		{"team/app", "../../_uploads", "invalid tag: ../../_uploads"},
+		{"team/app", "sha256:aaa", "invalid tag: sha256:aaa"},
+		{"team/app", "", "invalid tag: "},
+	}
+	for _, tt := range tests {
+		err := validateTag(tt.repository, tt.tag)
+		if (err == nil && tt.err != "") || (err != nil && err.Error() != tt.err) {
+			t.Errorf("validateTag(%q, %q) = %v, want %q", tt.repository, tt.tag, err, tt.err)
+		}
+	}
+}
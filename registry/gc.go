@@ -0,0 +1,167 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// storagePath is the registry's storage root inside its container
+	storagePath = "/var/lib/registry"
+	// configPath is the registry image's default configuration file
+	configPath = "/etc/docker/registry/config.yml"
+)
+
+// gcSummaryPattern matches the summary line printed by `registry
+// garbage-collect`
+var gcSummaryPattern = regexp.MustCompile(`(\d+) blobs marked, (\d+) blobs and (\d+) manifests eligible for deletion`)
+
+// Container is the registry's Docker container, which garbage collection
+// runs in
+type Container interface {
+	// Exec runs a command in the container and returns its combined output
+	Exec(ctx context.Context, cmd []string) (string, error)
+	// SetReadOnly restarts the registry with its storage read-only, or
+	// writable again
+	SetReadOnly(ctx context.Context, readOnly bool) error
+}
+
+// SetContainer enables garbage collection and repository directory removal
+// through the registry's container
+func (c *Client) SetContainer(container Container) {
+	c.container = container
+}
+
+// GCOptions controls a garbage collection run
+type GCOptions struct {
+	// DeleteUntagged also deletes manifests no tag points to
+	DeleteUntagged bool `json:"delete_untagged,omitempty"`
+	DryRun         bool `json:"dry_run,omitempty"`
+}
+
+// GCReport is the outcome of a garbage collection run. Sizes are the
+// registry's storage in bytes before and after the run.
+type GCReport struct {
+	DryRun           bool      `json:"dry_run"`
+	BlobsMarked      int       `json:"blobs_marked"`
+	BlobsDeleted     int       `json:"blobs_deleted"`
+	ManifestsDeleted int       `json:"manifests_deleted"`
+	SizeBefore       int64     `json:"size_before"`
+	SizeAfter        int64     `json:"size_after"`
+	SpaceReclaimed   int64     `json:"space_reclaimed"`
+	StartedAt        time.Time `json:"started_at"`
+	Duration         float64   `json:"duration_seconds"`
+}
+
+// GarbageCollect runs `registry garbage-collect` in the registry container
+// to delete blobs no manifest references. With a dry run, the counts are
+// what would be deleted and nothing is reclaimed.
+//
+// Collection deletes blobs that a push in progress may be about to
+// reference, so the registry is restarted read-only for the run and pushes
+// fail until it is writable again. Restarting it afterwards also clears its
+// in-memory blob cache, which would otherwise still report deleted blobs as
+// present and break pushes of the same layers.
+func (c *Client) GarbageCollect(ctx context.Context, opts GCOptions) (*GCReport, error) {
+	if c.container == nil {
+		return nil, fmt.Errorf("garbage collection is not available")
+	}
+	if !c.gcMu.TryLock() {
+		return nil, fmt.Errorf("garbage collection is already running")
+	}
+	defer c.gcMu.Unlock()
+
+	report := &GCReport{
+		DryRun:    opts.DryRun,
+		StartedAt: time.Now().UTC(),
+	}
+
+	before, err := c.storageSize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.SizeBefore = before
+
+	if !opts.DryRun {
+		if err := c.container.SetReadOnly(ctx, true); err != nil {
+			return nil, fmt.Errorf("failed to make registry read-only: %w", err)
+		}
+	}
+
+	output, err := c.container.Exec(ctx, gcCommand(opts))
+
+	if !opts.DryRun {
+		// Restored even when collection failed or the request went away,
+		// so the registry never stays read-only
+		restoreCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+		defer cancel()
+		if err := c.container.SetReadOnly(restoreCtx, false); err != nil {
+			return nil, fmt.Errorf("failed to make registry writable after garbage collection: %w", err)
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("garbage collection failed: %w", err)
+	}
+	report.BlobsMarked, report.BlobsDeleted, report.ManifestsDeleted = parseGCSummary(output)
+
+	report.SizeAfter = before
+	if !opts.DryRun {
+		after, err := c.storageSize(ctx)
+		if err != nil {
+			return nil, err
+		}
+		report.SizeAfter = after
+		report.SpaceReclaimed = max(before-after, 0)
+	}
+
+	report.Duration = time.Since(report.StartedAt).Seconds()
+	return report, nil
+}
+
+// gcCommand returns the `registry garbage-collect` command for opts
+func gcCommand(opts GCOptions) []string {
+	cmd := []string{"registry", "garbage-collect"}
+	if opts.DeleteUntagged {
+		cmd = append(cmd, "--delete-untagged")
+	}
+	if opts.DryRun {
+		cmd = append(cmd, "--dry-run")
+	}
+	return append(cmd, configPath)
+}
+
+// storageSize returns the size of the registry's storage in bytes
+func (c *Client) storageSize(ctx context.Context) (int64, error) {
+	output, err := c.container.Exec(ctx, []string{"du", "-sk", storagePath})
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure registry storage: %w", err)
+	}
+
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("failed to measure registry storage: unexpected output %q", output)
+	}
+	kilobytes, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure registry storage: unexpected output %q", output)
+	}
+	return kilobytes * 1024, nil
+}
+
+// parseGCSummary returns the marked blobs and the blobs and manifests
+// eligible for deletion from the output of `registry garbage-collect`
+func parseGCSummary(output string) (marked, blobs, manifests int) {
+	match := gcSummaryPattern.FindStringSubmatch(output)
+	if match == nil {
+		return 0, 0, 0
+	}
+	marked, _ = strconv.Atoi(match[1])
+	blobs, _ = strconv.Atoi(match[2])
+	manifests, _ = strconv.Atoi(match[3])
+	return marked, blobs, manifests
+}
@@ -0,0 +1,71 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeContainer records the commands run in it and answers `du` with the
+// next of sizes
+type fakeContainer struct {
+	commands []string
+	sizes    []string
+	output   string
+	// readOnly records each switch, and whether it came before or after
+	// the garbage-collect command
+	readOnly []string
+}
+
+func (f *fakeContainer) Exec(ctx context.Context, cmd []string) (string, error) {
+	f.commands = append(f.commands, strings.Join(cmd, " "))
+	if cmd[0] == "du" {
+		size := f.sizes[0]
+		f.sizes = f.sizes[1:]
+		return size + "\t/var/lib/registry\n", nil
+	}
+	return f.output, nil
+}
+
+func (f *fakeContainer) SetReadOnly(ctx context.Context, readOnly bool) error {
+	f.readOnly = append(f.readOnly, fmt.Sprintf("%v after %d commands", readOnly, len(f.commands)))
+	return nil
+}
+
+func TestGarbageCollect(t *testing.T) {
+	container := &fakeContainer{
+		sizes: []string{"2048", "512"},
+		output: `app
+app: marking manifest sha256:aaa
+app: marking blob sha256:ccc
+
+4 blobs marked, 3 blobs and 1 manifests eligible for deletion
+blob eligible for deletion: sha256:ddd
+`,
+	}
+	client := &Client{}
+	client.SetContainer(container)
+
+	report, err := client.GarbageCollect(t.Context(), GCOptions{DeleteUntagged: true})
+	if err != nil {
+		t.Fatalf("GarbageCollect failed: %v", err)
+	}
+	if report.BlobsMarked != 4 || report.BlobsDeleted != 3 || report.ManifestsDeleted != 1 {
+		t.Errorf("unexpected counts %+v", report)
+	}
+	if report.SizeBefore != 2048*1024 || report.SpaceReclaimed != 1536*1024 {
+		t.Errorf("unexpected sizes %+v", report)
+	}
+	// Read-only from just before the collection until just after it
+	if fmt.Sprint(container.readOnly) != "[true after 1 commands false after 2 commands]" {
+		t.Errorf("unexpected read-only switches %v", container.readOnly)
+	}
+	if container.commands[1] != "registry garbage-collect --delete-untagged /etc/docker/registry/config.yml" {
+		t.Errorf("unexpected command %q", container.commands[1])
+	}
+
+	if _, err := (&Client{}).GarbageCollect(t.Context(), GCOptions{}); err == nil || err.Error() != "garbage collection is not available" {
+		t.Errorf("expected garbage collection to be unavailable, got %v", err)
+	}
+}
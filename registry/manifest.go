@@ -0,0 +1,257 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Manifest media types the registry may return for a tag
+const (
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+)
+
+// manifestAccept asks for any of the supported manifest types, so the
+// registry returns the manifest as pushed instead of converting it
+var manifestAccept = strings.Join([]string{
+	MediaTypeOCIIndex,
+	MediaTypeDockerManifestList,
+	MediaTypeOCIManifest,
+	MediaTypeDockerManifest,
+}, ", ")
+
+// TagDetails describes the image a tag points to. For a multi-platform
+// image, Size is the total of all platforms and Layers, Created and Labels
+// are those of the first platform.
+type TagDetails struct {
+	Repository string            `json:"repository"`
+	Tag        string            `json:"tag"`
+	Digest     string            `json:"digest"`
+	MediaType  string            `json:"media_type"`
+	Size       int64             `json:"size"`
+	Created    *time.Time        `json:"created,omitempty"`
+	Layers     []Layer           `json:"layers"`
+	Platforms  []Platform        `json:"platforms"`
+	Labels     map[string]string `json:"labels"`
+}
+
+// Layer is a compressed image layer
+type Layer struct {
+	Digest    string `json:"digest"`
+	MediaType string `json:"media_type"`
+	Size      int64  `json:"size"`
+}
+
+// Platform is one platform variant of an image. Size is its compressed
+// config and layers.
+type Platform struct {
+	Digest       string     `json:"digest"`
+	OS           string     `json:"os"`
+	Architecture string     `json:"architecture"`
+	Variant      string     `json:"variant,omitempty"`
+	Size         int64      `json:"size"`
+	Layers       int        `json:"layers"`
+	Created      *time.Time `json:"created,omitempty"`
+}
+
+// descriptor references a blob or manifest in the registry
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Platform    *platformSpec     `json:"platform,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type platformSpec struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// manifest is a Docker v2 or OCI image manifest, or with Manifests set a
+// manifest list or image index
+type manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Config        descriptor   `json:"config"`
+	Layers        []descriptor `json:"layers"`
+	Manifests     []descriptor `json:"manifests"`
+}
+
+// imageConfig holds the fields used from an image config blob
+type imageConfig struct {
+	Created      *time.Time `json:"created"`
+	Architecture string     `json:"architecture"`
+	OS           string     `json:"os"`
+	Variant      string     `json:"variant"`
+	Config       struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"config"`
+}
+
+// GetTag fetches the manifest a tag points to, and for a multi-platform
+// image the manifest of each platform, with their image configs
+func (c *Client) GetTag(ctx context.Context, repository, tag string) (*TagDetails, error) {
+	if err := validateTag(repository, tag); err != nil {
+		return nil, err
+	}
+
+	m, digest, err := c.getManifest(ctx, repository, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest for %s:%s: %w", repository, tag, err)
+	}
+
+	details := &TagDetails{
+		Repository: repository,
+		Tag:        tag,
+		Digest:     digest,
+		MediaType:  m.MediaType,
+		Layers:     []Layer{},
+		Platforms:  []Platform{},
+		Labels:     map[string]string{},
+	}
+
+	if !isIndex(m.MediaType) {
+		platform, config, err := c.inspectImage(ctx, repository, digest, m, details)
+		if err != nil {
+			return nil, err
+		}
+		details.Size = platform.Size
+		details.Platforms = append(details.Platforms, platform)
+		details.Created = config.Created
+		if config.Config.Labels != nil {
+			details.Labels = config.Config.Labels
+		}
+		return details, nil
+	}
+
+	for _, desc := range m.Manifests {
+		if isAttestation(desc) {
+			continue
+		}
+
+		platformManifest, _, err := c.getManifest(ctx, repository, desc.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get manifest %s: %w", desc.Digest, err)
+		}
+
+		// Only the first platform's layers are listed
+		var layersOf *TagDetails
+		if len(details.Platforms) == 0 {
+			layersOf = details
+		}
+		platform, config, err := c.inspectImage(ctx, repository, desc.Digest, platformManifest, layersOf)
+		if err != nil {
+			return nil, err
+		}
+		if desc.Platform != nil {
+			platform.OS = desc.Platform.OS
+			platform.Architecture = desc.Platform.Architecture
+			platform.Variant = desc.Platform.Variant
+		}
+
+		if len(details.Platforms) == 0 {
+			details.Created = config.Created
+			if config.Config.Labels != nil {
+				details.Labels = config.Config.Labels
+			}
+		}
+		details.Size += platform.Size
+		details.Platforms = append(details.Platforms, platform)
+	}
+
+	return details, nil
+}
+
+// inspectImage summarizes a single-platform manifest and fetches its
+// config. The layers are added to details when it is not nil.
+func (c *Client) inspectImage(ctx context.Context, repository, digest string, m *manifest, details *TagDetails) (Platform, *imageConfig, error) {
+	if m.Config.Digest == "" {
+		return Platform{}, nil, fmt.Errorf("unsupported manifest media type: %s", m.MediaType)
+	}
+
+	platform := Platform{
+		Digest: digest,
+		Size:   m.Config.Size,
+		Layers: len(m.Layers),
+	}
+	for _, layer := range m.Layers {
+		platform.Size += layer.Size
+		if details != nil {
+			details.Layers = append(details.Layers, Layer{
+				Digest:    layer.Digest,
+				MediaType: layer.MediaType,
+				Size:      layer.Size,
+			})
+		}
+	}
+
+	body, _, err := c.fetch(ctx, fmt.Sprintf("/v2/%s/blobs/%s", repository, m.Config.Digest), "")
+	if err != nil {
+		return Platform{}, nil, fmt.Errorf("failed to get image config %s: %w", m.Config.Digest, err)
+	}
+
+	var config imageConfig
+	if err := json.Unmarshal(body, &config); err != nil {
+		return Platform{}, nil, fmt.Errorf("failed to parse image config: %w", err)
+	}
+	platform.OS = config.OS
+	platform.Architecture = config.Architecture
+	platform.Variant = config.Variant
+	platform.Created = config.Created
+
+	return platform, &config, nil
+}
+
+// getManifest fetches a manifest by tag or digest and returns it with its
+// digest
+func (c *Client) getManifest(ctx context.Context, repository, reference string) (*manifest, string, error) {
+	body, header, err := c.fetch(ctx, fmt.Sprintf("/v2/%s/manifests/%s", repository, reference), manifestAccept)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var m manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, "", fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	// The mediaType field is optional in OCI manifests
+	if m.MediaType == "" {
+		m.MediaType, _, _ = strings.Cut(header.Get("Content-Type"), ";")
+	}
+	if m.MediaType == "" || m.MediaType == "application/json" {
+		if m.Manifests != nil {
+			m.MediaType = MediaTypeOCIIndex
+		} else {
+			m.MediaType = MediaTypeOCIManifest
+		}
+	}
+
+	digest := header.Get("Docker-Content-Digest")
+	if digest == "" {
+		digest = fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+	}
+
+	return &m, digest, nil
+}
+
+func isIndex(mediaType string) bool {
+	return mediaType == MediaTypeOCIIndex || mediaType == MediaTypeDockerManifestList
+}
+
+// isAttestation reports whether an index entry is a BuildKit provenance or
+// SBOM attestation rather than an image
+func isAttestation(desc descriptor) bool {
+	if desc.Annotations["vnd.docker.reference.type"] == "attestation-manifest" {
+		return true
+	}
+	return desc.Platform != nil && desc.Platform.OS == "unknown" && desc.Platform.Architecture == "unknown"
+}
@@ -0,0 +1,81 @@
+package registry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetTag_Index(t *testing.T) {
+	responses := map[string]struct{ contentType, body string }{
+		"/v2/myapp/manifests/v1": {MediaTypeOCIIndex, `{
+			"schemaVersion": 2,
+			"manifests": [
+				{"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:amd", "size": 500, "platform": {"architecture": "amd64", "os": "linux"}},
+				{"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:arm", "size": 500, "platform": {"architecture": "arm64", "os": "linux", "variant": "v8"}},
+				{"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:att", "size": 800, "platform": {"architecture": "unknown", "os": "unknown"},
+				 "annotations": {"vnd.docker.reference.type": "attestation-manifest"}}
+			]
+		}`},
+		"/v2/myapp/manifests/sha256:amd": {MediaTypeOCIManifest, `{
+			"schemaVersion": 2,
+			"config": {"digest": "sha256:amdconfig", "size": 100},
+			"layers": [{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": "sha256:l1", "size": 1000}, {"digest": "sha256:l2", "size": 2000}]
+		}`},
+		"/v2/myapp/manifests/sha256:arm": {MediaTypeOCIManifest, `{
+			"schemaVersion": 2,
+			"config": {"digest": "sha256:armconfig", "size": 100},
+			"layers": [{"digest": "sha256:l3", "size": 4000}]
+		}`},
+		"/v2/myapp/blobs/sha256:amdconfig": {"application/octet-stream", `{
+			"created": "2025-01-15T10:30:00Z", "architecture": "amd64", "os": "linux",
+			"config": {"Labels": {"org.opencontainers.image.version": "1.0.0"}}
+		}`},
+		"/v2/myapp/blobs/sha256:armconfig": {"application/octet-stream", `{"created": "2025-01-15T10:31:00Z", "architecture": "arm64", "os": "linux"}`},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/manifests/") && !strings.Contains(r.Header.Get("Accept"), MediaTypeOCIIndex) {
+			t.Errorf("manifest request without index Accept header: %q", r.Header.Get("Accept"))
+		}
+		resp, ok := responses[r.URL.Path]
+		if !ok {
+			http.Error(w, `{"errors":[{"code":"MANIFEST_UNKNOWN"}]}`, http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/v2/myapp/manifests/v1" {
+			w.Header().Set("Docker-Content-Digest", "sha256:index")
+		}
+		w.Header().Set("Content-Type", resp.contentType)
+		w.Write([]byte(resp.body))
+	}))
+	defer server.Close()
+
+	client := &Client{baseURL: server.URL, client: server.Client()}
+
+	details, err := client.GetTag(t.Context(), "myapp", "v1")
+	if err != nil {
+		t.Fatalf("GetTag failed: %v", err)
+	}
+
+	if details.Digest != "sha256:index" || details.MediaType != MediaTypeOCIIndex {
+		t.Errorf("got digest %s and media type %s", details.Digest, details.MediaType)
+	}
+	if details.Size != 3100+4100 {
+		t.Errorf("got size %d, want %d", details.Size, 3100+4100)
+	}
+	if len(details.Platforms) != 2 || details.Platforms[1].Architecture != "arm64" || details.Platforms[1].Variant != "v8" || details.Platforms[1].Size != 4100 {
+		t.Errorf("unexpected platforms %+v", details.Platforms)
+	}
+	if len(details.Layers) != 2 || details.Layers[0].Digest != "sha256:l1" {
+		t.Errorf("expected the first platform's layers, got %+v", details.Layers)
+	}
+	if details.Created == nil || details.Created.Minute() != 30 || details.Labels["org.opencontainers.image.version"] != "1.0.0" {
+		t.Errorf("expected the first platform's config, got %v %v", details.Created, details.Labels)
+	}
+
+	if _, err := client.GetTag(t.Context(), "myapp", "missing"); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("expected a 404 error, got %v", err)
+	}
+}
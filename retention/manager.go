@@ -0,0 +1,371 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/noel-vega/hubble/projects"
+	"github.com/noel-vega/hubble/registry"
+)
+
+// defaultInterval is how often policies are applied unless
+// RETENTION_INTERVAL is set
+const defaultInterval = 24 * time.Hour
+
+// Report lists the tags a retention run kept and deleted, or with a dry
+// run would delete
+type Report struct {
+	DryRun       bool               `json:"dry_run"`
+	StartedAt    time.Time          `json:"started_at"`
+	Duration     float64            `json:"duration_seconds"`
+	Repositories []RepositoryReport `json:"repositories"`
+	TagsDeleted  int                `json:"tags_deleted"`
+	// GCNeeded is set when manifests were deleted. Garbage collection is
+	// left to POST /registry/gc, since the registry refuses pushes from
+	// builds and CI while it runs.
+	GCNeeded bool `json:"gc_needed"`
+}
+
+// RepositoryReport is the outcome for one repository a policy applies to.
+// A repository with an Error is left untouched.
+type RepositoryReport struct {
+	Repository string        `json:"repository"`
+	PolicyID   string        `json:"policy_id"`
+	PolicyName string        `json:"policy_name"`
+	Kept       []TagDecision `json:"kept"`
+	Deleted    []TagDecision `json:"deleted"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Manager keeps retention policies and applies them to the registry on a
+// schedule. Tags referenced by any project's compose file are never
+// deleted.
+type Manager struct {
+	registryClient  *registry.Client
+	projectsService *projects.Service
+	interval        time.Duration
+	policiesPath    string
+
+	mu       sync.Mutex
+	policies []Policy
+	lastRun  *Report
+	runMu    sync.Mutex
+	now      func() time.Time
+}
+
+// NewManager loads the policies kept in dir
+func NewManager(dir string, registryClient *registry.Client, projectsService *projects.Service) (*Manager, error) {
+	interval := defaultInterval
+	if value := os.Getenv("RETENTION_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid RETENTION_INTERVAL: %s", value)
+		}
+		interval = parsed
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create retention directory: %w", err)
+	}
+
+	policiesPath := filepath.Join(dir, "policies.json")
+	policies, err := loadPolicies(policiesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Manager{
+		registryClient:  registryClient,
+		projectsService: projectsService,
+		interval:        interval,
+		policiesPath:    policiesPath,
+		policies:        policies,
+		now:             time.Now,
+	}, nil
+}
+
+// Interval returns how often policies are applied
+func (m *Manager) Interval() time.Duration {
+	return m.interval
+}
+
+// Run applies the policies every interval until ctx is done
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		report, err := m.Apply(ctx)
+		if err != nil {
+			log.Printf("Warning: registry retention failed: %v", err)
+			continue
+		}
+		log.Printf("Registry retention deleted %d tags across %d repositories", report.TagsDeleted, len(report.Repositories))
+	}
+}
+
+// Preview reports what applying the policies now would delete, without
+// deleting anything
+func (m *Manager) Preview(ctx context.Context) (*Report, error) {
+	return m.run(ctx, true)
+}
+
+// Apply deletes the tags the policies select. Their space is reclaimed by
+// the next garbage collection.
+func (m *Manager) Apply(ctx context.Context) (*Report, error) {
+	report, err := m.run(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.lastRun = report
+	m.mu.Unlock()
+
+	return report, nil
+}
+
+// LastRun returns the report of the most recent scheduled or manual run,
+// or nil if there has been none since Hubble started
+func (m *Manager) LastRun() *Report {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastRun
+}
+
+func (m *Manager) run(ctx context.Context, dryRun bool) (*Report, error) {
+	if !m.runMu.TryLock() {
+		return nil, fmt.Errorf("retention is already running")
+	}
+	defer m.runMu.Unlock()
+
+	report := &Report{
+		DryRun:       dryRun,
+		StartedAt:    m.now().UTC(),
+		Repositories: []RepositoryReport{},
+	}
+
+	var policies []Policy
+	for _, policy := range m.ListPolicies() {
+		if !policy.Disabled {
+			policies = append(policies, policy)
+		}
+	}
+	if len(policies) == 0 {
+		return report, nil
+	}
+
+	// Without every compose reference, nothing can be deleted safely
+	imageReferences, err := m.projectsService.ImageReferences(ctx)
+	if err != nil {
+		return nil, err
+	}
+	references := make([]reference, 0, len(imageReferences))
+	for _, ref := range imageReferences {
+		references = append(references, parseReference(ref))
+	}
+
+	repositories, err := m.registryClient.ListRepositories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, repository := range repositories {
+		var policy *Policy
+		for i := range policies {
+			if policies[i].matches(repository) {
+				policy = &policies[i]
+				break
+			}
+		}
+		if policy == nil {
+			continue
+		}
+
+		result := m.evaluate(ctx, *policy, repository, references)
+		if !dryRun && result.Error == "" {
+			deleted, err := m.deleteTags(ctx, repository, result.Deleted)
+			result.Deleted = deleted
+			if err != nil {
+				result.Error = err.Error()
+			}
+			report.GCNeeded = report.GCNeeded || len(deleted) > 0
+		}
+
+		report.TagsDeleted += len(result.Deleted)
+		report.Repositories = append(report.Repositories, result)
+	}
+
+	report.Duration = m.now().Sub(report.StartedAt).Seconds()
+	return report, nil
+}
+
+// evaluate decides which of a repository's tags the policy deletes. Any
+// tag that cannot be inspected leaves the whole repository untouched.
+func (m *Manager) evaluate(ctx context.Context, policy Policy, repository string, references []reference) RepositoryReport {
+	result := RepositoryReport{
+		Repository: repository,
+		PolicyID:   policy.ID,
+		PolicyName: policy.Name,
+		Kept:       []TagDecision{},
+		Deleted:    []TagDecision{},
+	}
+
+	tagNames, err := m.registryClient.ListTags(ctx, repository)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	tags := make([]Tag, 0, len(tagNames))
+	for _, name := range tagNames {
+		details, err := m.registryClient.GetTag(ctx, repository, name)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		tags = append(tags, Tag{Tag: name, Digest: details.Digest, Created: details.Created})
+	}
+
+	kept, deleted, err := plan(policy, repository, tags, references, m.now())
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if kept != nil {
+		result.Kept = kept
+	}
+	if deleted != nil {
+		result.Deleted = deleted
+	}
+	return result
+}
+
+// deleteTags deletes the manifests of the given tags and returns the tags
+// that were deleted before any error
+func (m *Manager) deleteTags(ctx context.Context, repository string, tags []TagDecision) ([]TagDecision, error) {
+	deleted := []TagDecision{}
+	done := make(map[string]bool)
+	for _, tag := range tags {
+		if !done[tag.Digest] {
+			if err := m.registryClient.DeleteManifest(ctx, repository, tag.Digest); err != nil {
+				// The manifest may already be gone with another tag
+				if !strings.Contains(err.Error(), "status 404") {
+					return deleted, fmt.Errorf("failed to delete %s:%s: %w", repository, tag.Tag, err)
+				}
+			}
+			done[tag.Digest] = true
+		}
+		deleted = append(deleted, tag)
+	}
+	return deleted, nil
+}
+
+// ListPolicies returns every policy in the order they were created, which
+// is the order they are matched against repositories
+func (m *Manager) ListPolicies() []Policy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Policy{}, m.policies...)
+}
+
+// GetPolicy returns a policy by ID
+func (m *Manager) GetPolicy(id string) (Policy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, policy := range m.policies {
+		if policy.ID == id {
+			return policy, nil
+		}
+	}
+	return Policy{}, fmt.Errorf("policy not found: %s", id)
+}
+
+// CreatePolicy validates and saves a new policy
+func (m *Manager) CreatePolicy(policy Policy) (Policy, error) {
+	if err := policy.Validate(); err != nil {
+		return Policy{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now().UTC()
+	policy.ID = newID()
+	policy.CreatedAt = now
+	policy.UpdatedAt = now
+
+	policies := append(append([]Policy{}, m.policies...), policy)
+	if err := savePolicies(m.policiesPath, policies); err != nil {
+		return Policy{}, err
+	}
+	m.policies = policies
+
+	return policy, nil
+}
+
+// UpdatePolicy replaces a policy's settings
+func (m *Manager) UpdatePolicy(id string, policy Policy) (Policy, error) {
+	if err := policy.Validate(); err != nil {
+		return Policy{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, existing := range m.policies {
+		if existing.ID != id {
+			continue
+		}
+
+		policy.ID = id
+		policy.CreatedAt = existing.CreatedAt
+		policy.UpdatedAt = m.now().UTC()
+
+		policies := append([]Policy{}, m.policies...)
+		policies[i] = policy
+		if err := savePolicies(m.policiesPath, policies); err != nil {
+			return Policy{}, err
+		}
+		m.policies = policies
+
+		return policy, nil
+	}
+
+	return Policy{}, fmt.Errorf("policy not found: %s", id)
+}
+
+// DeletePolicy removes a policy
+func (m *Manager) DeletePolicy(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, policy := range m.policies {
+		if policy.ID != id {
+			continue
+		}
+
+		policies := append(append([]Policy{}, m.policies[:i]...), m.policies[i+1:]...)
+		if err := savePolicies(m.policiesPath, policies); err != nil {
+			return err
+		}
+		m.policies = policies
+
+		return nil
+	}
+
+	return fmt.Errorf("policy not found: %s", id)
+}
@@ -0,0 +1,183 @@
+package retention
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/noel-vega/hubble/projects"
+)
+
+// variablePattern matches compose variables such as ${TAG:-latest} and $TAG
+var variablePattern = regexp.MustCompile(`\$\{[^}]*\}|\$[A-Za-z_][A-Za-z0-9_]*`)
+
+// Tag is a registry tag with the manifest it points to
+type Tag struct {
+	Tag     string     `json:"tag"`
+	Digest  string     `json:"digest"`
+	Created *time.Time `json:"created,omitempty"`
+}
+
+// TagDecision is why a tag is kept or deleted
+type TagDecision struct {
+	Tag
+	Reason string `json:"reason"`
+}
+
+// reference is a compose image reference, as patterns matched against the
+// registry's repositories and tags. Variables match anything, and the
+// registry host is ignored, so a reference protects at least the tags it
+// could mean.
+type reference struct {
+	repositories []*regexp.Regexp
+	tag          string
+	digest       string
+	source       string
+}
+
+func parseReference(ref projects.ImageReference) reference {
+	image := variablePattern.ReplaceAllString(ref.Image, "*")
+
+	name, digest, _ := strings.Cut(image, "@")
+	tag := ""
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, tag = name[:i], name[i+1:]
+	}
+	if tag == "" && digest == "" {
+		tag = "latest"
+		// A variable in the name, as in ${IMAGE}, may carry the tag too
+		if strings.Contains(name, "*") {
+			tag = "*"
+		}
+	}
+
+	names := []string{name}
+	if host, rest, ok := strings.Cut(name, "/"); ok {
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			names = []string{rest}
+		} else if strings.Contains(host, "*") {
+			// A variable may be the registry host or a namespace
+			names = append(names, rest)
+		}
+	}
+
+	// Unlike path.Match, a variable may span several path segments
+	repositories := make([]*regexp.Regexp, 0, len(names))
+	for _, name := range names {
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(name), `\*`, ".*")
+		repositories = append(repositories, regexp.MustCompile("^"+pattern+"$"))
+	}
+
+	return reference{
+		repositories: repositories,
+		tag:          tag,
+		digest:       digest,
+		source:       ref.Project + "/" + ref.Service,
+	}
+}
+
+// matches reports whether the reference could mean a tag of a repository
+func (r reference) matches(repository string, tag Tag) bool {
+	for _, pattern := range r.repositories {
+		if !pattern.MatchString(repository) {
+			continue
+		}
+		if r.tag != "" {
+			if matched, _ := path.Match(r.tag, tag.Tag); matched {
+				return true
+			}
+		}
+		if r.digest != "" {
+			if matched, _ := path.Match(r.digest, tag.Digest); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// plan decides which of a repository's tags the policy deletes. Since the
+// registry deletes manifests rather than tags, a tag is also kept when it
+// shares its manifest with a kept tag.
+func plan(policy Policy, repository string, tags []Tag, references []reference, now time.Time) (kept, deleted []TagDecision, err error) {
+	var keepPattern *regexp.Regexp
+	if policy.KeepPattern != "" {
+		keepPattern, err = regexp.Compile(policy.KeepPattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid keep_pattern: %w", err)
+		}
+	}
+
+	// Newest first, with tags of unknown age last
+	sorted := append([]Tag{}, tags...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Created, sorted[j].Created
+		if a == nil || b == nil {
+			if (a == nil) != (b == nil) {
+				return b == nil
+			}
+			return sorted[i].Tag < sorted[j].Tag
+		}
+		if !a.Equal(*b) {
+			return a.After(*b)
+		}
+		return sorted[i].Tag < sorted[j].Tag
+	})
+
+	maxAge := time.Duration(policy.MaxAgeDays) * 24 * time.Hour
+	keptDigests := make(map[string]string)
+	var candidates []TagDecision
+
+	for rank, tag := range sorted {
+		reason := ""
+		for _, ref := range references {
+			if ref.matches(repository, tag) {
+				reason = "referenced by " + ref.source
+				break
+			}
+		}
+		if reason == "" {
+			switch {
+			case keepPattern != nil && keepPattern.MatchString(tag.Tag):
+				reason = "matches keep_pattern"
+			case tag.Created == nil:
+				reason = "creation time unknown"
+			case policy.KeepLast > 0 && rank < policy.KeepLast:
+				reason = fmt.Sprintf("among the %d newest", policy.KeepLast)
+			case maxAge > 0 && now.Sub(*tag.Created) < maxAge:
+				reason = fmt.Sprintf("newer than %d days", policy.MaxAgeDays)
+			}
+		}
+
+		if reason != "" {
+			kept = append(kept, TagDecision{Tag: tag, Reason: reason})
+			if _, ok := keptDigests[tag.Digest]; !ok {
+				keptDigests[tag.Digest] = tag.Tag
+			}
+			continue
+		}
+
+		var limits []string
+		if policy.KeepLast > 0 {
+			limits = append(limits, fmt.Sprintf("not among the %d newest", policy.KeepLast))
+		}
+		if maxAge > 0 {
+			limits = append(limits, fmt.Sprintf("older than %d days", policy.MaxAgeDays))
+		}
+		candidates = append(candidates, TagDecision{Tag: tag, Reason: strings.Join(limits, ", ")})
+	}
+
+	for _, candidate := range candidates {
+		if keptTag, ok := keptDigests[candidate.Digest]; ok {
+			candidate.Reason = "shares its manifest with kept tag " + keptTag
+			kept = append(kept, candidate)
+			continue
+		}
+		deleted = append(deleted, candidate)
+	}
+
+	return kept, deleted, nil
+}
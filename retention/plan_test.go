@@ -0,0 +1,127 @@
+package retention
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/noel-vega/hubble/projects"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		image, repository, tag string
+		want                   bool
+	}{
+		{"registry.example.com/site-web:v1.2.0", "site-web", "v1.2.0", true},
+		{"registry.example.com/site-web:v1.2.0", "site-web", "v1.1.0", false},
+		{"localhost:5000/team/api", "team/api", "latest", true},
+		{"registry.example.com:5000/team/api", "team/api", "latest", true},
+		{"${REGISTRY}/site-web:${TAG:-latest}", "site-web", "anything", true},
+		{"${REGISTRY}/site-web:${TAG:-latest}", "site-api", "anything", false},
+		{"registry.example.com/${PROJECT}-web:v2", "blog-web", "v2", true},
+		{"registry.example.com/site-web@sha256:aaa", "site-web", "v1.0.0", true},
+		{"registry.example.com/site-web@sha256:aaa", "site-web", "latest", false},
+		// A variable in the name may hold the tag as well as any part of the path
+		{"${IMAGE}", "team/api", "v3", true},
+		{"${REGISTRY}/${APP}", "site-web", "v3", true},
+		{"${REGISTRY}/${APP}", "team/api", "main-1", true},
+		{"registry.example.com/team/${APP}", "team/api", "v3", true},
+		{"registry.example.com/team/${APP}", "site-web", "v3", false},
+	}
+	for _, tt := range tests {
+		ref := parseReference(projects.ImageReference{Project: "site", Service: "web", Image: tt.image})
+		tag := Tag{Tag: tt.tag, Digest: "sha256:bbb"}
+		if tt.tag == "v1.0.0" {
+			tag.Digest = "sha256:aaa"
+		}
+		if got := ref.matches(tt.repository, tag); got != tt.want {
+			t.Errorf("%s matches %s:%s = %v, want %v", tt.image, tt.repository, tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) *time.Time {
+		created := now.AddDate(0, 0, -days)
+		return &created
+	}
+
+	tags := []Tag{
+		{Tag: "main-9", Digest: "sha256:9", Created: daysAgo(1)},
+		{Tag: "latest", Digest: "sha256:9", Created: daysAgo(1)},
+		{Tag: "main-8", Digest: "sha256:8", Created: daysAgo(10)},
+		{Tag: "main-7", Digest: "sha256:7", Created: daysAgo(40)},
+		{Tag: "v1.0.0", Digest: "sha256:6", Created: daysAgo(60)},
+		{Tag: "main-5", Digest: "sha256:5", Created: daysAgo(70)},
+		{Tag: "main-4", Digest: "sha256:4", Created: daysAgo(80)},
+		{Tag: "stable", Digest: "sha256:4", Created: daysAgo(80)},
+		{Tag: "main-3", Digest: "sha256:3", Created: daysAgo(90)},
+		{Tag: "scratch", Digest: "sha256:2"},
+	}
+	references := []reference{
+		parseReference(projects.ImageReference{Project: "site", Service: "web", Image: "registry.example.com/site-web:main-5"}),
+		parseReference(projects.ImageReference{Project: "site", Service: "worker", Image: "registry.example.com/site-web:stable"}),
+	}
+
+	tests := []struct {
+		policy  Policy
+		deleted string
+	}{
+		// latest and main-9 share a manifest but count separately toward keep_last
+		{Policy{KeepLast: 3, KeepPattern: `^v\d+\.\d+\.\d+$`}, "[main-7 main-3]"},
+		{Policy{MaxAgeDays: 30}, "[main-7 v1.0.0 main-3]"},
+		// Both limits must allow the deletion; main-7 is the fourth newest
+		{Policy{KeepLast: 4, MaxAgeDays: 5}, "[v1.0.0 main-3]"},
+	}
+	for _, tt := range tests {
+		kept, deleted, err := plan(tt.policy, "site-web", tags, references, now)
+		if err != nil {
+			t.Fatalf("plan failed: %v", err)
+		}
+
+		var names []string
+		for _, d := range deleted {
+			names = append(names, d.Tag.Tag)
+		}
+		if fmt.Sprint(names) != tt.deleted {
+			t.Errorf("%+v deleted %v, want %s", tt.policy, names, tt.deleted)
+		}
+		if len(kept)+len(deleted) != len(tags) {
+			t.Errorf("%+v kept %d and deleted %d of %d tags", tt.policy, len(kept), len(deleted), len(tags))
+		}
+
+		reasons := make(map[string]string)
+		for _, k := range kept {
+			reasons[k.Tag.Tag] = k.Reason
+		}
+		if reasons["main-5"] != "referenced by site/web" || reasons["scratch"] != "creation time unknown" {
+			t.Errorf("%+v: unexpected reasons %v", tt.policy, reasons)
+		}
+		if reasons["main-4"] != "shares its manifest with kept tag stable" {
+			t.Errorf("%+v: expected main-4 to be kept with stable, got %q", tt.policy, reasons["main-4"])
+		}
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		err    string
+	}{
+		{Policy{Name: "ci", Repository: "team/*", KeepLast: 10}, ""},
+		{Policy{Name: "ci", Repository: "app", MaxAgeDays: 30, KeepPattern: `^v\d+`}, ""},
+		{Policy{Repository: "app", KeepLast: 10}, "invalid policy: name is required"},
+		{Policy{Name: "ci", KeepLast: 10}, "invalid policy: repository is required"},
+		{Policy{Name: "ci", Repository: "app"}, "invalid policy: keep_last or max_age_days is required"},
+		{Policy{Name: "ci", Repository: "app", KeepLast: -1}, "invalid policy: keep_last and max_age_days must not be negative"},
+		{Policy{Name: "ci", Repository: "[app", KeepLast: 1}, `invalid policy: invalid repository pattern "[app"`},
+	}
+	for _, tt := range tests {
+		err := tt.policy.Validate()
+		if (err == nil && tt.err != "") || (err != nil && err.Error() != tt.err) {
+			t.Errorf("Validate(%+v) = %v, want %q", tt.policy, err, tt.err)
+		}
+	}
+}
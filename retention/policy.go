@@ -0,0 +1,113 @@
+package retention
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// Policy decides which tags of matching repositories are deleted. A tag is
+// deleted only when every limit that is set allows it: it is not among the
+// KeepLast newest tags and it is older than MaxAgeDays. Tags matching
+// KeepPattern and tags referenced by a project's compose file are always
+// kept.
+type Policy struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Repository is a repository name or a pattern such as "team/*"
+	Repository string `json:"repository"`
+	KeepLast   int    `json:"keep_last,omitempty"`
+	// KeepPattern is a regular expression for tags that are never deleted,
+	// such as release versions
+	KeepPattern string    `json:"keep_pattern,omitempty"`
+	MaxAgeDays  int       `json:"max_age_days,omitempty"`
+	Disabled    bool      `json:"disabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Validate checks the policy's settings
+func (p Policy) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("invalid policy: name is required")
+	}
+	if p.Repository == "" {
+		return fmt.Errorf("invalid policy: repository is required")
+	}
+	if _, err := path.Match(p.Repository, ""); err != nil {
+		return fmt.Errorf("invalid policy: invalid repository pattern %q", p.Repository)
+	}
+	if p.KeepLast < 0 || p.MaxAgeDays < 0 {
+		return fmt.Errorf("invalid policy: keep_last and max_age_days must not be negative")
+	}
+	if p.KeepLast == 0 && p.MaxAgeDays == 0 {
+		return fmt.Errorf("invalid policy: keep_last or max_age_days is required")
+	}
+	if p.KeepPattern != "" {
+		if _, err := regexp.Compile(p.KeepPattern); err != nil {
+			return fmt.Errorf("invalid policy: invalid keep_pattern: %v", err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the policy applies to a repository
+func (p Policy) matches(repository string) bool {
+	matched, _ := path.Match(p.Repository, repository)
+	return matched
+}
+
+// loadPolicies reads the policies file, which may not exist yet
+func loadPolicies(path string) ([]Policy, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []Policy{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read retention policies: %w", err)
+	}
+
+	var policies []Policy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("failed to parse retention policies: %w", err)
+	}
+	return policies, nil
+}
+
+// savePolicies replaces the policies file
+func savePolicies(path string, policies []Policy) error {
+	data, err := json.MarshalIndent(policies, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode retention policies: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".policies-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to save retention policies: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save retention policies: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save retention policies: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save retention policies: %w", err)
+	}
+	return nil
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
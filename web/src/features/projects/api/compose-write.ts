@@ -0,0 +1,19 @@
+// Requests that change a project's compose file send the ETag of the
+// version they are based on. The server refuses them with 412 when the file
+// has changed since, and with 428 when no ETag is sent at all.
+
+export function ifMatch(etag: string | undefined): Record<string, string> {
+	return etag ? { "If-Match": etag } : {};
+}
+
+export function checkComposeWrite(response: Response, action: string) {
+	if (response.status === 412 || response.status === 428) {
+		throw new Error(
+			"The compose file was changed by someone else. Reload and try again.",
+		);
+	}
+
+	if (!response.ok) {
+		throw new Error(`Failed to ${action}: ${response.statusText}`);
+	}
+}
@@ -1,5 +1,6 @@
 import z from "zod";
 import type { NetworkIdentifier } from "../types";
+import { checkComposeWrite, ifMatch } from "./compose-write";
 
 const ResponseSchema = z.object({
 	message: z.string(),
@@ -7,17 +8,18 @@ const ResponseSchema = z.object({
 	network: z.string(),
 });
 
-export async function deleteProjectNetwork(params: NetworkIdentifier) {
+export async function deleteProjectNetwork(
+	params: NetworkIdentifier & { etag?: string },
+) {
 	const response = await fetch(
 		`/api/projects/${params.projectName}/networks/${params.networkName}`,
 		{
 			method: "DELETE",
+			headers: ifMatch(params.etag),
 		},
 	);
 
-	if (!response.ok) {
-		throw new Error(`Failed to delete network: ${response.statusText}`);
-	}
+	checkComposeWrite(response, "delete network");
 
 	const data = await response.json();
 	return ResponseSchema.parse(data);
@@ -1,5 +1,6 @@
 import z from "zod";
 import type { ServiceIdentifier } from "../types";
+import { checkComposeWrite, ifMatch } from "./compose-write";
 
 const ResponseSchema = z.object({
 	message: z.string(),
@@ -7,13 +8,19 @@ const ResponseSchema = z.object({
 	service: z.string(),
 });
 
-export async function deleteProjectService(service: ServiceIdentifier) {
+export async function deleteProjectService(
+	service: ServiceIdentifier & { etag?: string },
+) {
 	const response = await fetch(
 		`/api/projects/${service.projectName}/services/${service.serviceName}`,
 		{
 			method: "DELETE",
+			headers: ifMatch(service.etag),
 		},
 	);
+
+	checkComposeWrite(response, "delete service");
+
 	const data = await response.json();
 	return ResponseSchema.parse(data);
 }
@@ -14,5 +14,6 @@ const ResponseSchema = z.object({
 export async function getProjectNetworks({ projectName }: RequestParams) {
   const response = await fetch(`/api/projects/${projectName}/networks`)
   const data = await response.json()
-  return ResponseSchema.parse(data)
+  // The ETag is echoed back via If-Match when editing a network
+  return { ...ResponseSchema.parse(data), etag: response.headers.get("ETag") ?? undefined }
 }
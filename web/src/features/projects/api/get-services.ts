@@ -10,5 +10,6 @@ export async function getProjectServices({ name }: { name: string }) {
   const response = await fetch(`/api/projects/${name}/services`)
   const data = await response.json()
   console.log(data)
-  return ResponseSchema.parse(data)
+  // The ETag is echoed back via If-Match when editing a service
+  return { ...ResponseSchema.parse(data), etag: response.headers.get("ETag") ?? undefined }
 }
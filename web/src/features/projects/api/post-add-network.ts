@@ -1,4 +1,5 @@
 import z from "zod";
+import { checkComposeWrite, ifMatch } from "./compose-write";
 
 type RequestParams = {
 	projectName: string;
@@ -8,6 +9,8 @@ type RequestParams = {
 		driver?: string;
 		config?: Record<string, unknown>;
 	};
+	// ETag of the compose file the edit is based on
+	etag?: string;
 };
 
 const ResponseSchema = z.object({
@@ -19,18 +22,18 @@ const ResponseSchema = z.object({
 export async function AddProjectNetwork({
 	projectName,
 	network,
+	etag,
 }: RequestParams) {
 	const response = await fetch(`/api/projects/${projectName}/networks`, {
 		method: "POST",
 		headers: {
 			"Content-Type": "application/json",
+			...ifMatch(etag),
 		},
 		body: JSON.stringify(network),
 	});
 
-	if (!response.ok) {
-		throw new Error(`Failed to add network: ${response.statusText}`);
-	}
+	checkComposeWrite(response, "add network");
 
 	const data = await response.json();
 	return ResponseSchema.parse(data);
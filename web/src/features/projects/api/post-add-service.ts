@@ -1,5 +1,6 @@
 import z from "zod";
 import { type ProjectComposeService } from "../types";
+import { checkComposeWrite, ifMatch } from "./compose-write";
 
 const ResponseSchema = z.object({
   message: z.string(),
@@ -10,19 +11,26 @@ const ResponseSchema = z.object({
 export interface AddServiceRequest {
   projectName: string;
   service: Omit<ProjectComposeService, "status">;
+  // ETag of the compose file the edit is based on
+  etag?: string;
 }
 
 export async function postAddService({
   projectName,
   service,
+  etag,
 }: AddServiceRequest) {
   const response = await fetch(`/api/projects/${projectName}/services`, {
     method: "POST",
     headers: {
       "Content-Type": "application/json",
+      ...ifMatch(etag),
     },
     body: JSON.stringify(service),
   });
+
+  checkComposeWrite(response, "add service");
+
   const data = await response.json();
   return ResponseSchema.parse(data);
 }
@@ -1,4 +1,5 @@
 import z from "zod";
+import { checkComposeWrite, ifMatch } from "./compose-write";
 
 type RequestParams = {
 	projectName: string;
@@ -9,6 +10,8 @@ type RequestParams = {
 		driver?: string;
 		config?: Record<string, unknown>;
 	};
+	// ETag of the compose file the edit is based on
+	etag?: string;
 };
 
 const ResponseSchema = z.object({
@@ -24,14 +27,13 @@ export async function updateProjectNetwork(params: RequestParams) {
 			method: "PUT",
 			headers: {
 				"Content-Type": "application/json",
+				...ifMatch(params.etag),
 			},
 			body: JSON.stringify(params.network),
 		},
 	);
 
-	if (!response.ok) {
-		throw new Error(`Failed to update network: ${response.statusText}`);
-	}
+	checkComposeWrite(response, "update network");
 
 	const data = await response.json();
 	return ResponseSchema.parse(data);
@@ -2,10 +2,15 @@
 
 import z from "zod";
 import type { ProjectComposeService } from "../types";
+import { checkComposeWrite, ifMatch } from "./compose-write";
 
 export interface UpdateServiceRequest {
   projectName: string;
-  service: Omit<ProjectComposeService, "status">;
+  // Fields left out are kept as they are in the compose file
+  service: Pick<ProjectComposeService, "name"> &
+    Partial<Omit<ProjectComposeService, "status">>;
+  // ETag of the compose file the edit is based on
+  etag?: string;
 }
 
 const ResponseSchema = z.object({
@@ -17,6 +22,7 @@ const ResponseSchema = z.object({
 export async function updateProjectService({
   projectName,
   service,
+  etag,
 }: UpdateServiceRequest) {
   const response = await fetch(
     `/api/projects/${projectName}/services/${service.name}`,
@@ -24,14 +30,13 @@ export async function updateProjectService({
       method: "PUT",
       headers: {
         "Content-Type": "application/json",
+        ...ifMatch(etag),
       },
       body: JSON.stringify(service),
     },
   );
 
-  if (!response.ok) {
-    throw new Error(`Failed to update service: ${response.statusText}`);
-  }
+  checkComposeWrite(response, "update service");
 
   const data = await response.json()
   return ResponseSchema.parse(data)
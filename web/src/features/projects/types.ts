@@ -26,18 +26,49 @@ export const ProjectEnvInfoSchema = z.object({
 	env: z.record(z.string(), z.string()),
 });
 
+// Compose entries that may come back in long form are shown in their short
+// string form, e.g. a long-form port as "8080:80/tcp".
+const ComposeEntry = z
+	.string()
+	.or(z.record(z.string(), z.unknown()))
+	.transform((entry) => (typeof entry === "string" ? entry : formatComposeEntry(entry)));
+
+function formatComposeEntry(entry: Record<string, unknown>): string {
+	if (typeof entry.service === "string") return entry.service;
+	if (typeof entry.name === "string" && !("target" in entry)) return entry.name;
+	if ("target" in entry) {
+		const published = entry.published ? `${entry.published}:` : "";
+		const source = entry.source ? `${entry.source}:` : "";
+		const protocol = entry.protocol ? `/${entry.protocol}` : "";
+		return `${published || source}${entry.target}${protocol}`;
+	}
+	return JSON.stringify(entry);
+}
+
+// Quote arguments containing whitespace so the command can be split again
+function formatCommand(args: string[]): string {
+	return args.map((arg) => (/\s/.test(arg) ? JSON.stringify(arg) : arg)).join(" ");
+}
+
 export const ProjectComposeService = z.object({
 	name: z.string(),
 	image: z.string(),
-	build: z.string(),
-	ports: z.string().array(),
-	environment: z.record(z.string(), z.string()),
-	volumes: z.string().array(),
+	build: z
+		.string()
+		.or(z.object({ context: z.string().optional() }).passthrough())
+		.transform((build) => (typeof build === "string" ? build : build.context ?? "")),
+	ports: ComposeEntry.array(),
+	environment: z
+		.record(z.string(), z.string().nullable())
+		.transform((env) =>
+			Object.fromEntries(Object.entries(env).map(([key, value]) => [key, value ?? ""])),
+		),
+	volumes: ComposeEntry.array(),
 	labels: z.string().array(),
-	depends_on: z.string().array(),
-	networks: z.string().array(),
+	depends_on: ComposeEntry.array(),
+	networks: ComposeEntry.array(),
 	restart: z.string(),
-	command: z.string(),
+	command: z.string().array().transform(formatCommand),
 	status: z
 		.literal("running")
 		.or(z.literal("stopped"))
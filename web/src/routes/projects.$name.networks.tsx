@@ -65,6 +65,7 @@ function RouteComponent() {
 	});
 
 	const networks = (data as { networks: any[] }).networks;
+	const { etag } = data as { etag?: string };
 
 	const addNetworkMutation = useMutation({
 		mutationFn: AddProjectNetwork,
@@ -95,6 +96,7 @@ function RouteComponent() {
 	const handleAddNetwork = () => {
 		addNetworkMutation.mutate({
 			projectName: name,
+			etag,
 			network: {
 				name: formData.name,
 				driver: formData.external ? undefined : formData.driver,
@@ -107,6 +109,7 @@ function RouteComponent() {
 	const handleUpdateNetwork = (originalName: string) => {
 		updateNetworkMutation.mutate({
 			projectName: name,
+			etag,
 			networkName: originalName,
 			network: {
 				name: formData.name,
@@ -135,6 +138,7 @@ function RouteComponent() {
 			deleteNetworkMutation.mutate({
 				projectName: name,
 				networkName: deletingNetwork,
+				etag,
 			});
 		}
 	};
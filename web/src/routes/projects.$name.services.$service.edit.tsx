@@ -10,7 +10,10 @@ import {
 	useSuspenseQuery,
 	useQueryClient,
 } from "@tanstack/react-query";
-import { updateProjectService } from "@/features/projects/api/update-service";
+import {
+	type UpdateServiceRequest,
+	updateProjectService,
+} from "@/features/projects/api/update-service";
 import { getRegistryCatalog } from "@/features/registry/api/get-catalog";
 import type { ProjectComposeService } from "@/features/projects/types";
 import z from "zod";
@@ -199,12 +202,13 @@ function RouteComponent() {
 	const services = (
 		data as { services: z.infer<typeof ProjectComposeService>[] }
 	).services;
+	const { etag } = data as { etag?: string };
 
 	const service = services.find((s) => s.name === serviceName);
 
 	const [error, setError] = useState<string | null>(null);
 	const [activeTab, setActiveTab] = useState("basics");
-	const [formData, setFormData] = useState({
+	const [initialFormData] = useState(() => ({
 		name: service?.name || "",
 		image: service?.image || "",
 		build: service?.build || "",
@@ -219,8 +223,9 @@ function RouteComponent() {
 		networks: service?.networks?.join(", ") || "",
 		restart: service?.restart || "",
 		command: service?.command || "",
-	});
-	const [labels, setLabels] = useState<{ key: string; value: string }[]>(
+	}));
+	const [formData, setFormData] = useState(initialFormData);
+	const [initialLabels] = useState<{ key: string; value: string }[]>(() =>
 		service?.labels && service.labels.length > 0
 			? service.labels.map((label) => {
 					const [key, ...valueParts] = label.split("=");
@@ -228,6 +233,7 @@ function RouteComponent() {
 				})
 			: [{ key: "", value: "" }],
 	);
+	const [labels, setLabels] = useState(initialLabels);
 
 	const updateServiceMutation = useMutation({
 		mutationFn: updateProjectService,
@@ -320,9 +326,26 @@ function RouteComponent() {
 			command: formData.command.trim(),
 		};
 
+		// Only send the fields that were edited so the server leaves the
+		// rest of the service (including long-form syntax) untouched
+		const changedService: UpdateServiceRequest["service"] = {
+			name: updatedService.name,
+		};
+		for (const field of Object.keys(initialFormData) as Array<
+			keyof typeof initialFormData
+		>) {
+			if (field !== "name" && formData[field] !== initialFormData[field]) {
+				Object.assign(changedService, { [field]: updatedService[field] });
+			}
+		}
+		if (JSON.stringify(labels) !== JSON.stringify(initialLabels)) {
+			changedService.labels = updatedService.labels;
+		}
+
 		updateServiceMutation.mutate({
 			projectName: name,
-			service: updatedService,
+			service: changedService,
+			etag,
 		});
 	};
 
@@ -1,5 +1,15 @@
-import { createFileRoute, useNavigate, Link } from "@tanstack/react-router";
-import { useMutation, useQuery } from "@tanstack/react-query";
+import {
+	createFileRoute,
+	useNavigate,
+	Link,
+	getRouteApi,
+} from "@tanstack/react-router";
+import {
+	useMutation,
+	useQuery,
+	useQueryClient,
+	useSuspenseQuery,
+} from "@tanstack/react-query";
 import { postAddService } from "@/features/projects/api/post-add-service";
 import { getRegistryCatalog } from "@/features/registry/api/get-catalog";
 import {
@@ -18,6 +28,8 @@ import { Input } from "@/components/ui/input";
 import { Label } from "@/components/ui/label";
 import { Tabs, TabsContent, TabsList, TabsTrigger } from "@/components/ui/tabs";
 
+const parentRoute = getRouteApi("/projects/$name/services");
+
 export const Route = createFileRoute("/projects/$name/services/add")({
 	component: RouteComponent,
 });
@@ -190,6 +202,10 @@ function ImageSelector({ value, onChange, disabled }: ImageSelectorProps) {
 function RouteComponent() {
 	const { name } = Route.useParams();
 	const navigate = useNavigate();
+	const queryClient = useQueryClient();
+	const { queryOptions } = parentRoute.useLoaderData();
+	const { data } = useSuspenseQuery(queryOptions);
+	const { etag } = data as { etag?: string };
 	const [error, setError] = useState<string | null>(null);
 	const [activeTab, setActiveTab] = useState("basics");
 	const [formData, setFormData] = useState({
@@ -211,6 +227,7 @@ function RouteComponent() {
 	const addServiceMutation = useMutation({
 		mutationFn: postAddService,
 		onSuccess: () => {
+			queryClient.invalidateQueries({ queryKey: queryOptions.queryKey });
 			navigate({
 				to: "/projects/$name/services",
 				params: { name },
@@ -283,7 +300,7 @@ function RouteComponent() {
 			command: formData.command.trim(),
 		};
 
-		addServiceMutation.mutate({ projectName: name, service });
+		addServiceMutation.mutate({ projectName: name, service, etag });
 	};
 
 	const handleInputChange = (field: string, value: string) => {
@@ -40,6 +40,7 @@ function RouteComponent() {
 	const services = (
 		data as { services: z.infer<typeof ProjectComposeService>[] }
 	).services;
+	const { etag } = data as { etag?: string };
 
 	const startService = useMutation({
 		mutationFn: postServiceStart,
@@ -254,6 +255,7 @@ function RouteComponent() {
 														deleteService.mutate({
 															projectName: name,
 															serviceName: service.name,
+															etag,
 														});
 													}}
 													className="bg-red-600 hover:bg-red-700 text-white"